@@ -0,0 +1,354 @@
+/**
+ * Binary wire format of the state stream and input events, mirroring game/codec
+ * on the server. The client lists the codecs it can decode in its state acks and
+ * the server switches the stream over; JSON messages are always objects, so
+ * anything else on the stream is a base64-encoded binary message.
+ */
+
+// Codecs this client can decode, preferred first
+export const WIRE_CODECS = ['binary', 'json'];
+
+const BINARY_VERSION = 1;
+
+// Message kinds
+const KIND_STATE = 1;
+const KIND_INPUT = 2;
+const KIND_ACK = 3;
+
+// Quantization steps, see game/codec/wire.go
+const POSITION_SCALE = 100;
+const ANGLE_SCALE = 10000;
+const DIRECTION_SCALE = 10000;
+const RATE_SCALE = 1000;
+
+// Known enum values by position; must match the server's tables
+const STATUS_VALUES = ['READY', 'ACTIVE', 'DESTROYED', 'DISCONNECT'];
+const MODE_VALUES = ['ffa', 'tdm', 'ctf', 'koth'];
+const PHASE_VALUES = ['waiting', 'warmup', 'live', 'post_match'];
+const OBSTACLE_VALUES = ['tree', 'rock'];
+
+// Bits of the flags byte of a player
+const PLAYER_MOVING = 1;
+const PLAYER_DESTROYED = 2;
+
+type Vector = { x: number; y: number; z: number };
+
+const textEncoder = new TextEncoder();
+const textDecoder = new TextDecoder();
+
+/**
+ * Returns true if a stream message is in the binary format
+ */
+export function isBinarySignal(signal: string): boolean {
+  return !signal.trimStart().startsWith('{');
+}
+
+/**
+ * Reads the primitives of the binary format. Varints are decoded with arithmetic
+ * rather than bit operations so values past 32 bits, like server times, survive.
+ */
+class Reader {
+  private offset = 0;
+
+  constructor(private bytes: Uint8Array) {}
+
+  byte(): number {
+    if (this.offset >= this.bytes.length) {
+      throw new Error('Binary message truncated');
+    }
+    return this.bytes[this.offset++];
+  }
+
+  uvarint(): number {
+    let value = 0;
+    let scale = 1;
+    for (;;) {
+      const b = this.byte();
+      value += (b & 0x7f) * scale;
+      if (b < 0x80) {
+        return value;
+      }
+      scale *= 128;
+    }
+  }
+
+  varint(): number {
+    const v = this.uvarint();
+    return v % 2 === 0 ? v / 2 : -(v + 1) / 2;
+  }
+
+  bool(): boolean {
+    return this.byte() !== 0;
+  }
+
+  string(): string {
+    const length = this.uvarint();
+    if (this.offset + length > this.bytes.length) {
+      throw new Error('Binary message truncated');
+    }
+    const value = textDecoder.decode(this.bytes.subarray(this.offset, this.offset + length));
+    this.offset += length;
+    return value;
+  }
+
+  fixed(scale: number): number {
+    return this.varint() / scale;
+  }
+
+  vector(scale: number): Vector {
+    return { x: this.fixed(scale), y: this.fixed(scale), z: this.fixed(scale) };
+  }
+
+  // Timestamps are sent relative to the message's server time, 0 when unset
+  time(ref: number): number {
+    const v = this.uvarint();
+    if (v === 0) {
+      return 0;
+    }
+    const offset = (v - 1) % 2 === 0 ? (v - 1) / 2 : -v / 2;
+    return ref + offset;
+  }
+
+  enum(values: string[]): string {
+    const i = this.uvarint();
+    if (i === 0) {
+      return this.string();
+    }
+    if (i > values.length) {
+      throw new Error(`Unknown enum value ${i}`);
+    }
+    return values[i - 1];
+  }
+
+  // Reads a list, returning undefined for empty ones like the JSON format omits them
+  list<T>(read: () => T): T[] | undefined {
+    const count = this.uvarint();
+    if (count === 0) {
+      return undefined;
+    }
+    const items: T[] = [];
+    for (let i = 0; i < count; i++) {
+      items.push(read());
+    }
+    return items;
+  }
+}
+
+/**
+ * Writes the primitives of the binary format
+ */
+class Writer {
+  private bytes: number[] = [];
+
+  byte(b: number) {
+    this.bytes.push(b);
+  }
+
+  uvarint(value: number) {
+    while (value >= 0x80) {
+      this.byte((value % 128) | 0x80);
+      value = Math.floor(value / 128);
+    }
+    this.byte(value);
+  }
+
+  varint(value: number) {
+    this.uvarint(value >= 0 ? value * 2 : -value * 2 - 1);
+  }
+
+  string(value: string) {
+    const encoded = textEncoder.encode(value);
+    this.uvarint(encoded.length);
+    encoded.forEach(b => this.byte(b));
+  }
+
+  fixed(value: number, scale: number) {
+    this.varint(Number.isFinite(value) ? Math.round(value * scale) : 0);
+  }
+
+  list(values: string[]) {
+    this.uvarint(values.length);
+    values.forEach(value => this.string(value));
+  }
+
+  toBase64(): string {
+    return btoa(String.fromCharCode(...this.bytes));
+  }
+}
+
+function fromBase64(signal: string): Uint8Array {
+  const binary = atob(signal);
+  const bytes = new Uint8Array(binary.length);
+  for (let i = 0; i < binary.length; i++) {
+    bytes[i] = binary.charCodeAt(i);
+  }
+  return bytes;
+}
+
+function readObstacle(r: Reader) {
+  return {
+    id: r.string(),
+    type: r.enum(OBSTACLE_VALUES),
+    position: r.vector(POSITION_SCALE),
+  };
+}
+
+function readPlayer(r: Reader, ref: number) {
+  const player: any = {
+    id: r.string(),
+    name: r.string(),
+    position: r.vector(POSITION_SCALE),
+    tankRotation: r.fixed(ANGLE_SCALE),
+    turretRotation: r.fixed(ANGLE_SCALE),
+    barrelElevation: r.fixed(ANGLE_SCALE),
+    health: r.varint(),
+  };
+  const flags = r.byte();
+  player.isMoving = (flags & PLAYER_MOVING) !== 0;
+  player.isDestroyed = (flags & PLAYER_DESTROYED) !== 0;
+  player.velocity = r.fixed(RATE_SCALE);
+  player.timestamp = r.time(ref);
+  player.color = r.string();
+  player.status = r.enum(STATUS_VALUES);
+  player.kills = r.varint();
+  player.deaths = r.varint();
+  player.trackRotation = r.fixed(RATE_SCALE);
+  player.lastKilledBy = r.string();
+  player.lastDeathTime = r.time(ref);
+  player.lastInputSeq = r.uvarint();
+  player.team = r.string();
+  player.score = r.varint();
+  return player;
+}
+
+// Rebuilds a map keyed by a field of its values
+function keyBy<T>(items: T[] | undefined, key: (item: T) => string): { [key: string]: T } | undefined {
+  if (!items) {
+    return undefined;
+  }
+  const map: { [key: string]: T } = {};
+  items.forEach(item => map[key(item)] = item);
+  return map;
+}
+
+/**
+ * Decodes a binary state message into the same shape as its JSON form
+ */
+export function decodeState(signal: string): any {
+  const r = new Reader(fromBase64(signal));
+  const version = r.byte();
+  const kind = r.byte();
+  if (version !== BINARY_VERSION || kind !== KIND_STATE) {
+    throw new Error(`Unsupported binary message version ${version} kind ${kind}`);
+  }
+
+  const delta: any = {
+    stream: r.string(),
+    rev: r.uvarint(),
+    base: r.uvarint(),
+    full: r.bool(),
+    tick: r.uvarint(),
+    serverTime: r.varint(),
+  };
+  const ref = delta.serverTime;
+
+  delta.match = {
+    mode: r.enum(MODE_VALUES),
+    scoreLimit: r.varint(),
+    endsAt: r.time(ref),
+    over: r.bool(),
+    endedAt: r.time(ref),
+    winner: r.string(),
+    phase: r.enum(PHASE_VALUES),
+    phaseEndsAt: r.time(ref),
+    countdown: r.varint(),
+    number: r.varint(),
+    startedAt: r.time(ref),
+    map: r.string(),
+  };
+
+  delta.teams = keyBy(r.list(() => ({
+    id: r.string(),
+    name: r.string(),
+    color: r.string(),
+    score: r.varint(),
+  })), team => team.id);
+  delta.flags = keyBy(r.list(() => ({
+    team: r.string(),
+    base: r.vector(POSITION_SCALE),
+    position: r.vector(POSITION_SCALE),
+    carrierId: r.string(),
+    dropped: r.bool(),
+    droppedAt: r.time(ref),
+  })), flag => flag.team);
+  delta.zones = r.list(() => ({
+    id: r.string(),
+    position: r.vector(POSITION_SCALE),
+    radius: r.fixed(POSITION_SCALE),
+    owner: r.string(),
+    capturer: r.string(),
+    progress: r.fixed(RATE_SCALE),
+    contested: r.bool(),
+  }));
+  delta.spectators = r.uvarint();
+
+  delta.impacts = r.list(() => ({
+    id: r.string(),
+    playerId: r.string(),
+    position: r.vector(POSITION_SCALE),
+    obstacle: readObstacle(r),
+    destroyed: r.bool(),
+    timestamp: r.time(ref),
+  }));
+  delta.destroyed = r.list(() => readObstacle(r));
+
+  delta.players = keyBy(r.list(() => readPlayer(r, ref)), player => player.id);
+  delta.shells = r.list(() => ({
+    id: r.string(),
+    playerId: r.string(),
+    position: r.vector(POSITION_SCALE),
+    direction: r.vector(DIRECTION_SCALE),
+    speed: r.fixed(RATE_SCALE),
+    timestamp: r.time(ref),
+  }));
+  delta.removedPlayers = r.list(() => r.string());
+  delta.removedShells = r.list(() => r.string());
+
+  return delta;
+}
+
+/**
+ * Encodes a PLAYER_INPUT event in the binary format
+ */
+export function encodeInput(input: {
+  throttle: number;
+  steer: number;
+  turretRotation: number;
+  barrelElevation: number;
+  seq: number;
+  timestamp: number;
+}): string {
+  const w = new Writer();
+  w.byte(BINARY_VERSION);
+  w.byte(KIND_INPUT);
+  w.fixed(input.throttle, RATE_SCALE);
+  w.fixed(input.steer, RATE_SCALE);
+  w.fixed(input.turretRotation, ANGLE_SCALE);
+  w.fixed(input.barrelElevation, ANGLE_SCALE);
+  w.uvarint(input.seq);
+  w.varint(input.timestamp);
+  return w.toBase64();
+}
+
+/**
+ * Encodes a STATE_ACK event in the binary format
+ */
+export function encodeAck(stream: string, rev: number, codecs: string[] = []): string {
+  const w = new Writer();
+  w.byte(BINARY_VERSION);
+  w.byte(KIND_ACK);
+  w.string(stream);
+  w.uvarint(rev);
+  w.list(codecs);
+  return w.toBase64();
+}
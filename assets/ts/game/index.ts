@@ -5,6 +5,7 @@ import { MapGenerator } from './map';
 import { Tank, RemoteTank, ITank, ICollidable, SpatialAudio } from './tank';
 import { CollisionSystem } from './collision';
 import { Shell } from './shell';
+import { WIRE_CODECS, isBinarySignal, decodeState, encodeInput, encodeAck } from './codec';
 import './stats'; // Import stats component
 
 // Make SpatialAudio accessible from window for global use
@@ -29,12 +30,126 @@ interface PlayerState {
   color?: string;
   isDestroyed?: boolean;
   status?: string; // READY, ACTIVE, DESTROYED, DISCONNECT
+  team?: string; // Team ID in team modes
 }
 
 // Interface for game state
 interface MultiplayerGameState {
   players: { [playerId: string]: PlayerState };
   shells: ShellState[];
+  tick?: number;
+  serverTime?: number;
+  match?: MatchState;
+  teams?: { [teamId: string]: TeamState };
+  flags?: { [teamId: string]: FlagState };
+  zones?: ZoneState[];
+  spectators?: number;
+  impacts?: ImpactState[];
+  destroyed?: ObstacleState[];
+}
+
+// Interface for a tree or rock of the map
+interface ObstacleState {
+  id: string;
+  type: string; // tree, rock
+  position: { x: number; y: number; z: number };
+}
+
+// Interface for a shell striking a tree or rock
+interface ImpactState {
+  id: string;
+  playerId: string;
+  position: { x: number; y: number; z: number };
+  obstacle: ObstacleState;
+  destroyed?: boolean;
+  timestamp: number;
+}
+
+// Interface for the current match mode and result
+interface MatchState {
+  mode: string;
+  scoreLimit?: number;
+  endsAt?: number;
+  over?: boolean;
+  endedAt?: number;
+  winner?: string;
+  phase?: string; // waiting, warmup, live, post_match
+  phaseEndsAt?: number;
+  countdown?: number;
+  number?: number;
+  startedAt?: number;
+  map?: string;
+}
+
+// Interface for a team in team modes
+interface TeamState {
+  id: string;
+  name: string;
+  color: string;
+  score: number;
+}
+
+// Interface for a team flag in capture-the-flag
+interface FlagState {
+  team: string;
+  base: { x: number; y: number; z: number };
+  position: { x: number; y: number; z: number };
+  carrierId?: string;
+  dropped?: boolean;
+  droppedAt?: number;
+}
+
+// Interface for a King of the Hill control zone
+interface ZoneState {
+  id: string;
+  position: { x: number; y: number; z: number };
+  radius: number;
+  owner?: string;
+  capturer?: string;
+  progress: number;
+  contested?: boolean;
+}
+
+// Interface for a message on the delta-encoded game state stream
+interface GameStateDelta {
+  stream: string;
+  rev: number;
+  base?: number;
+  full?: boolean;
+  tick: number;
+  serverTime: number;
+  match?: MatchState;
+  teams?: { [teamId: string]: TeamState };
+  flags?: { [teamId: string]: FlagState };
+  zones?: ZoneState[];
+  spectators?: number;
+  impacts?: ImpactState[];
+  destroyed?: ObstacleState[];
+  players?: { [playerId: string]: PlayerState };
+  shells?: ShellState[];
+  removedPlayers?: string[];
+  removedShells?: string[];
+}
+
+// Interface for a discrete match event on the room's event stream
+interface MatchEvent {
+  type: 'shot' | 'hit' | 'impact' | 'kill' | 'respawn' | 'join' | 'leave' | 'phase';
+  tick: number;
+  serverTime: number;
+  playerId?: string;
+  playerName?: string;
+  targetId?: string;
+  targetName?: string;
+  shellId?: string;
+  weapon?: string;
+  hitLocation?: string;
+  damage?: number;
+  position?: { x: number; y: number; z: number };
+  direction?: { x: number; y: number; z: number };
+  obstacle?: ObstacleState;
+  destroyed?: boolean;
+  phase?: string;
+  match?: number;
 }
 
 // Interface for shell state
@@ -75,20 +190,28 @@ export class GameComponent extends LitElement {
     
     try {
       if (value && typeof value === 'string') {
-        // Try to parse the game state as JSON
+        // Parse the game state as JSON, or the binary format once the server has switched to it
         let parsed;
         try {
-          parsed = JSON.parse(value);
+          this.binaryWire = isBinarySignal(value);
+          parsed = this.binaryWire ? decodeState(value) : JSON.parse(value);
         } catch (parseError) {
-          console.error('Initial JSON parse failed:', parseError);
-          console.error('Game state is not valid JSON:', value);
+          console.error('Initial parse failed:', parseError);
+          console.error('Game state is not valid JSON or binary:', value);
           return;
         }
         
         // Handle the format from DataStar in views/index.templ
         // The gameState property in our component gets ONLY the inner JSON
         // directly from the server via: data-attr-game-state__case.kebab="$gameState"
-        if (parsed && parsed.players && typeof parsed.players === 'object') {
+        if (parsed && typeof parsed.rev === 'number') {
+          // Delta-encoded stream message - rebuild the full state from our baseline
+          const state = this.applyStateDelta(parsed as GameStateDelta);
+          if (!state) {
+            return;
+          }
+          this.multiplayerState = state;
+        } else if (parsed && parsed.players && typeof parsed.players === 'object') {
           this.multiplayerState = parsed;
         } else {
           console.error('Unrecognized game state format - missing players object:', parsed);
@@ -98,12 +221,26 @@ export class GameComponent extends LitElement {
         // Update remote players
         this.updateRemotePlayers();
         
+        // Update capture-the-flag flags
+        this.updateFlags();
+        
+        // Update King of the Hill control zones
+        this.updateZones();
+        
+        // Show debris from shells hitting trees and rocks
+        this.updateImpacts();
+        
         // Update local player health from server state if available
         this.updateLocalPlayerHealth();
         
         // Update game stats with server data (kills, deaths, etc.)
         this.updateStats();
         
+        // Spectators have no tank to set up
+        if (!this.gameStateInitialized && this.spectator) {
+          this.gameStateInitialized = true;
+        }
+        
         // Use the player ID from the attribute, or generate one if not set
         if (!this.gameStateInitialized && this.playerTank) {
                     
@@ -126,6 +263,30 @@ export class GameComponent extends LitElement {
     this.requestUpdate('gameState', oldValue);
   }
   
+  // Match events that arrived with the latest state, as a JSON array
+  private _events: string = '';
+  
+  get events(): string {
+    return this._events;
+  }
+  
+  // Setter that handles each batch of match events once
+  set events(value: string) {
+    const oldValue = this._events;
+    this._events = value;
+    
+    if (value && value !== oldValue) {
+      try {
+        const events = JSON.parse(value) as MatchEvent[];
+        events.forEach(event => this.handleMatchEvent(event));
+      } catch (error) {
+        console.error('Error parsing match events:', error);
+      }
+    }
+    
+    this.requestUpdate('events', oldValue);
+  }
+  
   // Parsed multiplayer state
   @property({ attribute: false })
   private multiplayerState?: MultiplayerGameState;
@@ -149,6 +310,14 @@ export class GameComponent extends LitElement {
       mapData: {
         type: String,
         attribute: 'map-data'
+      },
+      spectator: {
+        type: Boolean,
+        attribute: 'spectator'
+      },
+      events: {
+        type: String,
+        attribute: 'events'
       }
     };
   }
@@ -164,12 +333,38 @@ export class GameComponent extends LitElement {
   @property({ type: String, attribute: 'map-data' })
   public mapData: string = '';
   
-  @property({ type: String, attribute: 'notification' })
-  public notification: string = '';
+  // Banner shown briefly for the latest kill
+  @property({ attribute: false })
+  private notification: string = '';
+  private notificationTimer?: number;
+  
+  // Spectators watch without a tank and only steer the camera
+  @property({ type: Boolean, attribute: 'spectator' })
+  public spectator: boolean = false;
+  
+  // Spectator camera: follow a player's tank or fly freely
+  private spectatorMode: 'follow' | 'free' = 'follow';
+  private followedPlayerId: string = '';
+  private freeCameraYaw: number = 0;
+  private freeCameraPitch: number = -0.4;
+  private readonly FREE_CAMERA_SPEED = 1.5; // World units per frame
+  private readonly FREE_CAMERA_LOOK_SENSITIVITY = 0.003;
   
   // Flag to track if we've processed initial game state
   private gameStateInitialized: boolean = false;
   
+  // Delta stream state: reconstructed states by revision, used as baselines for later deltas
+  private stateStream: string = '';
+  private stateHistory: Map<number, MultiplayerGameState> = new Map();
+  private lastStateAckTime: number = 0;
+  private lastAckedRevision: number = 0;
+  private binaryWire = false; // True once the server sends the binary format; inputs and acks follow
+  private mapName = ''; // Map of the match the page was rendered for
+  private readonly STATE_ACK_INTERVAL = 250; // ms between acks for delta messages
+  
+  // Tick of the latest state shown; sent with shots so the server can rewind targets to what we saw
+  private viewTick: number = 0;
+  
   // Camera variables - exposed as properties to allow stats component to access
   @property({ attribute: false })
   public scene?: THREE.Scene;
@@ -186,6 +381,16 @@ export class GameComponent extends LitElement {
   private playerTank?: Tank;
   private remoteTanks: Map<string, RemoteTank> = new Map();
   
+  // Capture-the-flag flag meshes, keyed by team
+  private flagMeshes: Map<string, THREE.Group> = new Map();
+  
+  // King of the Hill zone rings, keyed by zone ID
+  private zoneMeshes: Map<string, THREE.Mesh> = new Map();
+  
+  // Shell impacts on trees and rocks already shown, and colliders of broken rocks by obstacle ID
+  private seenImpacts: Set<string> = new Set();
+  private brokenRocks: Map<string, ICollidable> = new Map();
+  
   
   // Collision system
   private collisionSystem: CollisionSystem = new CollisionSystem();
@@ -212,11 +417,15 @@ export class GameComponent extends LitElement {
   private killNotifications: { text: string, time: number }[] = [];
   private readonly MAX_NOTIFICATIONS = 5; // Maximum number of visible notifications
   private readonly NOTIFICATION_DURATION = 5000; // How long notifications stay visible (ms)
-  private processedDeathEvents = new Set<string>(); // Track processed death events
   
   // Control state
   private keys: { [key: string]: boolean } = {};
   
+  // Server-authoritative movement
+  private inputSequence: number = 0;
+  private readonly RECONCILE_SNAP_DISTANCE = 15; // Snap to server position beyond this distance
+  private readonly RECONCILE_BLEND = 0.2;        // Fraction of drift corrected per server update
+  
   // Assets
   // Sky gradient colors
   private skyColor: THREE.Color = new THREE.Color(0x87ceeb); // Sky blue
@@ -291,6 +500,20 @@ export class GameComponent extends LitElement {
       font-size: 16px;
     }
     
+    .spectator-count {
+      position: absolute;
+      top: 56px;
+      right: 10px;
+      background: rgba(0, 0, 0, 0.7);
+      color: white;
+      padding: 6px 10px;
+      border-radius: 5px;
+      font-family: monospace;
+      pointer-events: none;
+      z-index: 1000;
+      font-size: 14px;
+    }
+    
     .callsign-card {
       position: absolute;
       top: 10px;
@@ -605,17 +828,33 @@ export class GameComponent extends LitElement {
           <canvas id="canvas"></canvas>
           <div class="damage-overlay ${this.showDamageOverlay ? 'active' : ''}"></div>
           
-          <div class="callsign-card">Callsign: ${this.playerName}</div>
+          ${this.spectator ? html`
+            <div class="callsign-card">${this.spectatorLabel()}</div>
+          ` : html`
+            <div class="callsign-card">Callsign: ${this.playerName}</div>
+          `}
+          ${this.multiplayerState?.spectators ? html`
+            <div class="spectator-count">${this.multiplayerState.spectators} watching</div>
+          ` : ''}
           
           <game-stats></game-stats>
-          <div class="controls" style="display: ${this.isMobile ? 'none' : 'block'}">
-            <div>W: Forward, S: Backward</div>
-            <div>A: Rotate tank left, D: Rotate tank right</div>
-            <div>Mouse: Aim turret and barrel</div>
-            <div>Arrow keys: Alternative turret control</div>
-            <div>Left Click, Space, or F: Fire shell</div>
-            <div>Click canvas to lock pointer</div>
-          </div>
+          ${this.spectator ? html`
+            <div class="controls" style="display: ${this.isMobile ? 'none' : 'block'}">
+              <div>Tab / Shift+Tab: Follow next / previous player</div>
+              <div>V: Switch between follow and free camera</div>
+              <div>Free camera: WASD to move, Q/E down/up, mouse to look</div>
+              <div>Click canvas to lock pointer</div>
+            </div>
+          ` : html`
+            <div class="controls" style="display: ${this.isMobile ? 'none' : 'block'}">
+              <div>W: Forward, S: Backward</div>
+              <div>A: Rotate tank left, D: Rotate tank right</div>
+              <div>Mouse: Aim turret and barrel</div>
+              <div>Arrow keys: Alternative turret control</div>
+              <div>Left Click, Space, or F: Fire shell</div>
+              <div>Click canvas to lock pointer</div>
+            </div>
+          `}
           <div class="game-over ${(this.playerTank && this.playerTank.getIsDestroyed()) || this.playerDestroyed ? 'visible' : ''}">
             <div class="wasted-text">WASTED</div>
           </div>
@@ -1219,6 +1458,7 @@ export class GameComponent extends LitElement {
         z: direction.z
       },
       speed: speed,
+      viewTick: this.viewTick,
       isNetworkEvent: true // Mark as a network event
     };
     
@@ -1290,6 +1530,9 @@ export class GameComponent extends LitElement {
         this.requestUpdate();
       }
       
+      // Correct local prediction toward the server-simulated position
+      this.reconcileLocalPlayer(playerData);
+      
       // Update UI stats if available
       if (this.statsComponent) {
         this.statsComponent.updateGameStats(
@@ -1302,6 +1545,203 @@ export class GameComponent extends LitElement {
   }
   
   // Update remote players from game state
+  /**
+   * Create, move or remove flag meshes to match the capture-the-flag state
+   */
+  private updateFlags() {
+    if (!this.scene) return;
+    
+    const flags = this.multiplayerState?.flags || {};
+    
+    // Remove flags that are no longer in the game, e.g. after a mode change
+    this.flagMeshes.forEach((mesh, team) => {
+      if (!flags[team]) {
+        this.scene!.remove(mesh);
+        this.flagMeshes.delete(team);
+      }
+    });
+    
+    Object.values(flags).forEach(flag => {
+      let mesh = this.flagMeshes.get(flag.team);
+      if (!mesh) {
+        const color = this.multiplayerState?.teams?.[flag.team]?.color || '#ffffff';
+        mesh = new THREE.Group();
+        
+        const pole = new THREE.Mesh(
+          new THREE.CylinderGeometry(0.2, 0.2, 12, 8),
+          new THREE.MeshStandardMaterial({ color: 0xcccccc })
+        );
+        pole.position.y = 6;
+        mesh.add(pole);
+        
+        const cloth = new THREE.Mesh(
+          new THREE.PlaneGeometry(5, 3),
+          new THREE.MeshStandardMaterial({ color, side: THREE.DoubleSide })
+        );
+        cloth.position.set(2.5, 10.5, 0);
+        mesh.add(cloth);
+        
+        this.scene!.add(mesh);
+        this.flagMeshes.set(flag.team, mesh);
+      }
+      
+      // Carried flags ride above the carrier's turret
+      mesh.position.set(flag.position.x, flag.position.y + (flag.carrierId ? 3 : 0), flag.position.z);
+    });
+  }
+  
+  /**
+   * Create or recolor the control zone rings to show who holds each zone
+   */
+  private updateZones() {
+    if (!this.scene) return;
+    
+    const zones = this.multiplayerState?.zones || [];
+    const zoneIds = new Set(zones.map(zone => zone.id));
+    
+    this.zoneMeshes.forEach((mesh, id) => {
+      if (!zoneIds.has(id)) {
+        this.scene!.remove(mesh);
+        this.zoneMeshes.delete(id);
+      }
+    });
+    
+    zones.forEach(zone => {
+      let mesh = this.zoneMeshes.get(zone.id);
+      if (!mesh) {
+        mesh = new THREE.Mesh(
+          new THREE.RingGeometry(zone.radius - 1.5, zone.radius, 64),
+          new THREE.MeshBasicMaterial({ color: 0xffffff, side: THREE.DoubleSide, transparent: true, opacity: 0.6 })
+        );
+        mesh.rotation.x = -Math.PI / 2;
+        mesh.position.set(zone.position.x, zone.position.y + 0.2, zone.position.z);
+        this.scene!.add(mesh);
+        this.zoneMeshes.set(zone.id, mesh);
+      }
+      
+      // Owning team's or player's color, yellow while contested
+      let color = '#ffffff';
+      if (zone.contested) {
+        color = '#ffeb3b';
+      } else if (zone.owner) {
+        color = this.multiplayerState?.teams?.[zone.owner]?.color
+          || this.multiplayerState?.players?.[zone.owner]?.color
+          || color;
+      }
+      (mesh.material as THREE.MeshBasicMaterial).color.set(color);
+    });
+  }
+  
+  /**
+   * Shows debris for new shell impacts and hides or rebuilds broken rocks
+   */
+  private updateImpacts() {
+    if (!this.scene || !this.mapGenerator) return;
+    
+    const impacts = this.multiplayerState?.impacts || [];
+    const impactIds = new Set(impacts.map(impact => impact.id));
+    
+    // Impacts stay in the state for a second - forget the ones that have expired
+    this.seenImpacts.forEach(id => {
+      if (!impactIds.has(id)) {
+        this.seenImpacts.delete(id);
+      }
+    });
+    
+    impacts.forEach(impact => {
+      if (this.seenImpacts.has(impact.id)) return;
+      this.seenImpacts.add(impact.id);
+      
+      const position = new THREE.Vector3(impact.position.x, impact.position.y, impact.position.z);
+      this.createImpactDebris(position, impact.obstacle.type, impact.destroyed ? 2.5 : 1.0);
+    });
+    
+    // Broken rocks are listed until the next match rebuilds them
+    const destroyed = this.multiplayerState?.destroyed || [];
+    const destroyedIds = new Set(destroyed.map(obstacle => obstacle.id));
+    
+    destroyed.forEach(obstacle => {
+      if (this.brokenRocks.has(obstacle.id)) return;
+      
+      const position = new THREE.Vector3(obstacle.position.x, obstacle.position.y, obstacle.position.z);
+      const collider = this.mapGenerator!.hideRockAt(position);
+      if (collider) {
+        this.collisionSystem.removeCollider(collider);
+        this.brokenRocks.set(obstacle.id, collider);
+      }
+    });
+    
+    this.brokenRocks.forEach((collider, id) => {
+      if (!destroyedIds.has(id)) {
+        this.mapGenerator!.showRock(collider);
+        this.collisionSystem.addCollider(collider);
+        this.brokenRocks.delete(id);
+      }
+    });
+  }
+  
+  /**
+   * Creates a burst of splinters or rock chips where a shell struck an obstacle
+   */
+  private createImpactDebris(position: THREE.Vector3, type: string, sizeScale: number) {
+    if (!this.scene) return;
+    
+    const isLowPerformance = (window as any).lowPerformanceMode || false;
+    const particleCount = Math.round((isLowPerformance ? 10 : 24) * sizeScale);
+    
+    const geometry = new THREE.BufferGeometry();
+    const positions = new Float32Array(particleCount * 3);
+    const velocities: THREE.Vector3[] = [];
+    for (let i = 0; i < particleCount; i++) {
+      velocities.push(new THREE.Vector3(
+        (Math.random() - 0.5) * 0.3,
+        Math.random() * 0.25 + 0.05,
+        (Math.random() - 0.5) * 0.3
+      ).multiplyScalar(sizeScale));
+    }
+    geometry.setAttribute('position', new THREE.BufferAttribute(positions, 3));
+    
+    // Brown splinters for trees, grey chips for rocks
+    const material = new THREE.PointsMaterial({
+      color: type === 'tree' ? 0x6b4a2b : 0x8a8a8a,
+      size: 0.25 * sizeScale,
+      transparent: true,
+      opacity: 1,
+      sizeAttenuation: true
+    });
+    
+    const debris = new THREE.Points(geometry, material);
+    debris.position.copy(position);
+    this.scene.add(debris);
+    
+    const startTime = performance.now();
+    const duration = 800;
+    
+    const animateDebris = () => {
+      const progress = Math.min(1, (performance.now() - startTime) / duration);
+      if (progress >= 1 || !this.scene) {
+        this.scene?.remove(debris);
+        geometry.dispose();
+        material.dispose();
+        return;
+      }
+      
+      // Chips fly out and fall back under gravity
+      for (let i = 0; i < particleCount; i++) {
+        const velocity = velocities[i];
+        velocity.y -= 0.01;
+        positions[i * 3] += velocity.x;
+        positions[i * 3 + 1] = Math.max(-position.y, positions[i * 3 + 1] + velocity.y);
+        positions[i * 3 + 2] += velocity.z;
+      }
+      geometry.attributes.position.needsUpdate = true;
+      material.opacity = 1 - progress;
+      
+      requestAnimationFrame(animateDebris);
+    };
+    requestAnimationFrame(animateDebris);
+  }
+  
   private updateRemotePlayers() {
     // First, wait until we have a valid scene initialized
     if (!this.scene) {
@@ -1401,52 +1841,43 @@ export class GameComponent extends LitElement {
   }
   
   /**
-   * Check for any death notifications from the server
-   * This looks for LastKilledBy fields that would indicate a player was killed
+   * Handle a discrete event from the room's event stream
    */
-  private checkForDeathNotifications(): void {
-    if (!this.multiplayerState || !this.multiplayerState.players) {
-      return;
-    }
-    
-    // Loop through all players
-    for (const [playerId, playerData] of Object.entries(this.multiplayerState.players)) {
-      // Check if this player has the lastKilledBy field set
-      if (playerData.lastKilledBy && playerData.lastDeathTime) {
-        // Create a unique death event ID
-        const deathEventId = `${playerId}_${playerData.lastKilledBy}_${playerData.lastDeathTime}`;
-        
-        // Skip if we've already processed this death event
-        if (this.processedDeathEvents.has(deathEventId)) {
-          continue;
-        }
-        
-        // Mark as processed
-        this.processedDeathEvents.add(deathEventId);
-        
-        // Get killer's name (the one who killed this player)
-        let killerName = "Unknown";
-        if (this.multiplayerState.players[playerData.lastKilledBy]) {
-          killerName = this.multiplayerState.players[playerData.lastKilledBy].name || 
-                       `Player ${playerData.lastKilledBy.substring(0, 6)}`;
-        }
-        if (playerData.lastKilledBy === this.playerId) {
-          killerName = "You"; // If the current player is the killer
-        }
-        
-        // Get victim's name (this player)
-        let victimName = playerData.name || `Player ${playerId.substring(0, 6)}`;
-        if (playerId === this.playerId) {
-          victimName = "You"; // If the current player is the victim
-        }
-        
-        // Add kill notification
+  private handleMatchEvent(event: MatchEvent): void {
+    switch (event.type) {
+      case 'kill': {
+        const killerName = this.eventPlayerName(event.playerId, event.playerName);
+        const victimName = this.eventPlayerName(event.targetId, event.targetName);
         this.addKillNotification(killerName, victimName);
-        
-        console.log(`Kill notification: ${killerName} destroyed ${victimName}`);
+        this.showNotification(`${killerName} destroyed ${victimName}`);
+        break;
       }
     }
   }
+  
+  /**
+   * Name to show for a player in an event, "You" for the local player
+   */
+  private eventPlayerName(playerId?: string, name?: string): string {
+    if (!playerId) {
+      return 'Unknown';
+    }
+    if (playerId === this.playerId) {
+      return 'You';
+    }
+    return name || `Player ${playerId.substring(0, 6)}`;
+  }
+  
+  /**
+   * Show the notification banner for a few seconds
+   */
+  private showNotification(text: string): void {
+    this.notification = text;
+    window.clearTimeout(this.notificationTimer);
+    this.notificationTimer = window.setTimeout(() => {
+      this.notification = '';
+    }, 3000);
+  }
 
   /**
    * Updates audio listeners for all remote players based on their positions
@@ -2098,19 +2529,27 @@ export class GameComponent extends LitElement {
     
     // Create map generator with server map data if available
     this.mapGenerator = new MapGenerator(this.scene, this.mapData);
+    const mapGenerator = this.mapGenerator;
+    Shell.groundHeight = (x, z) => mapGenerator.heightAt(x, z);
     
     // Create environment objects
     this.createTrees();
     this.createRocks();
     
-    // Create player tank at a random valid position
-    const spawnPoint = this.findRandomSpawnPoint();
-    this.playerTank = new Tank(this.scene, this.camera);
-    this.playerTank.initialize(spawnPoint); // Set initial position without explosion effects
-    this.collisionSystem.addCollider(this.playerTank);
-    
-    // Position camera
-    this.positionCamera();
+    if (this.spectator) {
+      // Spectators start above the centre of the map looking down
+      this.camera.position.set(0, 60, 80);
+      this.camera.rotation.set(this.freeCameraPitch, this.freeCameraYaw, 0, 'YXZ');
+    } else {
+      // Create player tank at a random valid position
+      const spawnPoint = this.findRandomSpawnPoint();
+      this.playerTank = new Tank(this.scene, this.camera);
+      this.playerTank.initialize(spawnPoint); // Set initial position without explosion effects
+      this.collisionSystem.addCollider(this.playerTank);
+      
+      // Position camera
+      this.positionCamera();
+    }
     
     // Handle window resize
     window.addEventListener('resize', this.handleResize.bind(this));
@@ -2335,6 +2774,16 @@ export class GameComponent extends LitElement {
     const key = event.key.toLowerCase();
     this.keys[key] = true;
     
+    // Spectator camera controls
+    if (this.spectator && !event.repeat) {
+      if (key === 'tab') {
+        this.cycleFollowedPlayer(event.shiftKey ? -1 : 1);
+        event.preventDefault();
+      } else if (key === 'v') {
+        this.toggleSpectatorMode();
+      }
+    }
+    
     // Special handling for space key
     if (key === ' ' || key === 'space') {
       // Space key for firing
@@ -2377,13 +2826,22 @@ export class GameComponent extends LitElement {
   }
   
   private handleMouseMove(event: MouseEvent) {
-    // Skip if we don't have a player tank
-    if (!this.playerTank) return;
-    
     // Get mouse movement deltas
     const movementX = event.movementX || (event as any).mozMovementX || (event as any).webkitMovementX || 0;
     const movementY = event.movementY || (event as any).mozMovementY || (event as any).webkitMovementY || 0;
     
+    // Spectators look around with the free camera
+    if (this.spectator) {
+      if (this.spectatorMode === 'free') {
+        this.freeCameraYaw -= movementX * this.FREE_CAMERA_LOOK_SENSITIVITY;
+        this.freeCameraPitch = Math.max(-1.4, Math.min(1.4, this.freeCameraPitch - movementY * this.FREE_CAMERA_LOOK_SENSITIVITY));
+      }
+      return;
+    }
+    
+    // Skip if we don't have a player tank
+    if (!this.playerTank) return;
+    
     // Update mouse position
     this.mouseX += movementX;
     this.mouseY += movementY;
@@ -2508,8 +2966,6 @@ export class GameComponent extends LitElement {
     // Update kill notifications every 5 frames
     if (this.frameCounter % 5 === 0) {
       this.updateKillNotifications();
-      // Check for any server-reported death events
-      this.checkForDeathNotifications();
     }
     
     // Run the collision system check
@@ -2535,6 +2991,12 @@ export class GameComponent extends LitElement {
       } else {
         // Update tank and check if shell was fired
         const newShell = this.playerTank.update(this.keys, allColliders);
+        
+        // Keep the tank on the terrain, as the server does
+        if (this.mapGenerator) {
+          const position = this.playerTank.tank.position;
+          position.y = this.mapGenerator.heightAt(position.x, position.z);
+        }
         if (newShell) {
           this.addShell(newShell);
         }
@@ -2556,6 +3018,11 @@ export class GameComponent extends LitElement {
       }
     }
     
+    // Spectators steer the camera instead of a tank
+    if (this.spectator) {
+      this.updateSpectatorCamera();
+    }
+    
     // Update player health tracking
     if (this.playerTank) {
       const currentHealth = this.playerTank.getHealth();
@@ -2623,8 +3090,8 @@ export class GameComponent extends LitElement {
       this.showPlayerHitEffects();
     }
     
-    // Always forward the hit event to server
-    // This is important to ensure hits register at all distances
+    // Report hits by our own shells to the server as hints
+    // The server detects every hit itself and only uses these to validate clients
     if (source && source !== tank) {
       // Skip if this is a network event to prevent loops
       if (event.detail.isNetworkEvent) {
@@ -2635,6 +3102,11 @@ export class GameComponent extends LitElement {
       let targetId = '';
       let sourceId = source.getOwnerId ? source.getOwnerId() : 'unknown';
       
+      // Hits by other tanks' shells are reported by their owners
+      if (sourceId !== this.playerId) {
+        return;
+      }
+      
       // Check if it's the player tank
       if (tank === this.playerTank) {
         targetId = this.playerId;
@@ -2661,7 +3133,7 @@ export class GameComponent extends LitElement {
       if (targetId) {
         console.log(`Sending tank hit for ${sourceId} -> ${targetId} (${hitLocation || 'body'}) to server`);
         
-        // Estimate damage amount (server will recalculate this)
+        // Estimate damage amount (the server applies its own damage)
         // Different hit locations have different damage multipliers
         let estimatedDamage = 20; // Base damage
         if (hitLocation === 'turret') estimatedDamage = 25;
@@ -2903,10 +3375,15 @@ export class GameComponent extends LitElement {
       
       const spawnPoint = new THREE.Vector3(
         Math.cos(angle) * distance,
-        0, // Y is always 0 (ground level)
+        0,
         Math.sin(angle) * distance
       );
       
+      // Place the tank on the terrain
+      if (this.mapGenerator) {
+        spawnPoint.y = this.mapGenerator.heightAt(spawnPoint.x, spawnPoint.z);
+      }
+      
       // Check if this position collides with any objects
       if (this.isValidSpawnPosition(spawnPoint)) {
         console.log(`Found valid spawn point at (${spawnPoint.x.toFixed(2)}, ${spawnPoint.z.toFixed(2)}) on attempt ${attempt + 1}`);
@@ -3010,17 +3487,25 @@ export class GameComponent extends LitElement {
       }
       
       (statsComponent as any).updateGameStats(health, kills, deaths, playerCount);
+      
+      // Show team scores in team modes
+      const teams = this.multiplayerState?.teams ? Object.values(this.multiplayerState.teams) : [];
+      (statsComponent as any).updateTeamScores(teams, this.multiplayerState?.match?.winner || '', !!this.multiplayerState?.match?.over);
+      
+      // Show the match phase and its countdown
+      const match = this.multiplayerState?.match;
+      (statsComponent as any).updateMatchPhase(match?.phase || '', match?.countdown || 0, match?.map || '');
     }
   }
   
   /**
-   * Emits a custom event with player position and orientation data
-   * Event includes position coordinates, tank rotation, turret rotation, and barrel elevation
+   * Emits the player's control input to the server
+   * The server simulates tank movement from throttle/steer; aim is sent directly
    */
   private emitPlayerPositionEvent(): void {
     if (!this.playerTank) return;
     
-    // Don't send position updates if the player is destroyed
+    // Don't send input if the player is destroyed
     // This prevents client from overriding server respawn behavior
     if (this.playerDestroyed) {
       return;
@@ -3028,44 +3513,27 @@ export class GameComponent extends LitElement {
     
     // Only emit if we have a playerId assigned (important for multiplayer)
     if (!this.playerId) {
-      console.log('Cannot emit position: No player ID assigned yet');
+      console.log('Cannot emit input: No player ID assigned yet');
       return;
     }
     
-    // Create event detail with all relevant position and orientation data
-    // Ensuring all data is clean serializable JSON
+    const control = this.playerTank.getControlInput(this.keys);
+    this.inputSequence++;
+    
+    // Create event detail with control input and aim
     const detail = {
-      // Add player ID
-      id: this.playerId,
-      
-      // Position data (convert THREE.Vector3 to simple object)
-      position: {
-        x: this.playerTank.tank.position.x,
-        y: this.playerTank.tank.position.y,
-        z: this.playerTank.tank.position.z
-      },
-      
-      // Rotation data (simple numeric values)
-      tankRotation: this.playerTank.tank.rotation.y,
+      throttle: control.throttle,
+      steer: control.steer,
       turretRotation: this.playerTank.turretPivot.rotation.y,
       barrelElevation: this.playerTank.barrelPivot.rotation.x,
-      
-      // Additional data
-      health: this.playerTank.getHealth(),
-      isMoving: this.playerTank.isMoving(),
-      velocity: this.playerTank.getVelocity ? this.playerTank.getVelocity() : 0,
-      
-      // Timestamp for tracking
+      seq: this.inputSequence,
       timestamp: Date.now()
     };
     
-    // Position updates are frequent, so don't log them
-    // console.log(`Emitting position update for player ${this.playerId}`);
-    
     // Create a custom event using the new consolidated format
     const gameEvent = new CustomEvent('game-event', { 
-      detail: {
-        type: "PLAYER_UPDATE",
+      detail: this.binaryWire ? encodeInput(detail) : {
+        type: "PLAYER_INPUT",
         data: detail,
         playerId: this.playerId,
         timestamp: Date.now()
@@ -3076,6 +3544,241 @@ export class GameComponent extends LitElement {
     
     this.dispatchEvent(gameEvent);
   }
+  
+  /**
+   * Moves the spectator camera behind the followed tank, or flies it from the keyboard
+   * in free mode. With nobody to follow the free camera is used.
+   */
+  private updateSpectatorCamera() {
+    if (!this.camera) return;
+    
+    const up = new THREE.Vector3(0, 1, 0);
+    const target = this.spectatorMode === 'follow' ? this.followedTank() : undefined;
+    if (target) {
+      // Same chase view a player has of their own tank, a little higher
+      const angle = target.tank.rotation.y + target.turretPivot.rotation.y;
+      const offset = new THREE.Vector3(0, 4, -10).applyAxisAngle(up, angle);
+      this.camera.position.copy(target.tank.position).add(offset);
+      
+      const lookDirection = new THREE.Vector3(0, 0, 10).applyAxisAngle(up, angle);
+      this.camera.lookAt(target.tank.position.clone().add(lookDirection).add(up));
+      return;
+    }
+    
+    // Free camera: WASD moves along the view direction, Q/E lowers and raises
+    const forward = new THREE.Vector3(-Math.sin(this.freeCameraYaw), 0, -Math.cos(this.freeCameraYaw));
+    const right = new THREE.Vector3(-forward.z, 0, forward.x);
+    const move = new THREE.Vector3();
+    if (this.keys['w']) move.add(forward);
+    if (this.keys['s']) move.sub(forward);
+    if (this.keys['d']) move.add(right);
+    if (this.keys['a']) move.sub(right);
+    if (this.keys['e']) move.add(up);
+    if (this.keys['q']) move.sub(up);
+    if (move.lengthSq() > 0) {
+      this.camera.position.add(move.normalize().multiplyScalar(this.FREE_CAMERA_SPEED));
+    }
+    
+    // Stay above the terrain
+    const ground = this.mapGenerator ? this.mapGenerator.heightAt(this.camera.position.x, this.camera.position.z) : 0;
+    this.camera.position.y = Math.max(this.camera.position.y, ground + 2);
+    
+    this.camera.rotation.set(this.freeCameraPitch, this.freeCameraYaw, 0, 'YXZ');
+  }
+  
+  /**
+   * Returns the tank the spectator follows, picking the first one if the
+   * followed player has left or none was chosen yet
+   */
+  private followedTank(): RemoteTank | undefined {
+    if (!this.remoteTanks.has(this.followedPlayerId)) {
+      const ids = Array.from(this.remoteTanks.keys()).sort();
+      this.followedPlayerId = ids.length > 0 ? ids[0] : '';
+    }
+    return this.remoteTanks.get(this.followedPlayerId);
+  }
+  
+  /**
+   * Follows the next or previous player in ID order
+   */
+  private cycleFollowedPlayer(step: number) {
+    const ids = Array.from(this.remoteTanks.keys()).sort();
+    if (ids.length === 0) return;
+    
+    const index = Math.max(0, ids.indexOf(this.followedPlayerId));
+    this.followedPlayerId = ids[(index + step + ids.length) % ids.length];
+    this.spectatorMode = 'follow';
+    this.requestUpdate();
+  }
+  
+  /**
+   * Switches between following a tank and the free camera. The free camera
+   * starts from the current view.
+   */
+  private toggleSpectatorMode() {
+    if (this.spectatorMode === 'follow' && this.camera) {
+      const view = new THREE.Euler().setFromQuaternion(this.camera.quaternion, 'YXZ');
+      this.freeCameraYaw = view.y;
+      this.freeCameraPitch = view.x;
+      this.spectatorMode = 'free';
+    } else {
+      this.spectatorMode = 'follow';
+    }
+    this.requestUpdate();
+  }
+  
+  /**
+   * Describes what the spectator is watching
+   */
+  private spectatorLabel(): string {
+    if (this.spectatorMode === 'follow' && this.followedPlayerId) {
+      const player = this.multiplayerState?.players[this.followedPlayerId];
+      if (player) {
+        return `Spectating: ${player.name || this.followedPlayerId}`;
+      }
+    }
+    return 'Spectating: Free camera';
+  }
+  
+  /**
+   * Applies a message from the delta-encoded state stream and returns the full state,
+   * or null if the baseline is missing and a resync has been requested
+   */
+  private applyStateDelta(delta: GameStateDelta): MultiplayerGameState | null {
+    // A new stream means a new connection - old baselines are meaningless
+    if (delta.stream !== this.stateStream) {
+      this.stateStream = delta.stream;
+      this.stateHistory.clear();
+      this.lastAckedRevision = 0;
+    }
+    
+    let state: MultiplayerGameState;
+    if (delta.full) {
+      state = {
+        players: delta.players || {},
+        shells: delta.shells || [],
+      };
+    } else {
+      const base = delta.base !== undefined ? this.stateHistory.get(delta.base) : undefined;
+      if (!base) {
+        // We don't have the state the server diffed against - ask for a full snapshot
+        if (Date.now() - this.lastStateAckTime >= this.STATE_ACK_INTERVAL) {
+          console.warn('Missing baseline for state delta, requesting resync', delta.base);
+          this.sendStateAck(0);
+        }
+        return null;
+      }
+      
+      // Copy the baseline and apply changes
+      const players = { ...base.players };
+      (delta.removedPlayers || []).forEach(id => delete players[id]);
+      Object.entries(delta.players || {}).forEach(([id, player]) => {
+        players[id] = player;
+      });
+      
+      const removedShells = new Set(delta.removedShells || []);
+      const changedShells = new Map((delta.shells || []).map(shell => [shell.id, shell]));
+      const shells = base.shells
+        .filter(shell => !removedShells.has(shell.id))
+        .map(shell => {
+          const changed = changedShells.get(shell.id);
+          if (changed) {
+            changedShells.delete(shell.id);
+            return changed;
+          }
+          return shell;
+        });
+      changedShells.forEach(shell => shells.push(shell));
+      
+      state = { players, shells };
+    }
+    state.tick = delta.tick;
+    state.serverTime = delta.serverTime;
+    this.viewTick = delta.tick;
+    // The page carries the map it was rendered with; reload onto the next one of the rotation
+    const mapName = delta.match?.map || '';
+    if (this.mapName && mapName && mapName !== this.mapName) {
+      window.location.reload();
+      return null;
+    }
+    if (mapName) {
+      this.mapName = mapName;
+    }
+    state.match = delta.match;
+    state.teams = delta.teams;
+    state.flags = delta.flags;
+    state.zones = delta.zones;
+    state.spectators = delta.spectators;
+    state.impacts = delta.impacts;
+    state.destroyed = delta.destroyed;
+    
+    this.stateHistory.set(delta.rev, state);
+    
+    // Ack full snapshots immediately so the server can start sending deltas,
+    // and ack deltas periodically so the baseline stays recent
+    const now = Date.now();
+    if (delta.full || now - this.lastStateAckTime >= this.STATE_ACK_INTERVAL) {
+      // Until this ack arrives the server may still diff against our previous one,
+      // but never against anything older
+      const previousAck = this.lastAckedRevision;
+      this.stateHistory.forEach((_, rev) => {
+        if (rev < previousAck) {
+          this.stateHistory.delete(rev);
+        }
+      });
+      
+      this.sendStateAck(delta.rev);
+      this.lastAckedRevision = delta.rev;
+    }
+    
+    return state;
+  }
+  
+  /**
+   * Acks a state revision on the current stream; revision 0 requests a full resync
+   */
+  private sendStateAck(rev: number) {
+    this.lastStateAckTime = Date.now();
+    
+    // JSON acks offer the codecs we can decode; the server switches the stream to the first it supports
+    const gameEvent = new CustomEvent('game-event', { 
+      detail: this.binaryWire ? encodeAck(this.stateStream, rev) : {
+        type: "STATE_ACK",
+        data: { stream: this.stateStream, rev, codecs: WIRE_CODECS },
+        playerId: this.playerId,
+        timestamp: Date.now()
+      },
+      bubbles: true,
+      composed: true
+    });
+    
+    this.dispatchEvent(gameEvent);
+  }
+  
+  /**
+   * Reconciles the locally predicted tank with the server's authoritative position
+   */
+  private reconcileLocalPlayer(playerData: any) {
+    if (!this.playerTank || !playerData.position || this.playerDestroyed) {
+      return;
+    }
+    
+    const tank = this.playerTank.tank;
+    const dx = playerData.position.x - tank.position.x;
+    const dz = playerData.position.z - tank.position.z;
+    const distance = Math.sqrt(dx * dx + dz * dz);
+    
+    if (distance > this.RECONCILE_SNAP_DISTANCE) {
+      // Too far off - snap to the server state
+      tank.position.x = playerData.position.x;
+      tank.position.z = playerData.position.z;
+      tank.rotation.y = playerData.tankRotation;
+    } else if (distance > 0.01) {
+      // Small drift - ease toward the server state
+      tank.position.x += dx * this.RECONCILE_BLEND;
+      tank.position.z += dz * this.RECONCILE_BLEND;
+    }
+  }
 }
 
 // Define the player movement event type for TypeScript
@@ -1,8 +1,8 @@
 import * as THREE from 'three';
-import { ICollidable } from './collision';
+import { ICollidable, StaticCollider } from './collision';
 import { TreeGenerator, ServerGameMap } from './trees';
 import { RockGenerator, ServerRockMap } from './rocks';
-import { MountainGenerator } from './mountains';
+import { Terrain, ServerTerrain } from './terrain';
 
 // Complete map interfaces
 interface CompleteServerGameMap {
@@ -12,21 +12,20 @@ interface CompleteServerGameMap {
   rocks: {
     rocks: any[];
   };
+  terrain?: ServerTerrain;
 }
 
 export class MapGenerator {
   private scene: THREE.Scene;
   private treeGenerator: TreeGenerator;
   private rockGenerator: RockGenerator;
-  private mountainGenerator: MountainGenerator;
+  private terrain: Terrain;
   private serverMapData: CompleteServerGameMap | null = null;
   
   constructor(scene: THREE.Scene, serverMapData?: string) {
     this.scene = scene;
     this.treeGenerator = new TreeGenerator(scene);
     this.rockGenerator = new RockGenerator(scene);
-    this.mountainGenerator = new MountainGenerator(scene);
-    
     // Parse server map data if provided
     if (serverMapData) {
       try {
@@ -47,6 +46,9 @@ export class MapGenerator {
       }
     }
     
+    // Mountains come from the server heightmap
+    this.terrain = new Terrain(scene, this.serverMapData?.terrain);
+    
     // Generate trees after initialization
     this.treeGenerator.generateTrees();
     
@@ -561,36 +563,18 @@ export class MapGenerator {
   generateTerrain() {
     // Generate rocks after tree generation
     this.rockGenerator.createRocks();
-    
-    // Generate mountains
-    this.generateMountains();
   }
   
-  // Generate mountains in specific areas of the map
-  private generateMountains() {
-    // 1. Northern mountain range
-    this.mountainGenerator.createMountainRangeGroup(0, 600, 800, 300, 5, 12345);
-    
-    // 2. Eastern mountain range
-    this.mountainGenerator.createMountainRangeGroup(600, 0, 300, 800, 4, 54321);
-    
-    // 3. Southwestern mountains
-    this.mountainGenerator.createMountainRangeGroup(-500, -500, 400, 400, 3, 98765);
-    
-    // 4. Create some individual mountain peaks at specific locations
-    this.mountainGenerator.createMountainRange(350, 350, 200, 200, 180, 64, 24680);
-    this.mountainGenerator.createMountainRange(-350, 350, 180, 180, 150, 64, 13579);
-    
-    // 5. Add a small mountain near the center for gameplay variety
-    this.mountainGenerator.createMountainRange(80, -80, 120, 120, 90, 48, 11223);
+  // Ground height at a world position, from the server heightmap
+  heightAt(x: number, z: number): number {
+    return this.terrain.heightAt(x, z);
   }
   
   // Methods to get colliders for collision detection
   getAllColliders(): ICollidable[] {
     return [
       ...this.treeGenerator.getTreeColliders(), 
-      ...this.rockGenerator.getRockColliders(),
-      ...this.mountainGenerator.getMountainColliders()
+      ...this.rockGenerator.getRockColliders()
     ];
   }
   
@@ -602,7 +586,13 @@ export class MapGenerator {
     return this.rockGenerator.getRockColliders();
   }
   
-  getMountainColliders(): ICollidable[] {
-    return this.mountainGenerator.getMountainColliders();
+  // Hides a rock broken by shells and returns its collider
+  hideRockAt(position: THREE.Vector3): ICollidable | null {
+    return this.rockGenerator.hideRockAt(position);
+  }
+  
+  // Shows a broken rock again once it is rebuilt
+  showRock(collider: ICollidable) {
+    this.rockGenerator.showRock(collider as StaticCollider);
   }
 }
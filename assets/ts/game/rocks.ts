@@ -34,6 +34,8 @@ export class RockGenerator {
   
   // Collider arrays for environment objects
   private rockColliders: StaticCollider[] = [];
+  // Rock meshes by collider, so rocks broken by shells can be hidden
+  private rockMeshes: Map<StaticCollider, THREE.Mesh> = new Map();
   
   // Materials
   private rockMaterial: THREE.MeshStandardMaterial;
@@ -109,10 +111,30 @@ export class RockGenerator {
     const position = colliderPosition ? colliderPosition.clone() : new THREE.Vector3(x, y, z);
     const rockCollider = new StaticCollider(position, 'rock', collisionRadius);
     this.rockColliders.push(rockCollider);
+    this.rockMeshes.set(rockCollider, rock);
     
     return rock;
   }
   
+  // Hides the rock whose collider is at the given position and returns its collider
+  hideRockAt(position: THREE.Vector3): StaticCollider | null {
+    for (const [collider, mesh] of this.rockMeshes) {
+      if (mesh.visible && collider.getPosition().distanceTo(position) < 0.01) {
+        mesh.visible = false;
+        return collider;
+      }
+    }
+    return null;
+  }
+  
+  // Shows a rock hidden by hideRockAt again
+  showRock(collider: StaticCollider) {
+    const mesh = this.rockMeshes.get(collider);
+    if (mesh) {
+      mesh.visible = true;
+    }
+  }
+  
   createRockCluster(centerX: number, centerZ: number, seed: number) {
     const cluster = new THREE.Group();
     
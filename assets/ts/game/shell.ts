@@ -33,6 +33,9 @@ export class Shell implements ICollidable {
   // Direction the shell is traveling - needed for network sync
   private direction: THREE.Vector3;
 
+  // Ground height lookup, set once the map's terrain is loaded
+  public static groundHeight: (x: number, z: number) => number = () => 0;
+  
   // Static shared geometry for all shells
   private static shellGeometry: THREE.SphereGeometry;
   private static shellMaterial: THREE.MeshStandardMaterial;
@@ -136,12 +139,13 @@ export class Shell implements ICollidable {
     // Update trail
     this.updateTrail();
     
-    // Check if shell is below ground (y = 0)
-    if (this.mesh.position.y < 0) {
+    // Check if shell is below the terrain
+    const groundHeight = Shell.groundHeight(this.mesh.position.x, this.mesh.position.z);
+    if (this.mesh.position.y < groundHeight) {
       // Create explosion effect at ground level with appropriate angle
       const hitPosition = new THREE.Vector3(
         this.mesh.position.x,
-        0,
+        groundHeight,
         this.mesh.position.z
       );
       
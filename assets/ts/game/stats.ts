@@ -20,6 +20,16 @@ export class GameStats extends LitElement {
   @state() private deaths: number = 0;
   @state() private playersOnline: number = 0;
   
+  // Team scores (empty outside team modes)
+  @state() private teams: { id: string; name: string; color: string; score: number }[] = [];
+  @state() private winner: string = '';
+  @state() private matchOver: boolean = false;
+  
+  // Match lifecycle
+  @state() private matchPhase: string = '';
+  @state() private countdown: number = 0;
+  @state() private mapName: string = '';
+  
   // Frame time tracking
   private frameTimeHistory: number[] = [];
   private readonly HISTORY_SIZE = 30; // Average over this many frames
@@ -140,6 +150,40 @@ export class GameStats extends LitElement {
         <span class="stat-value">${this.playersOnline}</span>
       </div>
       
+      ${this.matchPhase ? html`
+        <div class="section">
+          <div class="title">Match</div>
+          <div class="stat-row">
+            <span class="stat-label">${this.getPhaseLabel()}</span>
+            <span class="stat-value">${this.countdown > 0 ? this.formatCountdown(this.countdown) : ''}</span>
+          </div>
+          ${this.mapName ? html`
+            <div class="stat-row">
+              <span class="stat-label">Map</span>
+              <span class="stat-value">${this.mapName}</span>
+            </div>
+          ` : ''}
+        </div>
+      ` : ''}
+      
+      ${this.teams.length > 0 ? html`
+        <div class="section">
+          <div class="title">Team Score</div>
+          ${this.teams.map(team => html`
+            <div class="stat-row">
+              <span class="stat-label" style="color: ${team.color}">${team.name}</span>
+              <span class="stat-value" style="color: ${team.color}">${team.score}</span>
+            </div>
+          `)}
+          ${this.matchOver ? html`
+            <div class="stat-row">
+              <span class="stat-label">Result</span>
+              <span class="stat-value">${this.getWinnerLabel()}</span>
+            </div>
+          ` : ''}
+        </div>
+      ` : ''}
+      
       <div class="section">
         <div class="title">Performance</div>
         <div class="stat-row">
@@ -299,6 +343,56 @@ export class GameStats extends LitElement {
     this.requestUpdate();
   }
   
+  /**
+   * Update team scores and match result in team modes
+   */
+  updateTeamScores(teams: { id: string; name: string; color: string; score: number }[], winner: string, matchOver: boolean) {
+    this.teams = [...teams].sort((a, b) => a.id.localeCompare(b.id));
+    this.winner = winner;
+    this.matchOver = matchOver;
+    this.requestUpdate();
+  }
+  
+  /**
+   * Name of the winning team, or a draw
+   */
+  private getWinnerLabel(): string {
+    const team = this.teams.find(t => t.id === this.winner);
+    return team ? `${team.name} wins` : 'Draw';
+  }
+  
+  /**
+   * Update the match lifecycle phase and countdown
+   */
+  updateMatchPhase(phase: string, countdown: number, mapName: string) {
+    this.matchPhase = phase;
+    this.countdown = countdown;
+    this.mapName = mapName;
+    this.requestUpdate();
+  }
+  
+  /**
+   * Human readable name of the match phase
+   */
+  private getPhaseLabel(): string {
+    switch (this.matchPhase) {
+      case 'waiting': return 'Waiting for players';
+      case 'warmup': return 'Warmup';
+      case 'live': return 'Live';
+      case 'post_match': return 'Next map in';
+      default: return this.matchPhase;
+    }
+  }
+  
+  /**
+   * Format seconds as m:ss
+   */
+  private formatCountdown(seconds: number): string {
+    const minutes = Math.floor(seconds / 60);
+    const rest = seconds % 60;
+    return `${minutes}:${rest.toString().padStart(2, '0')}`;
+  }
+  
   /**
    * Update players online count
    */
@@ -2171,6 +2171,19 @@ export class Tank extends BaseTank {
     this.inputIntensities[direction] = Math.max(0, Math.min(1, intensity));
   }
   
+  // Returns the current throttle (-1..1) and steer (-1..1, positive turns left) for the server
+  public getControlInput(keys: {[key: string]: boolean}): { throttle: number; steer: number } {
+    const forwardInput = keys['w'] || keys['W'] ? 1 : this.inputIntensities['forward'];
+    const backwardInput = keys['s'] || keys['S'] ? 1 : this.inputIntensities['backward'];
+    const leftInput = keys['a'] || keys['A'] ? 1 : this.inputIntensities['left'];
+    const rightInput = keys['d'] || keys['D'] ? 1 : this.inputIntensities['right'];
+    
+    return {
+      throttle: forwardInput > 0 ? forwardInput : -backwardInput,
+      steer: leftInput - rightInput
+    };
+  }
+  
   // Method to handle physics-based movement
   private updateMovementWithPhysics(keys: {[key: string]: boolean}) {
     // Reset velocity variables
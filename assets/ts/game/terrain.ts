@@ -0,0 +1,138 @@
+import * as THREE from 'three';
+
+// Heightmap sent by the server with the rest of the map
+export interface ServerTerrain {
+  seed: number;
+  minX: number;
+  minZ: number;
+  cellSize: number;
+  cols: number;
+  rows: number;
+  heightStep: number;
+  heights: string; // Base64 little-endian uint16 samples, row-major, in units of heightStep
+}
+
+// Height above which the terrain is covered in snow
+const SNOW_LINE = 100;
+
+/**
+ * Terrain renders the server's heightmap and answers ground height queries.
+ * Heights between samples are interpolated across the same two triangles per
+ * cell as the server, so tanks and shells agree with it on where the ground is.
+ */
+export class Terrain {
+  private minX = 0;
+  private minZ = 0;
+  private cellSize = 1;
+  private cols = 0;
+  private rows = 0;
+  private heights: Float32Array = new Float32Array(0);
+
+  constructor(scene: THREE.Scene, data?: ServerTerrain) {
+    if (!data || !data.heights) {
+      return;
+    }
+
+    this.minX = data.minX;
+    this.minZ = data.minZ;
+    this.cellSize = data.cellSize;
+    this.cols = data.cols;
+    this.rows = data.rows;
+
+    // Decode the samples
+    const bytes = Uint8Array.from(atob(data.heights), c => c.charCodeAt(0));
+    const view = new DataView(bytes.buffer);
+    this.heights = new Float32Array(this.cols * this.rows);
+    for (let i = 0; i < this.heights.length; i++) {
+      this.heights[i] = view.getUint16(i * 2, true) * data.heightStep;
+    }
+
+    scene.add(this.createMesh());
+  }
+
+  // Ground height at a world position; flat ground at 0 outside the heightmap
+  heightAt(x: number, z: number): number {
+    if (this.heights.length === 0) {
+      return 0;
+    }
+
+    const gx = (x - this.minX) / this.cellSize;
+    const gz = (z - this.minZ) / this.cellSize;
+    const col = Math.floor(gx);
+    const row = Math.floor(gz);
+    const fx = gx - col;
+    const fz = gz - row;
+
+    const h00 = this.sample(col, row);
+    const h10 = this.sample(col + 1, row);
+    const h01 = this.sample(col, row + 1);
+    const h11 = this.sample(col + 1, row + 1);
+
+    if (fx + fz <= 1) {
+      return h00 + fx * (h10 - h00) + fz * (h01 - h00);
+    }
+    return h11 + (1 - fx) * (h01 - h11) + (1 - fz) * (h10 - h11);
+  }
+
+  private sample(col: number, row: number): number {
+    if (col < 0 || row < 0 || col >= this.cols || row >= this.rows) {
+      return 0;
+    }
+    return this.heights[row * this.cols + col];
+  }
+
+  // Builds one mesh for all mountains, with snow on the peaks
+  private createMesh(): THREE.Mesh {
+    const positions = new Float32Array(this.cols * this.rows * 3);
+    const colors = new Float32Array(this.cols * this.rows * 3);
+    const rock = new THREE.Color(0x61584b); // Brown-gray
+    const snow = new THREE.Color(0xffffff);
+
+    for (let row = 0; row < this.rows; row++) {
+      for (let col = 0; col < this.cols; col++) {
+        const i = row * this.cols + col;
+        const height = this.heights[i];
+
+        // Flat samples sink below the ground plane so only the mountains show
+        positions[i * 3] = this.minX + col * this.cellSize;
+        positions[i * 3 + 1] = height > 0 ? height : -1;
+        positions[i * 3 + 2] = this.minZ + row * this.cellSize;
+
+        const color = height > SNOW_LINE ? snow : rock;
+        colors[i * 3] = color.r;
+        colors[i * 3 + 1] = color.g;
+        colors[i * 3 + 2] = color.b;
+      }
+    }
+
+    // Two triangles per cell, split along the diagonal from (col+1, row) to (col, row+1)
+    const indices: number[] = [];
+    for (let row = 0; row < this.rows - 1; row++) {
+      for (let col = 0; col < this.cols - 1; col++) {
+        const a = row * this.cols + col;
+        const b = a + 1;
+        const c = a + this.cols;
+        const d = c + 1;
+        indices.push(a, c, b, c, d, b);
+      }
+    }
+
+    const geometry = new THREE.BufferGeometry();
+    geometry.setAttribute('position', new THREE.BufferAttribute(positions, 3));
+    geometry.setAttribute('color', new THREE.BufferAttribute(colors, 3));
+    geometry.setIndex(indices);
+    geometry.computeVertexNormals();
+
+    const material = new THREE.MeshStandardMaterial({
+      vertexColors: true,
+      roughness: 0.9,
+      metalness: 0.1
+    });
+
+    const mesh = new THREE.Mesh(geometry, material);
+    mesh.name = 'terrain';
+    mesh.castShadow = true;
+    mesh.receiveShadow = true;
+    return mesh;
+  }
+}
@@ -0,0 +1,428 @@
+package codec
+
+import (
+	"fmt"
+
+	"tank-game/game"
+)
+
+// binaryVersion is the first byte of every binary message. It changes whenever
+// the layout below does, so mismatched clients fail loudly instead of misreading.
+const binaryVersion = 1
+
+// Message kinds of the binary format
+const (
+	kindState byte = 1 // State stream message
+	kindInput byte = 2 // PLAYER_INPUT event
+	kindAck   byte = 3 // STATE_ACK event
+)
+
+// Known values of string enums, sent as their position in the table. Only append
+// to these: the positions are part of the format.
+var (
+	statusValues   = []string{string(game.StatusReady), string(game.StatusActive), string(game.StatusDestroyed), string(game.StatusDisconnect)}
+	modeValues     = []string{string(game.ModeFreeForAll), string(game.ModeTeamDeathmatch), string(game.ModeCaptureTheFlag), string(game.ModeKingOfTheHill)}
+	phaseValues    = []string{string(game.MatchPhaseWaiting), string(game.MatchPhaseWarmup), string(game.MatchPhaseLive), string(game.MatchPhasePostMatch)}
+	obstacleValues = []string{game.ObstacleTree, game.ObstacleRock}
+)
+
+// Bits of the flags byte of a player
+const (
+	playerMoving    = 1 << 0
+	playerDestroyed = 1 << 1
+)
+
+// binaryCodec is a compact format that quantizes positions and rotations and
+// sends timestamps relative to the message's server time. Only state messages,
+// inputs and acks have a binary form; other events are sent as JSON.
+type binaryCodec struct{}
+
+func (binaryCodec) Name() string {
+	return Binary
+}
+
+func (binaryCodec) EncodeState(delta game.StateDelta) ([]byte, error) {
+	w := &writer{buf: make([]byte, 0, 256)}
+	w.byte(binaryVersion)
+	w.byte(kindState)
+
+	ref := delta.ServerTime
+	w.string(delta.Stream)
+	w.uvarint(delta.Revision)
+	w.uvarint(delta.BaseRevision)
+	w.bool(delta.Full)
+	w.uvarint(delta.Tick)
+	w.varint(delta.ServerTime)
+	writeMatch(w, delta.Match, ref)
+
+	// Teams are keyed by their ID and flags by their team
+	w.uvarint(uint64(len(delta.Teams)))
+	for _, team := range delta.Teams {
+		w.string(team.ID)
+		w.string(team.Name)
+		w.string(team.Color)
+		w.varint(int64(team.Score))
+	}
+	w.uvarint(uint64(len(delta.Flags)))
+	for _, flag := range delta.Flags {
+		w.string(flag.Team)
+		w.position(flag.Base, positionScale)
+		w.position(flag.Position, positionScale)
+		w.string(flag.CarrierID)
+		w.bool(flag.Dropped)
+		w.time(flag.DroppedAt, ref)
+	}
+	w.uvarint(uint64(len(delta.Zones)))
+	for _, zone := range delta.Zones {
+		w.string(zone.ID)
+		w.position(zone.Position, positionScale)
+		w.fixed(zone.Radius, positionScale)
+		w.string(zone.Owner)
+		w.string(zone.Capturer)
+		w.fixed(zone.Progress, rateScale)
+		w.bool(zone.Contested)
+	}
+	w.uvarint(uint64(delta.Spectators))
+
+	w.uvarint(uint64(len(delta.Impacts)))
+	for _, impact := range delta.Impacts {
+		w.string(impact.ID)
+		w.string(impact.PlayerID)
+		w.position(impact.Position, positionScale)
+		writeObstacle(w, impact.Obstacle)
+		w.bool(impact.Destroyed)
+		w.time(impact.Timestamp, ref)
+	}
+	w.uvarint(uint64(len(delta.Destroyed)))
+	for _, obstacle := range delta.Destroyed {
+		writeObstacle(w, obstacle)
+	}
+
+	// Players are keyed by their ID
+	w.uvarint(uint64(len(delta.Players)))
+	for _, player := range delta.Players {
+		writePlayer(w, player, ref)
+	}
+	w.uvarint(uint64(len(delta.Shells)))
+	for _, shell := range delta.Shells {
+		w.string(shell.ID)
+		w.string(shell.PlayerID)
+		w.position(shell.Position, positionScale)
+		w.position(shell.Direction, directionScale)
+		w.fixed(shell.Speed, rateScale)
+		w.time(shell.Timestamp, ref)
+	}
+	writeStrings(w, delta.RemovedPlayers)
+	writeStrings(w, delta.RemovedShells)
+
+	return w.buf, nil
+}
+
+func (binaryCodec) DecodeState(data []byte) (game.StateDelta, error) {
+	r := &reader{buf: data}
+	if err := readHeader(r, kindState); err != nil {
+		return game.StateDelta{}, err
+	}
+
+	var delta game.StateDelta
+	delta.Stream = r.string()
+	delta.Revision = r.uvarint()
+	delta.BaseRevision = r.uvarint()
+	delta.Full = r.bool()
+	delta.Tick = r.uvarint()
+	delta.ServerTime = r.varint()
+	ref := delta.ServerTime
+	delta.Match = readMatch(r, ref)
+
+	if n := r.count(); n > 0 {
+		delta.Teams = make(map[string]game.TeamState, n)
+		for i := 0; i < n; i++ {
+			team := game.TeamState{
+				ID:    r.string(),
+				Name:  r.string(),
+				Color: r.string(),
+				Score: int(r.varint()),
+			}
+			delta.Teams[team.ID] = team
+		}
+	}
+	if n := r.count(); n > 0 {
+		delta.Flags = make(map[string]game.FlagState, n)
+		for i := 0; i < n; i++ {
+			flag := game.FlagState{
+				Team:      r.string(),
+				Base:      r.position(positionScale),
+				Position:  r.position(positionScale),
+				CarrierID: r.string(),
+				Dropped:   r.bool(),
+				DroppedAt: r.time(ref),
+			}
+			delta.Flags[flag.Team] = flag
+		}
+	}
+	if n := r.count(); n > 0 {
+		delta.Zones = make([]game.ZoneState, n)
+		for i := range delta.Zones {
+			delta.Zones[i] = game.ZoneState{
+				ID:        r.string(),
+				Position:  r.position(positionScale),
+				Radius:    r.fixed(positionScale),
+				Owner:     r.string(),
+				Capturer:  r.string(),
+				Progress:  r.fixed(rateScale),
+				Contested: r.bool(),
+			}
+		}
+	}
+	delta.Spectators = int(r.uvarint())
+
+	if n := r.count(); n > 0 {
+		delta.Impacts = make([]game.ImpactState, n)
+		for i := range delta.Impacts {
+			delta.Impacts[i] = game.ImpactState{
+				ID:        r.string(),
+				PlayerID:  r.string(),
+				Position:  r.position(positionScale),
+				Obstacle:  readObstacle(r),
+				Destroyed: r.bool(),
+				Timestamp: r.time(ref),
+			}
+		}
+	}
+	if n := r.count(); n > 0 {
+		delta.Destroyed = make([]game.ObstacleState, n)
+		for i := range delta.Destroyed {
+			delta.Destroyed[i] = readObstacle(r)
+		}
+	}
+
+	if n := r.count(); n > 0 {
+		delta.Players = make(map[string]game.PlayerState, n)
+		for i := 0; i < n; i++ {
+			player := readPlayer(r, ref)
+			delta.Players[player.ID] = player
+		}
+	}
+	if n := r.count(); n > 0 {
+		delta.Shells = make([]game.ShellState, n)
+		for i := range delta.Shells {
+			delta.Shells[i] = game.ShellState{
+				ID:        r.string(),
+				PlayerID:  r.string(),
+				Position:  r.position(positionScale),
+				Direction: r.position(directionScale),
+				Speed:     r.fixed(rateScale),
+				Timestamp: r.time(ref),
+			}
+		}
+	}
+	delta.RemovedPlayers = readStrings(r)
+	delta.RemovedShells = readStrings(r)
+
+	if r.err != nil {
+		return game.StateDelta{}, fmt.Errorf("failed to decode state: %v", r.err)
+	}
+	return delta, nil
+}
+
+func (binaryCodec) EncodeEvent(event game.GameEvent) ([]byte, error) {
+	w := &writer{}
+	w.byte(binaryVersion)
+
+	switch data := event.Data.(type) {
+	case game.PlayerInput:
+		w.byte(kindInput)
+		w.fixed(data.Throttle, rateScale)
+		w.fixed(data.Steer, rateScale)
+		w.fixed(data.TurretRotation, angleScale)
+		w.fixed(data.BarrelElevation, angleScale)
+		w.uvarint(uint64(data.Sequence))
+		w.varint(data.Timestamp)
+	case game.StateAck:
+		w.byte(kindAck)
+		w.string(data.Stream)
+		w.uvarint(data.Revision)
+		writeStrings(w, data.Codecs)
+	default:
+		return nil, fmt.Errorf("event %s has no binary form", event.Type)
+	}
+	return w.buf, nil
+}
+
+func (binaryCodec) DecodeEvent(data []byte) (game.GameEvent, error) {
+	r := &reader{buf: data}
+	if err := readHeader(r, 0); err != nil {
+		return game.GameEvent{}, err
+	}
+
+	var event game.GameEvent
+	switch kind := data[1]; kind {
+	case kindInput:
+		input := game.PlayerInput{
+			Throttle:        r.fixed(rateScale),
+			Steer:           r.fixed(rateScale),
+			TurretRotation:  r.fixed(angleScale),
+			BarrelElevation: r.fixed(angleScale),
+			Sequence:        uint32(r.uvarint()),
+			Timestamp:       r.varint(),
+		}
+		event = game.GameEvent{Type: game.EventPlayerInput, Data: input, Timestamp: input.Timestamp}
+	case kindAck:
+		ack := game.StateAck{
+			Stream:   r.string(),
+			Revision: r.uvarint(),
+			Codecs:   readStrings(r),
+		}
+		event = game.GameEvent{Type: game.EventStateAck, Data: ack}
+	default:
+		return game.GameEvent{}, fmt.Errorf("unknown binary message kind %d", kind)
+	}
+
+	if r.err != nil {
+		return game.GameEvent{}, fmt.Errorf("failed to decode event: %v", r.err)
+	}
+	return event, nil
+}
+
+// readHeader checks the version and kind of a message. A kind of 0 accepts any
+// kind, leaving it for the caller to read.
+func readHeader(r *reader, kind byte) error {
+	version := r.byte()
+	got := r.byte()
+	if r.err != nil {
+		return fmt.Errorf("failed to decode message: %v", r.err)
+	}
+	if version != binaryVersion {
+		return fmt.Errorf("unsupported binary format version %d", version)
+	}
+	if kind != 0 && got != kind {
+		return fmt.Errorf("unexpected binary message kind %d", got)
+	}
+	return nil
+}
+
+func writeMatch(w *writer, match game.MatchState, ref int64) {
+	w.enum(modeValues, string(match.Mode))
+	w.varint(int64(match.ScoreLimit))
+	w.time(match.EndsAt, ref)
+	w.bool(match.Over)
+	w.time(match.EndedAt, ref)
+	w.string(match.Winner)
+	w.enum(phaseValues, string(match.Phase))
+	w.time(match.PhaseEndsAt, ref)
+	w.varint(int64(match.Countdown))
+	w.varint(int64(match.Number))
+	w.time(match.StartedAt, ref)
+	w.string(match.Map)
+}
+
+func readMatch(r *reader, ref int64) game.MatchState {
+	var match game.MatchState
+	match.Mode = game.GameModeType(r.enum(modeValues))
+	match.ScoreLimit = int(r.varint())
+	match.EndsAt = r.time(ref)
+	match.Over = r.bool()
+	match.EndedAt = r.time(ref)
+	match.Winner = r.string()
+	match.Phase = game.MatchPhase(r.enum(phaseValues))
+	match.PhaseEndsAt = r.time(ref)
+	match.Countdown = int(r.varint())
+	match.Number = int(r.varint())
+	match.StartedAt = r.time(ref)
+	match.Map = r.string()
+	return match
+}
+
+func writePlayer(w *writer, player game.PlayerState, ref int64) {
+	w.string(player.ID)
+	w.string(player.Name)
+	w.position(player.Position, positionScale)
+	w.fixed(player.TankRotation, angleScale)
+	w.fixed(player.TurretRotation, angleScale)
+	w.fixed(player.BarrelElevation, angleScale)
+	w.varint(int64(player.Health))
+
+	var flags byte
+	if player.IsMoving {
+		flags |= playerMoving
+	}
+	if player.IsDestroyed {
+		flags |= playerDestroyed
+	}
+	w.byte(flags)
+
+	w.fixed(player.Velocity, rateScale)
+	w.time(player.Timestamp, ref)
+	w.string(player.Color)
+	w.enum(statusValues, string(player.Status))
+	w.varint(int64(player.Kills))
+	w.varint(int64(player.Deaths))
+	w.fixed(player.TrackRotation, rateScale)
+	w.string(player.LastKilledBy)
+	w.time(player.LastDeathTime, ref)
+	w.uvarint(uint64(player.LastInputSeq))
+	w.string(player.Team)
+	w.varint(int64(player.Score))
+}
+
+func readPlayer(r *reader, ref int64) game.PlayerState {
+	var player game.PlayerState
+	player.ID = r.string()
+	player.Name = r.string()
+	player.Position = r.position(positionScale)
+	player.TankRotation = r.fixed(angleScale)
+	player.TurretRotation = r.fixed(angleScale)
+	player.BarrelElevation = r.fixed(angleScale)
+	player.Health = int(r.varint())
+
+	flags := r.byte()
+	player.IsMoving = flags&playerMoving != 0
+	player.IsDestroyed = flags&playerDestroyed != 0
+
+	player.Velocity = r.fixed(rateScale)
+	player.Timestamp = r.time(ref)
+	player.Color = r.string()
+	player.Status = game.PlayerStatus(r.enum(statusValues))
+	player.Kills = int(r.varint())
+	player.Deaths = int(r.varint())
+	player.TrackRotation = r.fixed(rateScale)
+	player.LastKilledBy = r.string()
+	player.LastDeathTime = r.time(ref)
+	player.LastInputSeq = uint32(r.uvarint())
+	player.Team = r.string()
+	player.Score = int(r.varint())
+	return player
+}
+
+func writeObstacle(w *writer, obstacle game.ObstacleState) {
+	w.string(obstacle.ID)
+	w.enum(obstacleValues, obstacle.Type)
+	w.position(obstacle.Position, positionScale)
+}
+
+func readObstacle(r *reader) game.ObstacleState {
+	return game.ObstacleState{
+		ID:       r.string(),
+		Type:     r.enum(obstacleValues),
+		Position: r.position(positionScale),
+	}
+}
+
+func writeStrings(w *writer, values []string) {
+	w.uvarint(uint64(len(values)))
+	for _, value := range values {
+		w.string(value)
+	}
+}
+
+func readStrings(r *reader) []string {
+	n := r.count()
+	if n == 0 {
+		return nil
+	}
+	values := make([]string, n)
+	for i := range values {
+		values[i] = r.string()
+	}
+	return values
+}
@@ -0,0 +1,136 @@
+// Package codec encodes the game state stream and client input events for the wire.
+//
+// Clients list the codecs they can decode in their state acks and the server
+// switches their stream to the first one it supports. JSON is the fallback for
+// clients that list none. Datastar signals only carry strings, so binary
+// messages travel base64-encoded; JSON messages are always objects, which tells
+// the two apart without a separate marker.
+package codec
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"tank-game/game"
+)
+
+// Codec names clients negotiate with
+const (
+	JSON   = "json"
+	Binary = "binary"
+)
+
+// Codec is a wire format for state messages and input events
+type Codec interface {
+	Name() string
+	EncodeState(delta game.StateDelta) ([]byte, error)
+	DecodeState(data []byte) (game.StateDelta, error)
+	EncodeEvent(event game.GameEvent) ([]byte, error)
+	DecodeEvent(data []byte) (game.GameEvent, error)
+}
+
+// codecs are the supported codecs by name
+var codecs = map[string]Codec{
+	JSON:   jsonCodec{},
+	Binary: binaryCodec{},
+}
+
+// Negotiate returns the first supported codec of a client's preferences, or JSON
+func Negotiate(preferred []string) Codec {
+	for _, name := range preferred {
+		if c, ok := codecs[name]; ok {
+			return c
+		}
+	}
+	return jsonCodec{}
+}
+
+// StateSignal encodes a state message as a signal value
+func StateSignal(c Codec, delta game.StateDelta) (string, error) {
+	data, err := c.EncodeState(delta)
+	if err != nil {
+		return "", err
+	}
+	return toSignal(c, data), nil
+}
+
+// EventSignal encodes an event as a signal value. Events without a binary form
+// fall back to JSON.
+func EventSignal(c Codec, event game.GameEvent) (string, error) {
+	data, err := c.EncodeEvent(event)
+	if err != nil && c.Name() != JSON {
+		c = jsonCodec{}
+		data, err = c.EncodeEvent(event)
+	}
+	if err != nil {
+		return "", err
+	}
+	return toSignal(c, data), nil
+}
+
+// ParseState decodes a state message signal in either format
+func ParseState(signal string) (game.StateDelta, error) {
+	c, data, err := fromSignal(signal)
+	if err != nil {
+		return game.StateDelta{}, err
+	}
+	return c.DecodeState(data)
+}
+
+// ParseEvent decodes an event signal in either format
+func ParseEvent(signal string) (game.GameEvent, error) {
+	c, data, err := fromSignal(signal)
+	if err != nil {
+		return game.GameEvent{}, err
+	}
+	return c.DecodeEvent(data)
+}
+
+// toSignal wraps an encoded message for a string signal
+func toSignal(c Codec, data []byte) string {
+	if c.Name() == JSON {
+		return string(data)
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// fromSignal unwraps a signal and returns the codec it was encoded with
+func fromSignal(signal string) (Codec, []byte, error) {
+	if strings.HasPrefix(strings.TrimSpace(signal), "{") {
+		return jsonCodec{}, []byte(signal), nil
+	}
+	data, err := base64.StdEncoding.DecodeString(signal)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode binary signal: %v", err)
+	}
+	return binaryCodec{}, data, nil
+}
+
+// jsonCodec is the original format, the struct's JSON
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return JSON
+}
+
+func (jsonCodec) EncodeState(delta game.StateDelta) ([]byte, error) {
+	return json.Marshal(delta)
+}
+
+func (jsonCodec) DecodeState(data []byte) (game.StateDelta, error) {
+	var delta game.StateDelta
+	err := json.Unmarshal(data, &delta)
+	return delta, err
+}
+
+func (jsonCodec) EncodeEvent(event game.GameEvent) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+func (jsonCodec) DecodeEvent(data []byte) (game.GameEvent, error) {
+	var event game.GameEvent
+	err := json.Unmarshal(data, &event)
+	return event, err
+}
@@ -0,0 +1,371 @@
+package codec
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"tank-game/game"
+)
+
+// sampleState returns a reproducible full snapshot of a busy room. Values sit on
+// the binary format's quantization steps so both codecs round-trip them exactly.
+func sampleState(players, shells int) game.StateDelta {
+	r := rand.New(rand.NewSource(1))
+	const serverTime = 1742700000000
+	position := func() game.Position {
+		return game.Position{
+			X: float64(r.Intn(500000)-250000) / positionScale,
+			Y: float64(r.Intn(2000)) / positionScale,
+			Z: float64(r.Intn(500000)-250000) / positionScale,
+		}
+	}
+	angle := func() float64 {
+		return float64(r.Intn(62832)-31416) / angleScale
+	}
+
+	delta := game.StateDelta{
+		Stream:     "stream_12",
+		Revision:   4812,
+		Full:       true,
+		Tick:       96231,
+		ServerTime: serverTime,
+		Match: game.MatchState{
+			Mode:        game.ModeCaptureTheFlag,
+			ScoreLimit:  3,
+			EndsAt:      serverTime + 412000,
+			Phase:       game.MatchPhaseLive,
+			PhaseEndsAt: serverTime + 412000,
+			Countdown:   412,
+			Number:      7,
+			StartedAt:   serverTime - 188000,
+			Map:         "canyon",
+		},
+		Teams: map[string]game.TeamState{
+			game.TeamRed:  {ID: game.TeamRed, Name: "Red", Color: "#f44336", Score: 2},
+			game.TeamBlue: {ID: game.TeamBlue, Name: "Blue", Color: "#2196f3", Score: 1},
+		},
+		Flags: map[string]game.FlagState{
+			game.TeamRed:  {Team: game.TeamRed, Base: position(), Position: position()},
+			game.TeamBlue: {Team: game.TeamBlue, Base: position(), Position: position(), CarrierID: "bot_3", Dropped: true, DroppedAt: serverTime - 2500},
+		},
+		Zones: []game.ZoneState{
+			{ID: "zone_1", Position: position(), Radius: 25, Owner: game.TeamRed, Capturer: game.TeamBlue, Progress: 0.375, Contested: true},
+		},
+		Spectators: 3,
+		Impacts: []game.ImpactState{
+			{ID: "shell_90", PlayerID: "bot_1", Position: position(), Obstacle: game.ObstacleState{ID: "tree_12", Type: game.ObstacleTree, Position: position()}, Timestamp: serverTime - 800},
+			{ID: "shell_91", PlayerID: "bot_2", Position: position(), Obstacle: game.ObstacleState{ID: "rock_4", Type: game.ObstacleRock, Position: position()}, Destroyed: true, Timestamp: serverTime - 150},
+		},
+		Destroyed: []game.ObstacleState{
+			{ID: "rock_4", Type: game.ObstacleRock, Position: position()},
+		},
+		Players: make(map[string]game.PlayerState, players),
+	}
+
+	statuses := []game.PlayerStatus{game.StatusActive, game.StatusActive, game.StatusActive, game.StatusDestroyed}
+	for i := 0; i < players; i++ {
+		id := fmt.Sprintf("k3j%012d", i)
+		if i%2 == 1 {
+			id = fmt.Sprintf("bot_%d", i)
+		}
+		status := statuses[r.Intn(len(statuses))]
+		player := game.PlayerState{
+			ID:              id,
+			Name:            fmt.Sprintf("Tank %d", i),
+			Position:        position(),
+			TankRotation:    angle(),
+			TurretRotation:  angle(),
+			BarrelElevation: float64(r.Intn(3000)) / angleScale,
+			Health:          r.Intn(101),
+			IsMoving:        r.Intn(2) == 0,
+			Velocity:        float64(r.Intn(2000)-1000) / rateScale,
+			Timestamp:       serverTime - int64(r.Intn(100)),
+			Color:           "#f44336",
+			IsDestroyed:     status == game.StatusDestroyed,
+			Status:          status,
+			Kills:           r.Intn(20),
+			Deaths:          r.Intn(20),
+			TrackRotation:   float64(r.Intn(2000)-1000) / rateScale,
+			LastInputSeq:    uint32(r.Intn(100000)),
+			Team:            game.TeamRed,
+			Score:           r.Intn(5),
+		}
+		if status == game.StatusDestroyed {
+			player.LastKilledBy = "bot_1"
+			player.LastDeathTime = serverTime - 1200
+		}
+		delta.Players[id] = player
+	}
+
+	for i := 0; i < shells; i++ {
+		direction := game.Position{X: 0.6, Y: 0.0436, Z: -0.7986}
+		delta.Shells = append(delta.Shells, game.ShellState{
+			ID:        fmt.Sprintf("shell_%d", 1000+i),
+			PlayerID:  fmt.Sprintf("bot_%d", 2*r.Intn(players/2)+1),
+			Position:  position(),
+			Direction: direction,
+			Speed:     float64(r.Intn(5000)) / rateScale,
+			Timestamp: serverTime - int64(r.Intn(3000)),
+		})
+	}
+	return delta
+}
+
+// sampleDelta returns a typical delta message: a few tanks moved and a shell was fired
+func sampleDelta() game.StateDelta {
+	full := sampleState(16, 12)
+	delta := game.StateDelta{
+		Stream:         full.Stream,
+		Revision:       full.Revision + 1,
+		BaseRevision:   full.Revision,
+		Tick:           full.Tick + 1,
+		ServerTime:     full.ServerTime + 50,
+		Match:          full.Match,
+		Teams:          full.Teams,
+		Flags:          full.Flags,
+		Players:        make(map[string]game.PlayerState),
+		Shells:         full.Shells[:1],
+		RemovedShells:  []string{"shell_990"},
+		RemovedPlayers: []string{"bot_17"},
+	}
+	n := 0
+	for id, player := range full.Players {
+		if n == 4 {
+			break
+		}
+		delta.Players[id] = player
+		n++
+	}
+	return delta
+}
+
+// currentSignal wraps an encoded state the way the state stream sends it
+func currentSignal(state string) string {
+	return fmt.Sprintf(`{"gameState": %q}`, state)
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	samples := map[string]game.StateDelta{
+		"full":  sampleState(16, 12),
+		"delta": sampleDelta(),
+		"empty": {Stream: "stream_1", Revision: 1, Full: true, Match: game.MatchState{Mode: game.ModeFreeForAll, Phase: game.MatchPhaseWaiting}},
+	}
+	for _, name := range []string{JSON, Binary} {
+		c := codecs[name]
+		for sample, delta := range samples {
+			signal, err := StateSignal(c, delta)
+			if err != nil {
+				t.Fatalf("%s %s: encode: %v", name, sample, err)
+			}
+			got, err := ParseState(signal)
+			if err != nil {
+				t.Fatalf("%s %s: decode: %v", name, sample, err)
+			}
+			if !reflect.DeepEqual(got, delta) {
+				t.Errorf("%s %s: round trip changed the state\n got: %+v\nwant: %+v", name, sample, got, delta)
+			}
+		}
+	}
+}
+
+func TestBinaryQuantization(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	delta := game.StateDelta{Players: map[string]game.PlayerState{}}
+	want := game.PlayerState{
+		ID:              "player",
+		Position:        game.Position{X: r.Float64()*5000 - 2500, Y: r.Float64() * 20, Z: r.Float64()*5000 - 2500},
+		TankRotation:    r.Float64()*100 - 50, // Rotations are not wrapped
+		TurretRotation:  r.Float64()*2*math.Pi - math.Pi,
+		BarrelElevation: r.Float64() * 0.3,
+		Velocity:        r.Float64()*2 - 1,
+	}
+	delta.Players[want.ID] = want
+
+	data, err := binaryCodec{}.EncodeState(delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := binaryCodec{}.DecodeState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := decoded.Players[want.ID]
+
+	check := func(field string, got, want, scale float64) {
+		if math.Abs(got-want) > 0.5/scale+1e-9 {
+			t.Errorf("%s: got %v, want %v within %v", field, got, want, 0.5/scale)
+		}
+	}
+	check("position.x", got.Position.X, want.Position.X, positionScale)
+	check("position.y", got.Position.Y, want.Position.Y, positionScale)
+	check("position.z", got.Position.Z, want.Position.Z, positionScale)
+	check("tankRotation", got.TankRotation, want.TankRotation, angleScale)
+	check("turretRotation", got.TurretRotation, want.TurretRotation, angleScale)
+	check("barrelElevation", got.BarrelElevation, want.BarrelElevation, angleScale)
+	check("velocity", got.Velocity, want.Velocity, rateScale)
+}
+
+func TestUnknownEnumValues(t *testing.T) {
+	delta := game.StateDelta{
+		Match:   game.MatchState{Mode: "duel", Phase: "overtime"},
+		Players: map[string]game.PlayerState{"p": {ID: "p", Status: "SPECTATING"}},
+	}
+	data, err := binaryCodec{}.EncodeState(delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := binaryCodec{}.DecodeState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, delta) {
+		t.Errorf("round trip changed the state\n got: %+v\nwant: %+v", got, delta)
+	}
+}
+
+func TestEventRoundTrip(t *testing.T) {
+	events := []game.GameEvent{
+		{Type: game.EventPlayerInput, Data: game.PlayerInput{Throttle: 1, Steer: -0.25, TurretRotation: 1.5708, BarrelElevation: 0.12, Sequence: 4711, Timestamp: 1742700000123}},
+		{Type: game.EventStateAck, Data: game.StateAck{Stream: "stream_3", Revision: 99}},
+		{Type: game.EventStateAck, Data: game.StateAck{Stream: "stream_3", Codecs: []string{Binary, JSON}}},
+	}
+	for _, event := range events {
+		signal, err := EventSignal(codecs[Binary], event)
+		if err != nil {
+			t.Fatalf("%s: encode: %v", event.Type, err)
+		}
+		if strings.HasPrefix(signal, "{") {
+			t.Fatalf("%s: sent as JSON: %s", event.Type, signal)
+		}
+		got, err := ParseEvent(signal)
+		if err != nil {
+			t.Fatalf("%s: decode: %v", event.Type, err)
+		}
+		if input, ok := event.Data.(game.PlayerInput); ok {
+			event.Timestamp = input.Timestamp
+		}
+		if !reflect.DeepEqual(got, event) {
+			t.Errorf("%s: round trip changed the event\n got: %+v\nwant: %+v", event.Type, got, event)
+		}
+	}
+}
+
+func TestEventJSONFallback(t *testing.T) {
+	event := game.GameEvent{
+		Type:      game.EventShellFired,
+		Data:      game.ShellData{Speed: 3.5},
+		PlayerID:  "player",
+		Timestamp: 1742700000123,
+	}
+	signal, err := EventSignal(codecs[Binary], event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(signal, "{") {
+		t.Fatalf("event without a binary form was not sent as JSON: %s", signal)
+	}
+	got, err := ParseEvent(signal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != event.Type || got.PlayerID != event.PlayerID || got.Timestamp != event.Timestamp {
+		t.Errorf("got %+v, want %+v", got, event)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		preferred []string
+		want      string
+	}{
+		{nil, JSON},
+		{[]string{"cbor"}, JSON},
+		{[]string{Binary, JSON}, Binary},
+		{[]string{"cbor", JSON, Binary}, JSON},
+	}
+	for _, test := range tests {
+		if got := Negotiate(test.preferred).Name(); got != test.want {
+			t.Errorf("Negotiate(%v) = %s, want %s", test.preferred, got, test.want)
+		}
+	}
+}
+
+func TestBinaryTruncated(t *testing.T) {
+	data, err := binaryCodec{}.EncodeState(sampleState(4, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < len(data); n++ {
+		if _, err := (binaryCodec{}).DecodeState(data[:n]); err == nil {
+			t.Fatalf("decoding the first %d of %d bytes succeeded", n, len(data))
+		}
+	}
+}
+
+// benchmarkEncode reports the size of the signal sent for a state alongside the encoding time
+func benchmarkEncode(b *testing.B, c Codec, delta game.StateDelta) {
+	var signal string
+	for i := 0; i < b.N; i++ {
+		var err error
+		signal, err = StateSignal(c, delta)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(currentSignal(signal))), "bytes/msg")
+}
+
+func benchmarkDecode(b *testing.B, c Codec, delta game.StateDelta) {
+	signal, err := StateSignal(c, delta)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseState(signal); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// The JSON benchmarks are the current format: the state's JSON quoted inside the signals JSON
+func BenchmarkEncodeFullJSON(b *testing.B)    { benchmarkEncode(b, codecs[JSON], sampleState(16, 12)) }
+func BenchmarkEncodeFullBinary(b *testing.B)  { benchmarkEncode(b, codecs[Binary], sampleState(16, 12)) }
+func BenchmarkEncodeDeltaJSON(b *testing.B)   { benchmarkEncode(b, codecs[JSON], sampleDelta()) }
+func BenchmarkEncodeDeltaBinary(b *testing.B) { benchmarkEncode(b, codecs[Binary], sampleDelta()) }
+func BenchmarkDecodeFullJSON(b *testing.B)    { benchmarkDecode(b, codecs[JSON], sampleState(16, 12)) }
+func BenchmarkDecodeFullBinary(b *testing.B)  { benchmarkDecode(b, codecs[Binary], sampleState(16, 12)) }
+func BenchmarkDecodeDeltaJSON(b *testing.B)   { benchmarkDecode(b, codecs[JSON], sampleDelta()) }
+func BenchmarkDecodeDeltaBinary(b *testing.B) { benchmarkDecode(b, codecs[Binary], sampleDelta()) }
+
+func BenchmarkEncodeInputJSON(b *testing.B) {
+	benchmarkEncodeEvent(b, codecs[JSON])
+}
+
+func BenchmarkEncodeInputBinary(b *testing.B) {
+	benchmarkEncodeEvent(b, codecs[Binary])
+}
+
+// benchmarkEncodeEvent reports the size of the gameEvent signal of one input
+func benchmarkEncodeEvent(b *testing.B, c Codec) {
+	event := game.GameEvent{
+		Type:      game.EventPlayerInput,
+		Data:      game.PlayerInput{Throttle: 1, Steer: -0.5, TurretRotation: 1.2345, BarrelElevation: 0.0872, Sequence: 48213, Timestamp: 1742700000123},
+		PlayerID:  "k3j000000000001",
+		Timestamp: 1742700000123,
+	}
+	var signal string
+	for i := 0; i < b.N; i++ {
+		var err error
+		signal, err = EventSignal(c, event)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(fmt.Sprintf(`{"gameEvent": %q}`, signal))), "bytes/msg")
+}
@@ -0,0 +1,193 @@
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"tank-game/game"
+)
+
+// errTruncated is returned when a message ends before all its fields are read
+var errTruncated = errors.New("message truncated")
+
+// Quantization steps of the binary format. Values are sent as the nearest whole
+// number of steps, so they round-trip to within half a step.
+const (
+	positionScale  = 100   // World positions to 1cm
+	angleScale     = 10000 // Rotations in radians
+	directionScale = 10000 // Components of unit vectors
+	rateScale      = 1000  // Speeds, throttle and other small rates
+)
+
+// writer appends the primitives of the binary format to a buffer
+type writer struct {
+	buf []byte
+}
+
+func (w *writer) byte(b byte) {
+	w.buf = append(w.buf, b)
+}
+
+func (w *writer) uvarint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func (w *writer) varint(v int64) {
+	w.buf = binary.AppendVarint(w.buf, v)
+}
+
+func (w *writer) bool(v bool) {
+	if v {
+		w.byte(1)
+	} else {
+		w.byte(0)
+	}
+}
+
+func (w *writer) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+// fixed writes a float as a whole number of 1/scale steps
+func (w *writer) fixed(v float64, scale float64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		v = 0
+	}
+	w.varint(int64(math.Round(v * scale)))
+}
+
+func (w *writer) position(p game.Position, scale float64) {
+	w.fixed(p.X, scale)
+	w.fixed(p.Y, scale)
+	w.fixed(p.Z, scale)
+}
+
+// time writes a server timestamp relative to a reference time, usually the
+// message's server time, so it takes a few bytes instead of six. Zero means unset.
+func (w *writer) time(t, ref int64) {
+	if t == 0 {
+		w.uvarint(0)
+		return
+	}
+	offset := t - ref
+	w.uvarint(uint64(offset<<1^offset>>63) + 1)
+}
+
+// enum writes a string as its position in a table of known values, falling
+// back to the string itself for values the table doesn't know
+func (w *writer) enum(values []string, s string) {
+	for i, value := range values {
+		if value == s {
+			w.uvarint(uint64(i + 1))
+			return
+		}
+	}
+	w.uvarint(0)
+	w.string(s)
+}
+
+// reader reads the primitives of the binary format. The first error is kept and
+// every read after it returns a zero value, so callers check err once at the end.
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+	r.buf = nil
+}
+
+func (r *reader) byte() byte {
+	if len(r.buf) < 1 {
+		r.fail(errTruncated)
+		return 0
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+func (r *reader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.fail(errTruncated)
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *reader) varint() int64 {
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.fail(errTruncated)
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *reader) bool() bool {
+	return r.byte() != 0
+}
+
+func (r *reader) string() string {
+	n := r.uvarint()
+	if n > uint64(len(r.buf)) {
+		r.fail(errTruncated)
+		return ""
+	}
+	s := string(r.buf[:n])
+	r.buf = r.buf[n:]
+	return s
+}
+
+// count reads the length of a list. Every element takes at least one byte, which
+// stops a corrupt length from allocating more than the message could hold.
+func (r *reader) count() int {
+	n := r.uvarint()
+	if n > uint64(len(r.buf)) {
+		r.fail(errTruncated)
+		return 0
+	}
+	return int(n)
+}
+
+func (r *reader) fixed(scale float64) float64 {
+	return float64(r.varint()) / scale
+}
+
+func (r *reader) position(scale float64) game.Position {
+	return game.Position{
+		X: r.fixed(scale),
+		Y: r.fixed(scale),
+		Z: r.fixed(scale),
+	}
+}
+
+func (r *reader) time(ref int64) int64 {
+	v := r.uvarint()
+	if v == 0 {
+		return 0
+	}
+	v--
+	offset := int64(v>>1) ^ -int64(v&1)
+	return ref + offset
+}
+
+func (r *reader) enum(values []string) string {
+	i := r.uvarint()
+	if i == 0 {
+		return r.string()
+	}
+	if i > uint64(len(values)) {
+		r.fail(errors.New("unknown enum value"))
+		return ""
+	}
+	return values[i-1]
+}
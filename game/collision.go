@@ -0,0 +1,110 @@
+package game
+
+import (
+	"math"
+)
+
+// TankCollisionRadius is the radius of a tank's collider on the ground plane
+const TankCollisionRadius = tankCollisionRadius
+
+// TankCorrection is a tank's position and speed after collision response
+type TankCorrection struct {
+	Position Position
+	Velocity float64
+}
+
+// BlocksTanks reports whether an obstacle stops tanks. Colliders entirely above a
+// tank, such as arch tops, do not.
+func (o *GridObstacle) BlocksTanks() bool {
+	return o.Position.Y-o.Radius <= tankHeight
+}
+
+// contactNormal returns the unit vector on the ground plane pointing from center to
+// pos, and how far pos is inside a circle of the given radius around center
+func contactNormal(pos, center Position, radius float64) (nx, nz, depth float64, ok bool) {
+	dx := pos.X - center.X
+	dz := pos.Z - center.Z
+	distSq := dx*dx + dz*dz
+	if distSq >= radius*radius {
+		return 0, 0, 0, false
+	}
+
+	dist := math.Sqrt(distSq)
+	if dist < 0.0001 {
+		// Exactly on the center: push out along +X
+		return 1, 0, radius, true
+	}
+	return dx / dist, dz / dist, radius - dist, true
+}
+
+// slowIntoContact takes away the part of a tank's speed that drives it against a
+// contact normal: a head-on hit stops the tank while a glancing one barely slows it
+func slowIntoContact(player *PlayerState, nx, nz float64) {
+	// Hull direction, using the same axis convention as integrateTankMovement
+	alignment := math.Sin(player.TankRotation)*nx + math.Cos(player.TankRotation)*nz
+	if alignment*player.Velocity >= 0 {
+		// Moving away from the contact or along it
+		return
+	}
+
+	player.Velocity *= 1 - math.Abs(alignment)
+	if math.Abs(player.Velocity) < 0.001 {
+		player.Velocity = 0
+	}
+}
+
+// ResolveObstacleContact pushes a tank out of a circle on the ground plane along the
+// contact normal and slows it. Returns true if the tank overlapped the circle.
+func ResolveObstacleContact(player *PlayerState, center Position, radius float64) bool {
+	nx, nz, depth, ok := contactNormal(player.Position, center, radius)
+	if !ok {
+		return false
+	}
+
+	player.Position.X += nx * depth
+	player.Position.Z += nz * depth
+	slowIntoContact(player, nx, nz)
+	return true
+}
+
+// ResolveTankOverlap separates two overlapping tanks, moving each half the overlap
+// along the contact normal, and slows both. Returns true if the tanks overlapped.
+func ResolveTankOverlap(a, b *PlayerState) bool {
+	nx, nz, depth, ok := contactNormal(a.Position, b.Position, 2*tankCollisionRadius)
+	if !ok {
+		return false
+	}
+
+	a.Position.X += nx * depth / 2
+	a.Position.Z += nz * depth / 2
+	b.Position.X -= nx * depth / 2
+	b.Position.Z -= nz * depth / 2
+	slowIntoContact(a, nx, nz)
+	slowIntoContact(b, -nx, -nz)
+	return true
+}
+
+// CorrectTanks writes collision responses back to the game state. Tanks that were
+// destroyed or left in the meantime are skipped.
+func (m *Manager) CorrectTanks(corrections map[string]TankCorrection) {
+	if len(corrections) == 0 {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	halfSize := m.gameMap.HalfSize()
+	for id, correction := range corrections {
+		player, exists := m.state.Players[id]
+		if !exists || player.IsDestroyed {
+			continue
+		}
+
+		player.Position.X = clampFloat(correction.Position.X, -halfSize, halfSize)
+		player.Position.Z = clampFloat(correction.Position.Z, -halfSize, halfSize)
+		player.Position.Y = m.gameMap.GroundHeight(player.Position.X, player.Position.Z)
+		player.Velocity = correction.Velocity
+		m.state.Players[id] = player
+	}
+}
@@ -0,0 +1,198 @@
+package game
+
+import (
+	"math"
+
+	"github.com/charmbracelet/log"
+)
+
+// Capture-the-flag rules
+const (
+	defaultCaptureLimit = 3
+	flagTouchRadius     = 10.0  // Distance at which a tank picks up, returns or captures a flag
+	flagReturnDelayMs   = 30000 // Dropped flags return to base after this long
+	flagBaseOffsetX     = 800.0 // Bases sit on the X axis at ±this distance from the center of the default map
+)
+
+// FlagBases are where the teams' flags stand in capture-the-flag
+type FlagBases struct {
+	Red  Position `json:"red"`
+	Blue Position `json:"blue"`
+}
+
+// FlagBases returns the capture-the-flag bases of the map, by default on the X axis
+// at a distance scaled with the map size. The bases stand on the terrain.
+func (gm *GameMap) FlagBases() FlagBases {
+	bases := defaultFlagBases(gm.HalfSize())
+	if gm != nil && gm.Bases != nil {
+		bases = *gm.Bases
+	}
+
+	bases.Red.Y = gm.GroundHeight(bases.Red.X, bases.Red.Z)
+	bases.Blue.Y = gm.GroundHeight(bases.Blue.X, bases.Blue.Z)
+	return bases
+}
+
+// defaultFlagBases returns the bases of a map without its own, on the X axis
+func defaultFlagBases(halfSize float64) FlagBases {
+	offset := scaleToMap(flagBaseOffsetX, halfSize)
+	return FlagBases{Red: Position{X: -offset}, Blue: Position{X: offset}}
+}
+
+// FlagState is the broadcast state of one team's flag
+type FlagState struct {
+	Team      string   `json:"team"`                // Team that owns the flag
+	Base      Position `json:"base"`                // Where the flag returns to and where the owner captures
+	Position  Position `json:"position"`            // Current position: at base, on the carrier or where it was dropped
+	CarrierID string   `json:"carrierId,omitempty"` // Tank carrying the flag
+	Dropped   bool     `json:"dropped,omitempty"`   // True if the flag is lying where its carrier died
+	DroppedAt int64    `json:"droppedAt,omitempty"` // Server time the flag was dropped
+}
+
+// AtBase reports whether the flag is sitting at its base
+func (f FlagState) AtBase() bool {
+	return f.CarrierID == "" && !f.Dropped
+}
+
+// captureTheFlagMode uses Team Deathmatch teams but scores only by bringing the enemy flag home
+type captureTheFlagMode struct {
+	teamDeathmatchMode
+	bases *FlagBases // Bases of the room's map, those of the default map if nil
+}
+
+func (c *captureTheFlagMode) Type() GameModeType {
+	return ModeCaptureTheFlag
+}
+
+func (c *captureTheFlagMode) Start(state *GameState, now int64) {
+	c.teamDeathmatchMode.Start(state, now)
+	startMatch(state, ModeCaptureTheFlag, c.config, now)
+
+	bases := defaultFlagBases(mapHalfSize)
+	if c.bases != nil {
+		bases = *c.bases
+	}
+	redBase, blueBase := bases.Red, bases.Blue
+	state.Flags = map[string]FlagState{
+		TeamRed:  {Team: TeamRed, Base: redBase, Position: redBase},
+		TeamBlue: {Team: TeamBlue, Base: blueBase, Position: blueBase},
+	}
+}
+
+// OnKill does nothing: kills don't score in capture-the-flag
+func (c *captureTheFlagMode) OnKill(state *GameState, source, target PlayerState) {}
+
+// OnDeath drops any flag the destroyed tank was carrying where it died
+func (c *captureTheFlagMode) OnDeath(state *GameState, victim PlayerState) {
+	for team, flag := range state.Flags {
+		if flag.CarrierID == victim.ID {
+			dropFlag(&flag, victim.Position, state.ServerTime)
+			state.Flags[team] = flag
+			log.Info("Flag dropped", "team", team, "carrier", victim.ID)
+		}
+	}
+}
+
+func (c *captureTheFlagMode) Update(state *GameState, now int64) {
+	if state.Match.Over {
+		return
+	}
+
+	c.updateFlags(state, now)
+	endMatchIfLimitReached(state, now, c.config.ScoreLimit)
+}
+
+// updateFlags moves carried flags, returns dropped ones and handles pickups and captures
+func (c *captureTheFlagMode) updateFlags(state *GameState, now int64) {
+	for team, flag := range state.Flags {
+		if flag.CarrierID != "" {
+			carrier, exists := state.Players[flag.CarrierID]
+			if !exists || carrier.IsDestroyed {
+				// Carrier left or died without going through OnDeath
+				dropFlag(&flag, flag.Position, now)
+			} else {
+				flag.Position = carrier.Position
+			}
+		}
+
+		// Dropped flags return home on their own after a while
+		if flag.Dropped && now-flag.DroppedAt >= flagReturnDelayMs {
+			returnFlag(&flag)
+			log.Info("Flag returned to base", "team", team, "reason", "timeout")
+		}
+
+		state.Flags[team] = flag
+	}
+
+	for id, player := range state.Players {
+		if player.IsDestroyed || player.Team == "" {
+			continue
+		}
+
+		for team, flag := range state.Flags {
+			if !withinFlagRange(player.Position, flag.Position) {
+				continue
+			}
+
+			switch {
+			case team != player.Team && flag.CarrierID == "":
+				// Enemy flag at base or dropped: pick it up
+				flag.CarrierID = id
+				flag.Dropped = false
+				flag.DroppedAt = 0
+				flag.Position = player.Position
+				state.Flags[team] = flag
+				log.Info("Flag picked up", "team", team, "carrier", id)
+
+			case team == player.Team && flag.Dropped:
+				// Own flag lying in the field: return it
+				returnFlag(&flag)
+				state.Flags[team] = flag
+				log.Info("Flag returned to base", "team", team, "by", id)
+
+			case team == player.Team && flag.AtBase():
+				// At our own base with our flag home: capture any enemy flag we carry
+				c.captureCarriedFlags(state, id, player.Team)
+			}
+		}
+	}
+}
+
+// captureCarriedFlags scores a point for every enemy flag the player carries and sends it home
+func (c *captureTheFlagMode) captureCarriedFlags(state *GameState, playerID, playerTeam string) {
+	for team, flag := range state.Flags {
+		if team == playerTeam || flag.CarrierID != playerID {
+			continue
+		}
+
+		returnFlag(&flag)
+		state.Flags[team] = flag
+
+		scoring := state.Teams[playerTeam]
+		scoring.Score++
+		state.Teams[playerTeam] = scoring
+
+		log.Info("Flag captured", "team", team, "by", playerID, "scoringTeam", playerTeam, "score", scoring.Score)
+	}
+}
+
+// dropFlag leaves a flag lying at pos
+func dropFlag(flag *FlagState, pos Position, now int64) {
+	flag.CarrierID = ""
+	flag.Dropped = true
+	flag.DroppedAt = now
+	flag.Position = pos
+}
+
+// returnFlag puts a flag back at its base
+func returnFlag(flag *FlagState) {
+	flag.CarrierID = ""
+	flag.Dropped = false
+	flag.DroppedAt = 0
+	flag.Position = flag.Base
+}
+
+// withinFlagRange reports whether a tank at pos can touch a flag at flagPos
+func withinFlagRange(pos, flagPos Position) bool {
+	return math.Hypot(pos.X-flagPos.X, pos.Z-flagPos.Z) <= flagTouchRadius
+}
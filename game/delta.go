@@ -0,0 +1,313 @@
+package game
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/charmbracelet/log"
+)
+
+// deltaHistorySize is the number of sent snapshots kept per stream as possible delta baselines.
+// A client whose last ack is older than this gets a full resync.
+const deltaHistorySize = 64
+
+// StateDelta is one message on a client's game state stream.
+// Full snapshots carry every player and shell; deltas carry only what changed since BaseRevision.
+type StateDelta struct {
+	Stream         string                 `json:"stream"`                   // Stream the client must ack on
+	Revision       uint64                 `json:"rev"`                      // KV revision of the state this message produces
+	BaseRevision   uint64                 `json:"base,omitempty"`           // Revision the delta applies to, 0 for full snapshots
+	Full           bool                   `json:"full,omitempty"`           // True if this is a full snapshot
+	Tick           uint64                 `json:"tick"`                     // Simulation tick of the state
+	ServerTime     int64                  `json:"serverTime"`               // Server time of the state in milliseconds
+	Match          MatchState             `json:"match"`                    // Match state, always sent in full
+	Teams          map[string]TeamState   `json:"teams,omitempty"`          // Team scores, always sent in full
+	Flags          map[string]FlagState   `json:"flags,omitempty"`          // Flag positions, always sent in full
+	Zones          []ZoneState            `json:"zones,omitempty"`          // Control zones, always sent in full
+	Spectators     int                    `json:"spectators,omitempty"`     // Spectator count, always sent in full
+	Impacts        []ImpactState          `json:"impacts,omitempty"`        // Recent shell impacts, always sent in full
+	Destroyed      []ObstacleState        `json:"destroyed,omitempty"`      // Broken obstacles, always sent in full
+	Players        map[string]PlayerState `json:"players,omitempty"`        // Added or changed players (all players if full)
+	Shells         []ShellState           `json:"shells,omitempty"`         // Added or changed shells (all shells if full)
+	RemovedPlayers []string               `json:"removedPlayers,omitempty"` // Players no longer in the game
+	RemovedShells  []string               `json:"removedShells,omitempty"`  // Shells no longer in the game
+}
+
+// StateAck is sent by a client to confirm the latest state revision it has applied.
+// A revision of 0 requests a full resync.
+type StateAck struct {
+	Stream   string   `json:"stream"`
+	Revision uint64   `json:"rev"`
+	Codecs   []string `json:"codecs,omitempty"` // Wire formats the client can decode, preferred first; empty keeps the current ones
+}
+
+// DiffState computes the players and shells that changed between two states
+func DiffState(base, next GameState) StateDelta {
+	delta := StateDelta{
+		Tick:       next.Tick,
+		ServerTime: next.ServerTime,
+		Match:      next.Match,
+		Teams:      next.Teams,
+		Flags:      next.Flags,
+		Zones:      next.Zones,
+		Spectators: next.Spectators,
+		Impacts:    next.Impacts,
+		Destroyed:  next.DestroyedObstacles,
+	}
+
+	// Added or changed players
+	for id, player := range next.Players {
+		if prev, exists := base.Players[id]; !exists || prev != player {
+			if delta.Players == nil {
+				delta.Players = make(map[string]PlayerState)
+			}
+			delta.Players[id] = player
+		}
+	}
+
+	// Removed players
+	for id := range base.Players {
+		if _, exists := next.Players[id]; !exists {
+			delta.RemovedPlayers = append(delta.RemovedPlayers, id)
+		}
+	}
+
+	// Index base shells by ID for comparison
+	baseShells := make(map[string]ShellState, len(base.Shells))
+	for _, shell := range base.Shells {
+		baseShells[shell.ID] = shell
+	}
+
+	// Added or changed shells
+	nextShells := make(map[string]bool, len(next.Shells))
+	for _, shell := range next.Shells {
+		nextShells[shell.ID] = true
+		if prev, exists := baseShells[shell.ID]; !exists || prev != shell {
+			delta.Shells = append(delta.Shells, shell)
+		}
+	}
+
+	// Removed shells
+	for _, shell := range base.Shells {
+		if !nextShells[shell.ID] {
+			delta.RemovedShells = append(delta.RemovedShells, shell.ID)
+		}
+	}
+
+	return delta
+}
+
+// ApplyDelta returns the state produced by applying a delta to its base. The base is not modified.
+func ApplyDelta(base GameState, delta StateDelta) GameState {
+	next := GameState{
+		Players:            make(map[string]PlayerState, len(base.Players)),
+		Tick:               delta.Tick,
+		ServerTime:         delta.ServerTime,
+		Match:              delta.Match,
+		Teams:              delta.Teams,
+		Flags:              delta.Flags,
+		Zones:              delta.Zones,
+		Spectators:         delta.Spectators,
+		Impacts:            delta.Impacts,
+		DestroyedObstacles: delta.Destroyed,
+	}
+
+	// Players
+	for id, player := range base.Players {
+		next.Players[id] = player
+	}
+	for _, id := range delta.RemovedPlayers {
+		delete(next.Players, id)
+	}
+	for id, player := range delta.Players {
+		next.Players[id] = player
+	}
+
+	// Shells keep their order; changed shells are updated in place and new ones appended
+	removed := make(map[string]bool, len(delta.RemovedShells))
+	for _, id := range delta.RemovedShells {
+		removed[id] = true
+	}
+	changed := make(map[string]ShellState, len(delta.Shells))
+	for _, shell := range delta.Shells {
+		changed[shell.ID] = shell
+	}
+	next.Shells = make([]ShellState, 0, len(base.Shells)+len(delta.Shells))
+	for _, shell := range base.Shells {
+		if removed[shell.ID] {
+			continue
+		}
+		if update, exists := changed[shell.ID]; exists {
+			shell = update
+			delete(changed, shell.ID)
+		}
+		next.Shells = append(next.Shells, shell)
+	}
+	for _, shell := range delta.Shells {
+		if _, added := changed[shell.ID]; added {
+			next.Shells = append(next.Shells, shell)
+		}
+	}
+
+	return next
+}
+
+// DeltaEncoder produces the state stream for a single client connection.
+// Each state is encoded against the latest revision the client acked.
+type DeltaEncoder struct {
+	id      string
+	ownerID string
+	mutex   sync.Mutex
+	history map[uint64]GameState // Snapshots sent to the client, keyed by revision
+	acked   uint64               // Latest revision acked by the client, 0 if none
+	codecs  []string             // Wire formats the client can decode, preferred first
+}
+
+// ID returns the stream ID the client acks on
+func (d *DeltaEncoder) ID() string {
+	return d.id
+}
+
+// Ack records that the client has applied the given revision.
+// Older acks are ignored and a revision of 0 forces the next message to be a full snapshot.
+func (d *DeltaEncoder) Ack(revision uint64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if revision == 0 {
+		log.Debug("Client requested state resync", "stream", d.id)
+		d.acked = 0
+		return
+	}
+
+	if revision <= d.acked {
+		return
+	}
+
+	// Only revisions we actually sent can become a baseline
+	if _, exists := d.history[revision]; !exists {
+		return
+	}
+	d.acked = revision
+
+	// Snapshots older than the ack can never be a baseline again
+	for rev := range d.history {
+		if rev < revision {
+			delete(d.history, rev)
+		}
+	}
+}
+
+// Codecs returns the wire formats the client said it can decode, preferred first
+func (d *DeltaEncoder) Codecs() []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.codecs
+}
+
+// Accept records the wire formats the client can decode
+func (d *DeltaEncoder) Accept(codecs []string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.codecs = codecs
+}
+
+// Encode returns the message to send for the state at the given revision
+func (d *DeltaEncoder) Encode(revision uint64, state GameState) StateDelta {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	var delta StateDelta
+	base, hasBase := d.history[d.acked]
+	if d.acked != 0 && hasBase && revision > d.acked {
+		delta = DiffState(base, state)
+		delta.BaseRevision = d.acked
+	} else {
+		delta = StateDelta{
+			Full:       true,
+			Tick:       state.Tick,
+			ServerTime: state.ServerTime,
+			Match:      state.Match,
+			Teams:      state.Teams,
+			Flags:      state.Flags,
+			Zones:      state.Zones,
+			Spectators: state.Spectators,
+			Impacts:    state.Impacts,
+			Destroyed:  state.DestroyedObstacles,
+			Players:    state.Players,
+			Shells:     state.Shells,
+		}
+	}
+	delta.Stream = d.id
+	delta.Revision = revision
+
+	d.history[revision] = state
+
+	// Client has fallen too far behind: drop the stale baseline so the next message is a full resync
+	if len(d.history) > deltaHistorySize {
+		log.Debug("Client fell behind state stream, resyncing", "stream", d.id, "acked", d.acked, "revision", revision)
+		d.history = map[uint64]GameState{revision: state}
+		d.acked = 0
+	}
+
+	return delta
+}
+
+// StateStreams tracks the delta encoders of all open state streams so acks can reach them
+type StateStreams struct {
+	mutex    sync.RWMutex
+	encoders map[string]*DeltaEncoder
+	counter  atomic.Uint64
+}
+
+// NewStateStreams creates an empty stream registry
+func NewStateStreams() *StateStreams {
+	return &StateStreams{
+		encoders: make(map[string]*DeltaEncoder),
+	}
+}
+
+// Open registers a new state stream for a client
+func (s *StateStreams) Open(ownerID string) *DeltaEncoder {
+	encoder := &DeltaEncoder{
+		id:      fmt.Sprintf("stream_%d", s.counter.Add(1)),
+		ownerID: ownerID,
+		history: make(map[uint64]GameState),
+	}
+
+	s.mutex.Lock()
+	s.encoders[encoder.id] = encoder
+	s.mutex.Unlock()
+
+	return encoder
+}
+
+// Close removes a state stream when its connection ends
+func (s *StateStreams) Close(id string) {
+	s.mutex.Lock()
+	delete(s.encoders, id)
+	s.mutex.Unlock()
+}
+
+// Ack forwards a client ack to its stream. Clients can only ack their own streams.
+func (s *StateStreams) Ack(ownerID string, ack StateAck) error {
+	s.mutex.RLock()
+	encoder, exists := s.encoders[ack.Stream]
+	s.mutex.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("state stream %s not found", ack.Stream)
+	}
+	if encoder.ownerID != ownerID {
+		return fmt.Errorf("state stream %s does not belong to player %s", ack.Stream, ownerID)
+	}
+
+	if len(ack.Codecs) > 0 {
+		encoder.Accept(ack.Codecs)
+	}
+	encoder.Ack(ack.Revision)
+	return nil
+}
@@ -0,0 +1,193 @@
+package game
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// testState returns a state with the given players and shells, each tagged with a version
+// so a changed entry differs from its base
+func testState(tick uint64, players map[string]int, shells []string) GameState {
+	state := GameState{
+		Players:    make(map[string]PlayerState, len(players)),
+		Shells:     make([]ShellState, 0, len(shells)),
+		Tick:       tick,
+		ServerTime: int64(tick) * 50,
+		Match:      MatchState{Mode: ModeFreeForAll, Phase: MatchPhaseLive},
+	}
+	for id, version := range players {
+		state.Players[id] = PlayerState{
+			ID:       id,
+			Name:     "Tank " + id,
+			Position: Position{X: float64(version) * 10, Z: -float64(version)},
+			Health:   100 - version,
+		}
+	}
+	for i, id := range shells {
+		state.Shells = append(state.Shells, ShellState{
+			ID:        id,
+			PlayerID:  "p1",
+			Position:  Position{X: float64(tick), Y: 5, Z: float64(i)},
+			Direction: Position{X: 1},
+			Speed:     shellSpeed,
+		})
+	}
+	return state
+}
+
+// cloneState copies the players and shells of a state
+func cloneState(state GameState) GameState {
+	players := make(map[string]PlayerState, len(state.Players))
+	for id, player := range state.Players {
+		players[id] = player
+	}
+	state.Players = players
+	state.Shells = append([]ShellState{}, state.Shells...)
+	return state
+}
+
+func TestApplyDiffRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		prev, next GameState
+	}{
+		{
+			name: "unchanged",
+			prev: testState(1, map[string]int{"p1": 1, "p2": 1}, []string{"s1"}),
+			next: testState(1, map[string]int{"p1": 1, "p2": 1}, []string{"s1"}),
+		},
+		{
+			name: "changed players and moved shells",
+			prev: testState(1, map[string]int{"p1": 1, "p2": 1}, []string{"s1", "s2"}),
+			next: testState(2, map[string]int{"p1": 2, "p2": 1}, []string{"s1", "s2"}),
+		},
+		{
+			name: "added players and shells",
+			prev: testState(1, map[string]int{"p1": 1}, []string{"s1"}),
+			next: testState(1, map[string]int{"p1": 1, "p2": 1, "bot_3": 4}, []string{"s1", "s2", "s3"}),
+		},
+		{
+			name: "removed players and shells",
+			prev: testState(1, map[string]int{"p1": 1, "p2": 1, "bot_3": 4}, []string{"s1", "s2", "s3"}),
+			next: testState(1, map[string]int{"p2": 1}, []string{"s2"}),
+		},
+		{
+			name: "removed, added and changed at once",
+			prev: testState(1, map[string]int{"p1": 1, "p2": 1}, []string{"s1", "s2", "s3"}),
+			next: testState(2, map[string]int{"p2": 3, "p4": 1}, []string{"s2", "s4"}),
+		},
+		{
+			name: "everything removed",
+			prev: testState(1, map[string]int{"p1": 1, "p2": 1}, []string{"s1", "s2"}),
+			next: testState(2, map[string]int{}, []string{}),
+		},
+	}
+
+	for _, test := range tests {
+		prev := cloneState(test.prev)
+		got := ApplyDelta(test.prev, DiffState(test.prev, test.next))
+		if !reflect.DeepEqual(got, test.next) {
+			t.Errorf("%s: applied diff does not match\n got: %+v\nwant: %+v", test.name, got, test.next)
+		}
+		if !reflect.DeepEqual(test.prev, prev) {
+			t.Errorf("%s: applying the diff modified the base", test.name)
+		}
+	}
+}
+
+func TestDiffStateRemovals(t *testing.T) {
+	prev := testState(1, map[string]int{"p1": 1, "p2": 1}, []string{"s1", "s2"})
+	next := testState(2, map[string]int{"p2": 1}, []string{"s2"})
+
+	delta := DiffState(prev, next)
+	if !reflect.DeepEqual(delta.RemovedPlayers, []string{"p1"}) {
+		t.Errorf("removed players = %v, want [p1]", delta.RemovedPlayers)
+	}
+	if !reflect.DeepEqual(delta.RemovedShells, []string{"s1"}) {
+		t.Errorf("removed shells = %v, want [s1]", delta.RemovedShells)
+	}
+	if len(delta.Players) != 0 {
+		t.Errorf("unchanged players sent: %v", delta.Players)
+	}
+}
+
+// streamStates encodes consecutive revisions on a stream and returns the messages
+func streamStates(encoder *DeltaEncoder, from, to uint64) []StateDelta {
+	var deltas []StateDelta
+	for rev := from; rev <= to; rev++ {
+		state := testState(rev, map[string]int{"p1": int(rev)}, []string{fmt.Sprintf("s%d", rev)})
+		deltas = append(deltas, encoder.Encode(rev, state))
+	}
+	return deltas
+}
+
+func TestDeltaEncoderAcks(t *testing.T) {
+	streams := NewStateStreams()
+	encoder := streams.Open("p1")
+
+	first := streamStates(encoder, 1, 1)[0]
+	if !first.Full || first.Stream != encoder.ID() {
+		t.Fatalf("first message is not a full snapshot on the stream: %+v", first)
+	}
+
+	if err := streams.Ack("p1", StateAck{Stream: encoder.ID(), Revision: 1}); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+	delta := streamStates(encoder, 2, 2)[0]
+	if delta.Full || delta.BaseRevision != 1 {
+		t.Fatalf("message after an ack is not a delta against it: full=%v base=%d", delta.Full, delta.BaseRevision)
+	}
+	state := ApplyDelta(testState(1, map[string]int{"p1": 1}, []string{"s1"}), delta)
+	if want := testState(2, map[string]int{"p1": 2}, []string{"s2"}); !reflect.DeepEqual(state, want) {
+		t.Errorf("delta does not rebuild the state\n got: %+v\nwant: %+v", state, want)
+	}
+
+	// Only the stream's owner may ack and a resync request forces a full snapshot
+	if err := streams.Ack("p2", StateAck{Stream: encoder.ID(), Revision: 2}); err == nil {
+		t.Error("ack from another player was accepted")
+	}
+	if err := streams.Ack("p1", StateAck{Stream: encoder.ID(), Revision: 0}); err != nil {
+		t.Fatalf("resync: %v", err)
+	}
+	if resync := streamStates(encoder, 3, 3)[0]; !resync.Full {
+		t.Error("message after a resync request is not a full snapshot")
+	}
+
+	streams.Close(encoder.ID())
+	if err := streams.Ack("p1", StateAck{Stream: encoder.ID(), Revision: 3}); err == nil {
+		t.Error("ack on a closed stream was accepted")
+	}
+}
+
+func TestDeltaEncoderResyncsLaggingClient(t *testing.T) {
+	streams := NewStateStreams()
+	encoder := streams.Open("p1")
+
+	streamStates(encoder, 1, 1)
+	encoder.Ack(1)
+
+	// The client stops acking until its baseline falls out of the history
+	deltas := streamStates(encoder, 2, deltaHistorySize+1)
+	for _, delta := range deltas {
+		if delta.Full || delta.BaseRevision != 1 {
+			t.Fatalf("revision %d: want a delta against revision 1, got full=%v base=%d", delta.Revision, delta.Full, delta.BaseRevision)
+		}
+	}
+	if next := streamStates(encoder, deltaHistorySize+2, deltaHistorySize+2)[0]; !next.Full {
+		t.Fatalf("revision %d: lagging client did not get a full snapshot", next.Revision)
+	}
+
+	// Acks of revisions no longer in the history cannot become a baseline
+	encoder.Ack(5)
+	if next := streamStates(encoder, deltaHistorySize+3, deltaHistorySize+3)[0]; !next.Full {
+		t.Errorf("revision %d: ack of a dropped revision was used as a baseline (base %d)", next.Revision, next.BaseRevision)
+	}
+
+	// An ack of a revision still held resumes deltas
+	last := uint64(deltaHistorySize + 3)
+	encoder.Ack(last)
+	if next := streamStates(encoder, last+1, last+1)[0]; next.Full || next.BaseRevision != last {
+		t.Errorf("revision %d: want a delta against revision %d, got full=%v base=%d", next.Revision, last, next.Full, next.BaseRevision)
+	}
+}
@@ -0,0 +1,95 @@
+package game
+
+// MatchEventType identifies a discrete event of a match
+type MatchEventType string
+
+const (
+	MatchEventShotFired MatchEventType = "shot"    // A tank fired a shell
+	MatchEventHit       MatchEventType = "hit"     // A shell damaged a tank
+	MatchEventImpact    MatchEventType = "impact"  // A shell struck a tree or rock
+	MatchEventKill      MatchEventType = "kill"    // A tank was destroyed
+	MatchEventRespawn   MatchEventType = "respawn" // A destroyed tank came back
+	MatchEventJoin      MatchEventType = "join"    // A player entered the room
+	MatchEventLeave     MatchEventType = "leave"   // A player left the room
+	MatchEventPhase     MatchEventType = "phase"   // The match moved to another phase
+)
+
+// WeaponCannon is the tank's main gun, currently the only weapon
+const WeaponCannon = "cannon"
+
+// MatchEvent is something that happened at one moment of the game, as opposed to
+// the continuous state in snapshots. Only the fields of the event's type are set.
+type MatchEvent struct {
+	Type        MatchEventType `json:"type"`
+	Tick        uint64         `json:"tick"`                  // Last published tick when the event happened
+	ServerTime  int64          `json:"serverTime"`            // Server time in milliseconds
+	PlayerID    string         `json:"playerId,omitempty"`    // Shooter, attacker, killer, or the tank that respawned, joined or left
+	PlayerName  string         `json:"playerName,omitempty"`  // Name of PlayerID, so events can be shown after the player is gone
+	TargetID    string         `json:"targetId,omitempty"`    // Tank hit or destroyed
+	TargetName  string         `json:"targetName,omitempty"`  // Name of TargetID
+	ShellID     string         `json:"shellId,omitempty"`     // Shell fired, or the shell of a hit or impact
+	Weapon      string         `json:"weapon,omitempty"`      // Weapon of a hit or kill
+	HitLocation string         `json:"hitLocation,omitempty"` // Part of the tank a hit or killing shot struck
+	Damage      int            `json:"damage,omitempty"`      // Damage of a hit
+	Position    *Position      `json:"position,omitempty"`    // Where the shell was fired or struck, or the tank respawned
+	Direction   *Position      `json:"direction,omitempty"`   // Direction of a shell
+	Obstacle    *ObstacleState `json:"obstacle,omitempty"`    // Tree or rock of an impact
+	Destroyed   bool           `json:"destroyed,omitempty"`   // True if an impact broke the obstacle
+	Phase       MatchPhase     `json:"phase,omitempty"`       // Phase the match entered
+	Match       int            `json:"match,omitempty"`       // Number of the match of a phase change
+}
+
+// ReplayRecorder records matches for later playback.
+// It is called from the tick, mostly with the manager's mutex held, so implementations must not block.
+type ReplayRecorder interface {
+	// StartMatch is called when a match goes live on the given map
+	StartMatch(match MatchState, gameMap *GameMap)
+	// RecordState is called with every published snapshot; the state is not used by the manager afterwards
+	RecordState(state GameState)
+	// RecordEvent is called for every discrete event
+	RecordEvent(event MatchEvent)
+	// EndMatch is called with the result when a live match finishes
+	EndMatch(result MatchResult)
+}
+
+// EventPublisher broadcasts match events to connected clients as they happen.
+// It is called with the manager's mutex held, so implementations must not block.
+type EventPublisher interface {
+	PublishEvent(event MatchEvent)
+}
+
+// SetReplayRecorder sets where matches are recorded for replays
+func (m *Manager) SetReplayRecorder(replay ReplayRecorder) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.replay = replay
+}
+
+// SetEventPublisher sets where match events are broadcast
+func (m *Manager) SetEventPublisher(events EventPublisher) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.events = events
+}
+
+// emitEvent stamps an event with the current tick and time and hands it to the
+// replay recorder and the event publisher. Caller must hold the mutex.
+func (m *Manager) emitEvent(event MatchEvent) {
+	event.Tick = m.state.Tick
+	event.ServerTime = m.getTime()
+
+	if m.replay != nil {
+		m.replay.RecordEvent(event)
+	}
+	if m.events != nil {
+		m.events.PublishEvent(event)
+	}
+}
+
+// playerName returns the name of a player in the room, or an empty string if they
+// are not in it. Caller must hold the mutex.
+func (m *Manager) playerName(playerID string) string {
+	return m.state.Players[playerID].Name
+}
@@ -0,0 +1,181 @@
+package game
+
+import (
+	"github.com/charmbracelet/log"
+)
+
+// Client hit reports are only hints. Damage is applied solely from hits the physics
+// engine detects against server-simulated shells; client claims are compared with
+// those afterwards so modified clients can be spotted.
+const (
+	hitClaimWindowMs   = 1500 // How far apart a claim and the server hit may be to match
+	maxHitRejections   = 100  // Rejections kept in the log per room
+	suspicionWarnEvery = 10   // Log a warning each time a player's suspicion reaches a multiple of this
+)
+
+// Reasons a client hit claim is rejected
+const (
+	RejectNotShooter    = "not_shooter"    // Claimed a hit for someone else's shell
+	RejectUnknownTarget = "unknown_target" // Target is not in the game
+	RejectNoServerHit   = "no_server_hit"  // The server did not see the hit
+)
+
+// HitRejection is a client hit claim the server disagreed with
+type HitRejection struct {
+	PlayerID      string `json:"playerId"` // Client that sent the claim
+	TargetID      string `json:"targetId"`
+	ClaimedDamage int    `json:"claimedDamage"`
+	Reason        string `json:"reason"`
+	Timestamp     int64  `json:"timestamp"` // Server time the claim was received
+}
+
+// hitClaim is a client hit report waiting to be compared with server hits
+type hitClaim struct {
+	playerID   string
+	hit        HitData
+	receivedAt int64
+}
+
+// serverHit is a hit the physics engine applied
+type serverHit struct {
+	sourceID  string
+	targetID  string
+	timestamp int64
+}
+
+// hitValidator compares client hit claims with server hits. Guarded by the manager's mutex.
+type hitValidator struct {
+	claims     []hitClaim
+	serverHits []serverHit
+	rejections []HitRejection
+	suspicion  map[string]int // Rejected claims per player
+}
+
+func newHitValidator() *hitValidator {
+	return &hitValidator{
+		suspicion: make(map[string]int),
+	}
+}
+
+// ReportHitClaim records a hit a client says it scored. It never applies damage;
+// the claim is checked against server-detected hits once the match window has passed.
+func (m *Manager) ReportHitClaim(hitData HitData, playerID string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.getTime()
+
+	// Clients only report hits by their own shells
+	if hitData.SourceID != playerID {
+		m.rejectHitClaimLocked(playerID, hitData, RejectNotShooter, now)
+		return
+	}
+	if _, exists := m.state.Players[hitData.TargetID]; !exists {
+		m.rejectHitClaimLocked(playerID, hitData, RejectUnknownTarget, now)
+		return
+	}
+
+	m.hits.claims = append(m.hits.claims, hitClaim{playerID: playerID, hit: hitData, receivedAt: now})
+}
+
+// recordServerHitLocked remembers a hit applied by the physics engine. Caller must hold the mutex.
+func (m *Manager) recordServerHitLocked(hitData HitData) {
+	m.hits.serverHits = append(m.hits.serverHits, serverHit{
+		sourceID:  hitData.SourceID,
+		targetID:  hitData.TargetID,
+		timestamp: m.getTime(),
+	})
+}
+
+// reconcileHitClaims matches client claims with server hits. Claims still unmatched
+// after the window are rejected. Runs in the cleanup phase of every tick.
+func (m *Manager) reconcileHitClaims() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.getTime()
+	hits := m.hits
+
+	pending := hits.claims[:0]
+	for _, claim := range hits.claims {
+		if index := hits.findServerHit(claim); index >= 0 {
+			// Each server hit confirms a single claim
+			hits.serverHits = append(hits.serverHits[:index], hits.serverHits[index+1:]...)
+			continue
+		}
+		if now-claim.receivedAt > hitClaimWindowMs {
+			m.rejectHitClaimLocked(claim.playerID, claim.hit, RejectNoServerHit, claim.receivedAt)
+			continue
+		}
+		pending = append(pending, claim)
+	}
+	hits.claims = pending
+
+	// Server hits only need to wait for claims sent up to a window later
+	recent := hits.serverHits[:0]
+	for _, hit := range hits.serverHits {
+		if now-hit.timestamp <= 2*hitClaimWindowMs {
+			recent = append(recent, hit)
+		}
+	}
+	hits.serverHits = recent
+}
+
+// findServerHit returns the index of a server hit matching a claim, or -1
+func (h *hitValidator) findServerHit(claim hitClaim) int {
+	for i, hit := range h.serverHits {
+		if hit.sourceID != claim.hit.SourceID || hit.targetID != claim.hit.TargetID {
+			continue
+		}
+		if diff := hit.timestamp - claim.receivedAt; diff <= hitClaimWindowMs && diff >= -hitClaimWindowMs {
+			return i
+		}
+	}
+	return -1
+}
+
+// rejectHitClaimLocked logs a rejected claim and raises the player's suspicion. Caller must hold the mutex.
+func (m *Manager) rejectHitClaimLocked(playerID string, hitData HitData, reason string, timestamp int64) {
+	hits := m.hits
+
+	hits.rejections = append(hits.rejections, HitRejection{
+		PlayerID:      playerID,
+		TargetID:      hitData.TargetID,
+		ClaimedDamage: hitData.DamageAmount,
+		Reason:        reason,
+		Timestamp:     timestamp,
+	})
+	if len(hits.rejections) > maxHitRejections {
+		hits.rejections = hits.rejections[len(hits.rejections)-maxHitRejections:]
+	}
+
+	hits.suspicion[playerID]++
+	suspicion := hits.suspicion[playerID]
+
+	log.Debug("Rejected client hit claim", "playerID", playerID, "targetID", hitData.TargetID, "reason", reason, "suspicion", suspicion)
+	if suspicion%suspicionWarnEvery == 0 {
+		log.Warn("Player keeps reporting hits the server did not see", "playerID", playerID, "suspicion", suspicion)
+	}
+}
+
+// HitRejections returns the most recent rejected client hit claims, oldest first
+func (m *Manager) HitRejections() []HitRejection {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	rejections := make([]HitRejection, len(m.hits.rejections))
+	copy(rejections, m.hits.rejections)
+	return rejections
+}
+
+// Suspicion returns the number of rejected hit claims per player
+func (m *Manager) Suspicion() map[string]int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	suspicion := make(map[string]int, len(m.hits.suspicion))
+	for playerID, count := range m.hits.suspicion {
+		suspicion[playerID] = count
+	}
+	return suspicion
+}
@@ -0,0 +1,86 @@
+package game
+
+import (
+	"github.com/charmbracelet/log"
+)
+
+// impactLifetimeMs is how long a shell impact stays in the game state. Every state
+// published in that time carries it, so clients see it even if they miss a few.
+const impactLifetimeMs = 1000
+
+// ObstacleState identifies a tree or rock of the map
+type ObstacleState struct {
+	ID       string   `json:"id"`
+	Type     string   `json:"type"` // "tree" or "rock"
+	Position Position `json:"position"`
+}
+
+// ImpactState is a shell hitting a tree or rock, for clients to render debris
+type ImpactState struct {
+	ID        string        `json:"id"` // ID of the shell, each shell hits one obstacle at most
+	PlayerID  string        `json:"playerId"`
+	Position  Position      `json:"position"` // Point the shell struck
+	Obstacle  ObstacleState `json:"obstacle"`
+	Destroyed bool          `json:"destroyed,omitempty"` // True if the hit broke the obstacle
+	Timestamp int64         `json:"timestamp"`
+}
+
+// RecordImpact adds a shell impact on an obstacle to the game state. Obstacles with
+// hit points break once they have taken that many hits in the current match; zero
+// means indestructible. Returns true if this impact broke the obstacle.
+func (m *Manager) RecordImpact(impact ImpactState, hitPoints int) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	impact.Timestamp = m.getTime()
+
+	if hitPoints > 0 {
+		m.obstacleDamage[impact.Obstacle.ID]++
+		if m.obstacleDamage[impact.Obstacle.ID] == hitPoints {
+			impact.Destroyed = true
+			m.state.DestroyedObstacles = append(m.state.DestroyedObstacles, impact.Obstacle)
+			log.Info("Obstacle destroyed", "obstacle", impact.Obstacle.ID, "type", impact.Obstacle.Type, "playerID", impact.PlayerID)
+		}
+	}
+
+	m.state.Impacts = append(m.state.Impacts, impact)
+	m.emitEvent(MatchEvent{
+		Type:      MatchEventImpact,
+		PlayerID:  impact.PlayerID,
+		ShellID:   impact.ID,
+		Position:  &impact.Position,
+		Obstacle:  &impact.Obstacle,
+		Destroyed: impact.Destroyed,
+	})
+	return impact.Destroyed
+}
+
+// DestroyedObstacles returns the IDs of obstacles broken in the current match
+func (m *Manager) DestroyedObstacles() map[string]bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	destroyed := make(map[string]bool, len(m.state.DestroyedObstacles))
+	for _, obstacle := range m.state.DestroyedObstacles {
+		destroyed[obstacle.ID] = true
+	}
+	return destroyed
+}
+
+// pruneImpactsLocked drops impacts older than impactLifetimeMs. Caller must hold the mutex.
+func (m *Manager) pruneImpactsLocked(now int64) {
+	recent := m.state.Impacts[:0]
+	for _, impact := range m.state.Impacts {
+		if now-impact.Timestamp < impactLifetimeMs {
+			recent = append(recent, impact)
+		}
+	}
+	m.state.Impacts = recent
+}
+
+// restoreObstaclesLocked rebuilds every obstacle broken in the previous match.
+// Caller must hold the mutex.
+func (m *Manager) restoreObstaclesLocked() {
+	m.state.DestroyedObstacles = nil
+	m.obstacleDamage = make(map[string]int)
+}
@@ -0,0 +1,199 @@
+package game
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/charmbracelet/log"
+)
+
+// King of the Hill rules
+const (
+	defaultZoneScoreLimit = 300   // One point per second per held zone
+	zoneCaptureTimeMs     = 10000 // Time an uncontested side needs to capture a zone
+	zoneDecayTimeMs       = 20000 // Time for full capture progress to drain from an empty zone
+	zoneScoreIntervalMs   = 1000  // Held zones score once per interval
+)
+
+// ZoneState is the broadcast state of one control zone
+type ZoneState struct {
+	ID        string   `json:"id"`
+	Position  Position `json:"position"`
+	Radius    float64  `json:"radius"`
+	Owner     string   `json:"owner,omitempty"`     // Team, or player in solo mode, holding the zone
+	Capturer  string   `json:"capturer,omitempty"`  // Team or player currently making capture progress
+	Progress  float64  `json:"progress"`            // Capture progress of the capturer from 0 to 1
+	Contested bool     `json:"contested,omitempty"` // True while rival sides are inside the zone
+}
+
+// kingOfTheHillMode scores points for holding control zones.
+// By default it plays in teams; in solo mode every player holds zones for themselves.
+type kingOfTheHillMode struct {
+	teamDeathmatchMode
+	lastUpdate int64         // Server time of the previous update, for capture progress
+	lastScore  int64         // Server time zones last scored
+	zones      []ControlZone // Zones of the room's map, the stone circles if empty
+}
+
+func (k *kingOfTheHillMode) Type() GameModeType {
+	return ModeKingOfTheHill
+}
+
+func (k *kingOfTheHillMode) Start(state *GameState, now int64) {
+	if k.config.Solo {
+		state.Teams = nil
+	} else {
+		k.teamDeathmatchMode.Start(state, now)
+	}
+	startMatch(state, ModeKingOfTheHill, k.config, now)
+
+	zones := k.zones
+	if len(zones) == 0 {
+		zones = stoneCircleZones(mapHalfSize)
+	}
+	state.Zones = make([]ZoneState, len(zones))
+	for i, zone := range zones {
+		state.Zones[i] = ZoneState{
+			ID:       fmt.Sprintf("zone_%d", i+1),
+			Position: zone.Position,
+			Radius:   zone.Radius,
+		}
+	}
+
+	k.lastUpdate = now
+	k.lastScore = now
+}
+
+func (k *kingOfTheHillMode) AssignPlayer(state *GameState, player *PlayerState) {
+	if k.config.Solo {
+		player.Team = ""
+		return
+	}
+	k.teamDeathmatchMode.AssignPlayer(state, player)
+}
+
+func (k *kingOfTheHillMode) CanDamage(state *GameState, source, target PlayerState) bool {
+	// Without teams everyone is an enemy
+	if k.config.Solo {
+		return !state.Match.Over
+	}
+	return k.teamDeathmatchMode.CanDamage(state, source, target)
+}
+
+// OnKill does nothing: kills don't score in King of the Hill
+func (k *kingOfTheHillMode) OnKill(state *GameState, source, target PlayerState) {}
+
+func (k *kingOfTheHillMode) Update(state *GameState, now int64) {
+	if state.Match.Over {
+		return
+	}
+
+	elapsed := now - k.lastUpdate
+	k.lastUpdate = now
+	k.updateZones(state, elapsed)
+
+	// Held zones score on a fixed interval regardless of the tick rate
+	for now-k.lastScore >= zoneScoreIntervalMs {
+		k.lastScore += zoneScoreIntervalMs
+		k.scoreZones(state)
+	}
+
+	if k.config.Solo {
+		endSoloMatchIfLimitReached(state, now, k.config.ScoreLimit)
+	} else {
+		endMatchIfLimitReached(state, now, k.config.ScoreLimit)
+	}
+}
+
+// sideOf returns the team or player a tank captures zones for
+func (k *kingOfTheHillMode) sideOf(player PlayerState) string {
+	if k.config.Solo || player.Team == "" {
+		return player.ID
+	}
+	return player.Team
+}
+
+// updateZones advances capture progress for every zone with a single side inside it
+func (k *kingOfTheHillMode) updateZones(state *GameState, elapsed int64) {
+	captureStep := float64(elapsed) / zoneCaptureTimeMs
+	decayStep := float64(elapsed) / zoneDecayTimeMs
+
+	for i := range state.Zones {
+		zone := &state.Zones[i]
+
+		// Find which sides have living tanks inside the zone
+		sides := make(map[string]bool)
+		for _, player := range state.Players {
+			if player.IsDestroyed {
+				continue
+			}
+			if math.Hypot(player.Position.X-zone.Position.X, player.Position.Z-zone.Position.Z) <= zone.Radius {
+				sides[k.sideOf(player)] = true
+			}
+		}
+
+		zone.Contested = len(sides) > 1
+		if zone.Contested {
+			// Progress freezes while the zone is fought over
+			continue
+		}
+
+		if len(sides) == 0 {
+			// Abandoned progress drains away
+			zone.Progress = math.Max(0, zone.Progress-decayStep)
+			if zone.Progress == 0 {
+				zone.Capturer = ""
+			}
+			continue
+		}
+
+		var side string
+		for s := range sides {
+			side = s
+		}
+
+		if side == zone.Owner {
+			// Holders push back any enemy progress
+			zone.Progress = math.Max(0, zone.Progress-captureStep)
+			if zone.Progress == 0 {
+				zone.Capturer = ""
+			}
+			continue
+		}
+
+		// A different side has to undo the previous capturer's progress first
+		if zone.Capturer != side {
+			zone.Progress -= captureStep
+			if zone.Progress <= 0 {
+				zone.Progress = 0
+				zone.Capturer = side
+			}
+			continue
+		}
+
+		zone.Progress += captureStep
+		if zone.Progress >= 1 {
+			log.Info("Zone captured", "zone", zone.ID, "owner", side, "previousOwner", zone.Owner)
+			zone.Owner = side
+			zone.Capturer = ""
+			zone.Progress = 0
+		}
+	}
+}
+
+// scoreZones awards one point per held zone to its owner
+func (k *kingOfTheHillMode) scoreZones(state *GameState) {
+	for _, zone := range state.Zones {
+		if zone.Owner == "" {
+			continue
+		}
+
+		if team, exists := state.Teams[zone.Owner]; exists {
+			team.Score++
+			state.Teams[zone.Owner] = team
+		} else if player, exists := state.Players[zone.Owner]; exists {
+			player.Score++
+			state.Players[zone.Owner] = player
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package game
+
+import (
+	"sort"
+
+	"github.com/charmbracelet/log"
+)
+
+// MatchPhase is a step of the match lifecycle
+type MatchPhase string
+
+const (
+	MatchPhaseWaiting   MatchPhase = "waiting"    // Not enough players to start a match
+	MatchPhaseWarmup    MatchPhase = "warmup"     // Free play while the countdown to the match runs
+	MatchPhaseLive      MatchPhase = "live"       // The match is being played and scored
+	MatchPhasePostMatch MatchPhase = "post_match" // Scoreboard before the next map
+)
+
+// DefaultMapName is the name of the built-in map
+const DefaultMapName = "default"
+
+// Lifecycle defaults
+const (
+	defaultMinPlayers     = 2
+	defaultWarmupTimeS    = 30
+	defaultPostMatchTimeS = 15
+)
+
+// LifecycleConfig holds the match lifecycle settings of a room.
+// Zero values use the defaults.
+type LifecycleConfig struct {
+	MinPlayers    int      `json:"minPlayers"`    // Tanks, including NPCs, needed to leave waiting
+	WarmupTime    int      `json:"warmupTime"`    // Warmup length in seconds
+	PostMatchTime int      `json:"postMatchTime"` // Scoreboard length in seconds
+	Maps          []string `json:"maps"`          // Map rotation, played in order; the room moves on after each scoreboard
+}
+
+// withDefaults fills in zero values
+func (c LifecycleConfig) withDefaults() LifecycleConfig {
+	if c.MinPlayers <= 0 {
+		c.MinPlayers = defaultMinPlayers
+	}
+	if c.WarmupTime <= 0 {
+		c.WarmupTime = defaultWarmupTimeS
+	}
+	if c.PostMatchTime <= 0 {
+		c.PostMatchTime = defaultPostMatchTimeS
+	}
+	if len(c.Maps) == 0 {
+		c.Maps = []string{DefaultMapName}
+	}
+	return c
+}
+
+// PlayerTally counts what a player did in a live match beyond kills and deaths
+type PlayerTally struct {
+	ShotsFired  int   `json:"shotsFired"`
+	Hits        int   `json:"hits"`
+	DamageDealt int   `json:"damageDealt"`
+	DamageTaken int   `json:"damageTaken"`
+	PlaytimeMs  int64 `json:"playtimeMs"` // Time spent in the live match
+}
+
+// add accumulates another tally into this one
+func (t *PlayerTally) add(other PlayerTally) {
+	t.ShotsFired += other.ShotsFired
+	t.Hits += other.Hits
+	t.DamageDealt += other.DamageDealt
+	t.DamageTaken += other.DamageTaken
+	t.PlaytimeMs += other.PlaytimeMs
+}
+
+// PlayerResult is one player's line in a finished match
+type PlayerResult struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Team   string `json:"team,omitempty"`
+	Kills  int    `json:"kills"`
+	Deaths int    `json:"deaths"`
+	Score  int    `json:"score"`
+	Left   bool   `json:"left,omitempty"` // True if the player left before the match ended
+	PlayerTally
+}
+
+// add accumulates the line of an earlier stint in the same match
+func (r *PlayerResult) add(before PlayerResult) {
+	r.Kills += before.Kills
+	r.Deaths += before.Deaths
+	r.Score += before.Score
+	r.PlayerTally.add(before.PlayerTally)
+}
+
+// MatchResult is the final result of a match
+type MatchResult struct {
+	Room      string               `json:"room"` // Set by the room that played the match
+	Number    int                  `json:"number"`
+	Mode      GameModeType         `json:"mode"`
+	Map       string               `json:"map"`
+	StartedAt int64                `json:"startedAt"`
+	EndedAt   int64                `json:"endedAt"`
+	Winner    string               `json:"winner,omitempty"`
+	Teams     map[string]TeamState `json:"teams,omitempty"`
+	Players   []PlayerResult       `json:"players"`
+}
+
+// MatchRecorder persists the results of finished matches
+type MatchRecorder interface {
+	RecordMatch(result MatchResult) error
+}
+
+// StatsRecorder receives combat events of live matches for persistent player statistics.
+// It is called with the manager's mutex held, so implementations must not block.
+type StatsRecorder interface {
+	// ShotFired is called when a player fires a shell
+	ShotFired(playerID string)
+	// Hit is called when damage is applied; killed is true if the hit destroyed the target
+	Hit(sourceID, targetID string, damage int, killed bool)
+}
+
+// SetLifecycle applies new lifecycle settings and sends the room back to waiting for players
+func (m *Manager) SetLifecycle(cfg LifecycleConfig) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.lifecycle = cfg.withDefaults()
+	m.mapIndex = 0
+	m.loadingMap = false
+	m.nextMap = nil
+	m.state.Match.Map = m.lifecycle.Maps[0]
+	m.enterPhase(MatchPhaseWaiting, m.getTime())
+
+	log.Info("Match lifecycle configured", "minPlayers", m.lifecycle.MinPlayers, "warmup", m.lifecycle.WarmupTime, "postMatch", m.lifecycle.PostMatchTime, "maps", m.lifecycle.Maps)
+}
+
+// SetMatchRecorder sets where finished match results are persisted
+func (m *Manager) SetMatchRecorder(recorder MatchRecorder) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.recorder = recorder
+}
+
+// SetStatsRecorder sets where combat events are sent for career stats
+func (m *Manager) SetStatsRecorder(stats StatsRecorder) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stats = stats
+}
+
+// addTally adds to a player's tally for the live match. Caller must hold the mutex.
+func (m *Manager) addTally(playerID string, delta PlayerTally) {
+	if m.tallies == nil {
+		return
+	}
+	tally := m.tallies[playerID]
+	tally.add(delta)
+	m.tallies[playerID] = tally
+}
+
+// enterPhase switches the lifecycle phase and starts its timer. Caller must hold the mutex.
+func (m *Manager) enterPhase(phase MatchPhase, now int64) {
+	m.state.Match.Phase = phase
+	m.state.Match.PhaseEndsAt = 0
+	m.state.Match.Countdown = 0
+
+	switch phase {
+	case MatchPhaseWaiting, MatchPhaseWarmup:
+		// The previous result has been shown; damage is allowed again
+		m.state.Match.Over = false
+		m.state.Match.Winner = ""
+		m.state.Match.EndedAt = 0
+		m.state.Match.EndsAt = 0
+		if phase == MatchPhaseWarmup {
+			m.state.Match.PhaseEndsAt = now + int64(m.lifecycle.WarmupTime)*1000
+		}
+	case MatchPhaseLive:
+		m.state.Match.PhaseEndsAt = m.state.Match.EndsAt
+	case MatchPhasePostMatch:
+		m.state.Match.PhaseEndsAt = now + int64(m.lifecycle.PostMatchTime)*1000
+		m.loadNextMap()
+	}
+
+	log.Info("Match phase changed", "phase", phase, "match", m.state.Match.Number, "map", m.state.Match.Map)
+	m.emitEvent(MatchEvent{Type: MatchEventPhase, Phase: phase, Match: m.state.Match.Number})
+}
+
+// updateMatch advances the match lifecycle and runs the game mode while a match is live
+func (m *Manager) updateMatch() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.getTime()
+	match := &m.state.Match
+	enoughPlayers := len(m.state.Players) >= m.lifecycle.MinPlayers
+
+	switch match.Phase {
+	case MatchPhaseWaiting:
+		if enoughPlayers {
+			m.enterPhase(MatchPhaseWarmup, now)
+		}
+
+	case MatchPhaseWarmup:
+		if !enoughPlayers {
+			m.enterPhase(MatchPhaseWaiting, now)
+		} else if now >= match.PhaseEndsAt {
+			m.startLiveMatch(now)
+		}
+
+	case MatchPhaseLive:
+		// Everyone in the room is playing the match
+		for id := range m.state.Players {
+			m.addTally(id, PlayerTally{PlaytimeMs: now - m.lastMatchUpdate})
+		}
+
+		m.mode.Update(&m.state, now)
+		match.PhaseEndsAt = match.EndsAt
+		if match.Over {
+			m.finishMatch()
+			m.enterPhase(MatchPhasePostMatch, now)
+		}
+
+	case MatchPhasePostMatch:
+		// Move on to the next map in the rotation once the scoreboard is over and the map is built
+		if now >= match.PhaseEndsAt && m.advanceMap(now) {
+			if enoughPlayers {
+				m.enterPhase(MatchPhaseWarmup, now)
+			} else {
+				m.enterPhase(MatchPhaseWaiting, now)
+			}
+		}
+
+	default:
+		m.enterPhase(MatchPhaseWaiting, now)
+	}
+
+	m.lastMatchUpdate = now
+
+	// Broadcast whole seconds left so clients can show a countdown
+	match.Countdown = 0
+	if match.PhaseEndsAt > now {
+		match.Countdown = int((match.PhaseEndsAt - now + 999) / 1000)
+	}
+}
+
+// startLiveMatch resets every player's stats and starts the mode's rules. Caller must hold the mutex.
+func (m *Manager) startLiveMatch(now int64) {
+	for id, player := range m.state.Players {
+		player.Kills = 0
+		player.Deaths = 0
+		player.Score = 0
+		player.LastKilledBy = ""
+
+		// Everyone starts the match with a fresh tank
+		if player.IsDestroyed {
+			player.IsDestroyed = false
+			player.Status = StatusActive
+		}
+		player.Health = 100
+
+		m.state.Players[id] = player
+	}
+	m.departed = make(map[string]PlayerResult)
+	m.tallies = make(map[string]PlayerTally)
+	m.restoreObstaclesLocked()
+
+	m.mode.Start(&m.state, now)
+	m.state.Match.Number++
+	m.state.Match.StartedAt = now
+	m.enterPhase(MatchPhaseLive, now)
+
+	if m.replay != nil {
+		m.replay.StartMatch(m.state.Match, m.gameMap)
+	}
+}
+
+// recordDeparture keeps the stats of a player who leaves a live match for its result.
+// Caller must hold the mutex.
+func (m *Manager) recordDeparture(player PlayerState) {
+	if m.state.Match.Phase != MatchPhaseLive || m.departed == nil {
+		return
+	}
+	line := m.playerResult(player, true)
+	if before, exists := m.departed[player.ID]; exists {
+		line.add(before)
+	}
+	m.departed[player.ID] = line
+
+	// A player who rejoins starts a new tally
+	delete(m.tallies, player.ID)
+}
+
+// finishMatch builds the result of the match that just ended and persists it. Caller must hold the mutex.
+func (m *Manager) finishMatch() {
+	match := m.state.Match
+	result := MatchResult{
+		Number:    match.Number,
+		Mode:      match.Mode,
+		Map:       match.Map,
+		StartedAt: match.StartedAt,
+		EndedAt:   match.EndedAt,
+		Winner:    match.Winner,
+		Players:   make([]PlayerResult, 0, len(m.state.Players)+len(m.departed)),
+	}
+
+	if m.state.Teams != nil {
+		result.Teams = make(map[string]TeamState, len(m.state.Teams))
+		for id, team := range m.state.Teams {
+			result.Teams[id] = team
+		}
+	}
+
+	for id, player := range m.state.Players {
+		line := m.playerResult(player, false)
+
+		// Players who left and came back keep what they scored before leaving
+		if before, rejoined := m.departed[id]; rejoined {
+			line.add(before)
+			delete(m.departed, id)
+		}
+		result.Players = append(result.Players, line)
+	}
+	for _, player := range m.departed {
+		result.Players = append(result.Players, player)
+	}
+	m.departed = nil
+	m.tallies = nil
+
+	// Best score first, then most kills
+	sort.Slice(result.Players, func(i, j int) bool {
+		a, b := result.Players[i], result.Players[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Kills > b.Kills
+	})
+
+	log.Info("Match finished", "match", result.Number, "mode", result.Mode, "map", result.Map, "winner", result.Winner, "players", len(result.Players))
+
+	if m.replay != nil {
+		m.replay.EndMatch(result)
+	}
+
+	if m.recorder == nil {
+		return
+	}
+
+	// Persist outside the tick so storage latency never stalls the simulation
+	recorder := m.recorder
+	go func() {
+		if err := recorder.RecordMatch(result); err != nil {
+			log.Error("Error recording match result", "match", result.Number, "error", err)
+		}
+	}()
+}
+
+// playerResult converts a player's state and tally into their match result line.
+// Caller must hold the mutex.
+func (m *Manager) playerResult(player PlayerState, left bool) PlayerResult {
+	return PlayerResult{
+		ID:          player.ID,
+		Name:        player.Name,
+		Team:        player.Team,
+		Kills:       player.Kills,
+		Deaths:      player.Deaths,
+		Score:       player.Score,
+		Left:        left,
+		PlayerTally: m.tallies[player.ID],
+	}
+}
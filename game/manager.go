@@ -4,7 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"math/rand"
+	"math"
 	"strings"
 	"sync"
 	"time"
@@ -13,6 +13,9 @@ import (
 	"github.com/nats-io/nats.go/jetstream"
 )
 
+// respawnDelayMs is how long a destroyed tank waits before it can respawn
+const respawnDelayMs = 5000
+
 // Player colors for consistent player identification
 var playerColors = []string{
 	"#4a7c59", // Green (default)
@@ -28,15 +31,44 @@ type Manager struct {
 	state              GameState
 	mutex              sync.RWMutex
 	kv                 jetstream.KeyValue
+	stateKey           string // KV key this manager publishes its game state under
 	ctx                context.Context
 	shellIDCounter     int
 	getTime            TimeStamper
-	lastPlayerFireTime map[string]int64 // Map to track the last time each player fired a shell
-	fireCooldownMs     int64            // Cooldown time between firing shells
+	lastPlayerFireTime map[string]int64       // Map to track the last time each player fired a shell
+	fireCooldownMs     int64                  // Cooldown time between firing shells
+	gameMap            *GameMap               // Static obstacles used for movement collisions
+	inputs             map[string]PlayerInput // Latest control input per client-controlled tank
+	tickRate           int                    // Simulation ticks per second
+	phases             map[TickPhase][]TickFunc
+	phaseMutex         sync.RWMutex // Guards phases separately so phase functions can take the state mutex
+	mode               GameMode     // Rules for teams, damage and scoring
+	lifecycle          LifecycleConfig
+	recorder           MatchRecorder           // Where finished match results are persisted, nil to skip
+	departed           map[string]PlayerResult // Stats of players who left the live match
+	tallies            map[string]PlayerTally  // Shots, hits, damage and playtime in the live match
+	lastMatchUpdate    int64                   // Server time of the previous lifecycle update, for playtime
+	stats              StatsRecorder           // Receives combat events for career stats, nil to skip
+	mapIndex           int                     // Position of the current map in the rotation
+	hits               *hitValidator           // Compares client hit claims with server-detected hits
+	obstacleDamage     map[string]int          // Shell hits taken by destructible obstacles this match
+	replay             ReplayRecorder          // Records matches for replays, nil to skip
+	events             EventPublisher          // Broadcasts match events to clients, nil to skip
+	spectators         map[string]int          // Open spectator connections per user
+	mapLoader          MapLoader               // Builds the maps of the rotation, nil to stay on the first map
+	mapListeners       []MapListener           // Components that follow map changes
+	mapLoads           int                     // Number of next-map builds started, to ignore superseded ones
+	loadingMap         bool                    // The next map is being built for the end of the scoreboard
+	nextMap            *loadedMap              // The built next map, nil until it is ready
+	changedMap         *GameMap                // New map the listeners have not been told about yet
 }
 
 // NewManager creates a new game manager instance
-func NewManager(ctx context.Context, kv jetstream.KeyValue) (*Manager, error) {
+func NewManager(ctx context.Context, kv jetstream.KeyValue, stateKey string, gameMap *GameMap, tickRate int) (*Manager, error) {
+	if err := ValidateTickRate(tickRate); err != nil {
+		return nil, err
+	}
+
 	manager := &Manager{
 		state: GameState{
 			Players: make(map[string]PlayerState),
@@ -44,15 +76,30 @@ func NewManager(ctx context.Context, kv jetstream.KeyValue) (*Manager, error) {
 		},
 		mutex:              sync.RWMutex{},
 		kv:                 kv,
+		stateKey:           stateKey,
 		ctx:                ctx,
 		shellIDCounter:     0,
 		getTime:            DefaultTimeStamper,
 		lastPlayerFireTime: make(map[string]int64),
 		fireCooldownMs:     500, // 500ms cooldown between shell firings
+		gameMap:            gameMap,
+		inputs:             make(map[string]PlayerInput),
+		tickRate:           tickRate,
+		phases:             make(map[TickPhase][]TickFunc),
+		mode:               &freeForAllMode{config: ModeConfig{ScoreLimit: defaultKillLimit, TimeLimit: defaultMatchTimeLimitS}},
+		lifecycle:          LifecycleConfig{}.withDefaults(),
+		hits:               newHitValidator(),
+		obstacleDamage:     make(map[string]int),
+		spectators:         make(map[string]int),
 	}
 
 	// Always ensure we start with an empty players map
 	manager.state.Players = make(map[string]PlayerState)
+	manager.mode.Start(&manager.state, manager.getTime())
+
+	// No match until enough players have joined
+	manager.state.Match.Map = manager.lifecycle.Maps[0]
+	manager.enterPhase(MatchPhaseWaiting, manager.getTime())
 
 	// Save initial empty state to KV
 	if err := manager.saveState(); err != nil {
@@ -61,8 +108,8 @@ func NewManager(ctx context.Context, kv jetstream.KeyValue) (*Manager, error) {
 
 	log.Debug("Game manager initialized", "state", "empty players map")
 
-	// Start background processes
-	go manager.runStateCleanup()
+	// Start the authoritative tick loop; it is the only publisher of game state
+	go manager.runTickLoop()
 
 	return manager, nil
 }
@@ -72,10 +119,50 @@ func (m *Manager) GetState() GameState {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
-	// Create a deep copy to avoid race conditions
+	return m.copyState()
+}
+
+// copyState returns a deep copy of the current game state. Caller must hold the mutex.
+func (m *Manager) copyState() GameState {
 	stateCopy := GameState{
-		Players: make(map[string]PlayerState, len(m.state.Players)),
-		Shells:  make([]ShellState, len(m.state.Shells)),
+		Players:    make(map[string]PlayerState, len(m.state.Players)),
+		Shells:     make([]ShellState, len(m.state.Shells)),
+		Tick:       m.state.Tick,
+		ServerTime: m.state.ServerTime,
+		Match:      m.state.Match,
+		Spectators: m.state.Spectators,
+	}
+
+	// Copy teams
+	if m.state.Teams != nil {
+		stateCopy.Teams = make(map[string]TeamState, len(m.state.Teams))
+		for id, team := range m.state.Teams {
+			stateCopy.Teams[id] = team
+		}
+	}
+
+	// Copy zones
+	if m.state.Zones != nil {
+		stateCopy.Zones = make([]ZoneState, len(m.state.Zones))
+		copy(stateCopy.Zones, m.state.Zones)
+	}
+
+	// Copy flags
+	if m.state.Flags != nil {
+		stateCopy.Flags = make(map[string]FlagState, len(m.state.Flags))
+		for team, flag := range m.state.Flags {
+			stateCopy.Flags[team] = flag
+		}
+	}
+
+	// Copy impacts and broken obstacles
+	if m.state.Impacts != nil {
+		stateCopy.Impacts = make([]ImpactState, len(m.state.Impacts))
+		copy(stateCopy.Impacts, m.state.Impacts)
+	}
+	if m.state.DestroyedObstacles != nil {
+		stateCopy.DestroyedObstacles = make([]ObstacleState, len(m.state.DestroyedObstacles))
+		copy(stateCopy.DestroyedObstacles, m.state.DestroyedObstacles)
 	}
 
 	// Copy players
@@ -89,12 +176,12 @@ func (m *Manager) GetState() GameState {
 	return stateCopy
 }
 
-// UpdatePlayer handles player state updates
+// UpdatePlayer handles player state updates from trusted server-side controllers such as NPCs.
+// Client-controlled tanks are moved through ApplyInput instead.
 func (m *Manager) UpdatePlayer(update PlayerState, playerID string, playerName string) error {
 	// Set ID and name in player update
 	update.ID = playerID
 	update.Name = playerName
-	update.Color = m.getPlayerColor(playerID)
 
 	// Get current player state if exists
 	m.mutex.RLock()
@@ -103,18 +190,10 @@ func (m *Manager) UpdatePlayer(update PlayerState, playerID string, playerName s
 
 	// Handle new player joining (not in game state yet)
 	if !playerExists {
-		// Random position anywhere on the 5000x5000 map
-		posX := -2500.0 + rand.Float64()*5000.0
-		posZ := -2500.0 + rand.Float64()*5000.0
-
-		log.Info("New player joined", "playerID", playerID, "posX", posX, "posZ", posZ)
-
 		// Set spawn position across full map
-		update.Position = Position{
-			X: posX,
-			Y: 0,
-			Z: posZ,
-		}
+		update.Position = m.randomSpawnPosition()
+
+		log.Info("New player joined", "playerID", playerID, "posX", update.Position.X, "posZ", update.Position.Z)
 
 		// Initialize health, kills and deaths for new player
 		update.Health = 100
@@ -138,41 +217,90 @@ func (m *Manager) UpdatePlayer(update PlayerState, playerID string, playerName s
 			update.Status = currentPlayer.Status
 		}
 
-		// Preserve existing kills and deaths counts from current player state
+		// Preserve existing kills, deaths and objective score from current player state
 		update.Kills = currentPlayer.Kills
 		update.Deaths = currentPlayer.Deaths
+		update.Score = currentPlayer.Score
 	}
 
+	// Controllers only steer on the ground plane; keep the tank on the terrain
+	update.Position.Y = m.gameMap.GroundHeight(update.Position.X, update.Position.Z)
+
 	// Update player state in game state
 	m.mutex.Lock()
+	if playerExists {
+		// Keep the team the player was assigned on join
+		update.Team = currentPlayer.Team
+	} else {
+		m.mode.AssignPlayer(&m.state, &update)
+	}
+	update.Color = m.getPlayerColor(update)
 	m.state.Players[playerID] = update
+	if !playerExists {
+		m.emitEvent(MatchEvent{Type: MatchEventJoin, PlayerID: playerID, PlayerName: playerName})
+	}
 	m.mutex.Unlock()
 
-	// NOTE: Physics collision detection will occur in a separate goroutine
-	// via the PhysicsIntegration component initiated in main.go
-
-	// Save to KV store
-	if err := m.saveState(); err != nil {
-		log.Error("Error saving game state after player update", "error", err)
-	}
+	// NOTE: Physics collision detection and publishing happen in the tick loop
 
 	log.Debug("Updated player position", "playerID", playerID, "x", update.Position.X, "y", update.Position.Y, "z", update.Position.Z)
 
 	return nil
 }
 
-// FireShell adds a new shell to the game state with debouncing
+// FireShell fires a shell from a player's tank with debouncing. The muzzle
+// position, direction and speed come from the tank's server state; only the view
+// tick used for lag compensation is taken from the client.
 func (m *Manager) FireShell(shellData ShellData, playerID string) (ShellState, error) {
-	// Apply debouncing logic
-	currentTime := m.getTime()
-
 	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	player, exists := m.state.Players[playerID]
+	if !exists {
+		return ShellState{}, fmt.Errorf("player %s not found", playerID)
+	}
+	if player.IsDestroyed {
+		log.Debug("Rejected shell firing", "playerID", playerID, "reason", "tank destroyed")
+		return ShellState{}, fmt.Errorf("player %s is destroyed", playerID)
+	}
+
+	return m.addShell(muzzleShell(player, shellData.ViewTick), playerID)
+}
+
+// muzzleShell returns the shell a tank fires from the end of its barrel, matching
+// the barrel geometry of tank.ts
+func muzzleShell(player PlayerState, viewTick uint64) ShellData {
+	yaw := player.TankRotation + player.TurretRotation
+	elevation := clampFloat(player.BarrelElevation, minBarrelElevation, maxBarrelElevation)
+
+	// Negative elevation raises the barrel
+	direction := Position{
+		X: math.Sin(yaw) * math.Cos(elevation),
+		Y: -math.Sin(elevation),
+		Z: math.Cos(yaw) * math.Cos(elevation),
+	}
+
+	return ShellData{
+		Position: Position{
+			X: player.Position.X + direction.X*barrelLength,
+			Y: player.Position.Y + turretHeight + direction.Y*barrelLength,
+			Z: player.Position.Z + direction.Z*barrelLength,
+		},
+		Direction: direction,
+		Speed:     shellSpeed,
+		ViewTick:  viewTick,
+	}
+}
+
+// addShell adds a new shell to the game state if the shooter's cooldown has passed.
+// Caller must hold the mutex.
+func (m *Manager) addShell(shellData ShellData, playerID string) (ShellState, error) {
+	currentTime := m.getTime()
 
 	// Check if the player has fired recently
 	lastFireTime, exists := m.lastPlayerFireTime[playerID]
 	if exists && (currentTime-lastFireTime < m.fireCooldownMs) {
 		// Player is trying to fire too quickly
-		m.mutex.Unlock()
 		log.Debug("Rejected shell firing", "playerID", playerID, "reason", "cooldown in effect")
 		return ShellState{}, fmt.Errorf("firing too rapidly, please wait %dms between shots", m.fireCooldownMs)
 	}
@@ -180,36 +308,47 @@ func (m *Manager) FireShell(shellData ShellData, playerID string) (ShellState, e
 	// Update the last fire time for this player
 	m.lastPlayerFireTime[playerID] = currentTime
 
+	// Count the shot towards match and career accuracy
+	if m.state.Match.Phase == MatchPhaseLive {
+		m.addTally(playerID, PlayerTally{ShotsFired: 1})
+		if m.stats != nil {
+			m.stats.ShotFired(playerID)
+		}
+	}
+
 	// Generate shell ID
 	m.shellIDCounter++
 	newShell := ShellState{
-		ID:        fmt.Sprintf("shell_%d", m.shellIDCounter),
-		PlayerID:  playerID,
-		Position:  shellData.Position,
-		Direction: shellData.Direction,
-		Speed:     shellData.Speed,
-		Timestamp: currentTime,
+		ID:          fmt.Sprintf("shell_%d", m.shellIDCounter),
+		PlayerID:    playerID,
+		Position:    shellData.Position,
+		Direction:   shellData.Direction,
+		Speed:       shellData.Speed,
+		Timestamp:   currentTime,
+		RewindTicks: m.rewindTicks(shellData.ViewTick),
 	}
 
 	// Add shell to game state
 	m.state.Shells = append(m.state.Shells, newShell)
+	m.emitEvent(MatchEvent{
+		Type:      MatchEventShotFired,
+		PlayerID:  playerID,
+		ShellID:   newShell.ID,
+		Position:  &newShell.Position,
+		Direction: &newShell.Direction,
+	})
 
 	// Cap the number of shells to avoid memory issues
 	if len(m.state.Shells) > 100 {
 		m.state.Shells = m.state.Shells[len(m.state.Shells)-100:]
 	}
-	m.mutex.Unlock()
-
-	// Save to KV store
-	if err := m.saveState(); err != nil {
-		log.Error("Error saving game state after shell fired", "error", err)
-	}
 
 	log.Debug("Added new shell", "shellID", newShell.ID, "playerID", playerID)
 	return newShell, nil
 }
 
-// ProcessTankHit handles when a tank is hit by a shell - server is authoritative for all damage
+// ProcessTankHit handles when a tank is hit by a shell - server is authoritative for all damage.
+// Only the physics engine calls this; client hit reports go through ReportHitClaim.
 func (m *Manager) ProcessTankHit(hitData HitData) error {
 	// Create a transaction function to be executed with proper locking
 	processTankHitFunc := func() error {
@@ -228,6 +367,12 @@ func (m *Manager) ProcessTankHit(hitData HitData) error {
 				return nil
 			}
 
+			// Let the game mode veto damage, e.g. friendly fire in team modes
+			if sourcePlayer, sourceExists := m.state.Players[hitData.SourceID]; sourceExists && !m.mode.CanDamage(&m.state, sourcePlayer, targetPlayer) {
+				log.Debug("Hit ignored by game mode", "targetID", hitData.TargetID, "sourceID", hitData.SourceID, "mode", m.mode.Type())
+				return nil
+			}
+
 			// Log detailed hit location info for debugging
 			log.Debug("Tank hit", "targetID", hitData.TargetID, "location", hitData.HitLocation, "damage", hitData.DamageAmount, "sourceID", hitData.SourceID)
 
@@ -243,6 +388,26 @@ func (m *Manager) ProcessTankHit(hitData HitData) error {
 
 			// Apply damage to tank
 			targetPlayer.Health = targetPlayer.Health - hitData.DamageAmount
+			m.recordServerHitLocked(hitData)
+			m.emitEvent(MatchEvent{
+				Type:        MatchEventHit,
+				PlayerID:    hitData.SourceID,
+				TargetID:    hitData.TargetID,
+				Weapon:      WeaponCannon,
+				HitLocation: hitData.HitLocation,
+				Damage:      hitData.DamageAmount,
+			})
+
+			// Count hits and damage towards match and career stats
+			if m.state.Match.Phase == MatchPhaseLive {
+				m.addTally(hitData.TargetID, PlayerTally{DamageTaken: hitData.DamageAmount})
+				if hitData.SourceID != hitData.TargetID {
+					m.addTally(hitData.SourceID, PlayerTally{Hits: 1, DamageDealt: hitData.DamageAmount})
+				}
+				if m.stats != nil {
+					m.stats.Hit(hitData.SourceID, hitData.TargetID, hitData.DamageAmount, targetPlayer.Health <= 0)
+				}
+			}
 
 			// Log health after damage
 			log.Debug("Tank health after hit", "targetID", hitData.TargetID, "health", targetPlayer.Health)
@@ -255,35 +420,36 @@ func (m *Manager) ProcessTankHit(hitData HitData) error {
 
 				// Increment target player's death count
 				targetPlayer.Deaths++
+				m.emitEvent(MatchEvent{
+					Type:        MatchEventKill,
+					PlayerID:    hitData.SourceID,
+					PlayerName:  m.playerName(hitData.SourceID),
+					TargetID:    hitData.TargetID,
+					TargetName:  targetPlayer.Name,
+					Weapon:      WeaponCannon,
+					HitLocation: hitData.HitLocation,
+				})
 
 				// Increment the source player's kill count if they exist
 				if sourcePlayer, sourceExists := m.state.Players[hitData.SourceID]; sourceExists {
 					sourcePlayer.Kills++
 					m.state.Players[hitData.SourceID] = sourcePlayer
 					log.Debug("Incremented kill count", "playerID", hitData.SourceID, "kills", sourcePlayer.Kills)
-				}
 
-				// Track kill information for client notifications
-				targetPlayer.LastKilledBy = hitData.SourceID
-				targetPlayer.LastDeathTime = m.getTime()
-
-				// Get killer and victim names for notification
-				killerName := "Unknown"
-				victimName := "Unknown"
-
-				if sourcePlayer, exists := m.state.Players[hitData.SourceID]; exists {
-					killerName = sourcePlayer.Name
+					// Award mode points, e.g. team score, only while the match is live
+					if m.state.Match.Phase == MatchPhaseLive {
+						m.mode.OnKill(&m.state, sourcePlayer, targetPlayer)
+					}
 				}
 
-				victimName = targetPlayer.Name
+				// Let the mode react to the death, e.g. drop a carried flag
+				m.mode.OnDeath(&m.state, targetPlayer)
 
-				// Create notification message for client display
-				notification := fmt.Sprintf("%s destroyed %s", killerName, victimName)
-
-				// This will be handled by signals in the frontend
-				targetPlayer.Notification = notification
+				// Track who killed the player and when, for the respawn timer
+				targetPlayer.LastKilledBy = hitData.SourceID
+				targetPlayer.LastDeathTime = m.getTime()
 
-				log.Info("Tank destroyed", "message", notification)
+				log.Info("Tank destroyed", "killerID", hitData.SourceID, "victimID", hitData.TargetID, "location", hitData.HitLocation)
 			}
 
 			// Save updated player back to game state
@@ -314,6 +480,8 @@ func (m *Manager) ProcessTankHit(hitData HitData) error {
 				IsDestroyed: false,
 				Status:      StatusActive, // Default to active status
 			}
+			m.mode.AssignPlayer(&m.state, &newPlayer)
+			newPlayer.Color = m.getPlayerColor(newPlayer)
 
 			// Check if health is zero
 			if newPlayer.Health <= 0 {
@@ -342,12 +510,6 @@ func (m *Manager) ProcessTankHit(hitData HitData) error {
 		return err
 	}
 
-	// Save state after processing hit (without holding lock)
-	if err := m.saveState(); err != nil {
-		log.Error("Error saving game state after tank hit", "error", err)
-		return err
-	}
-
 	// Check if this was a killing hit
 	m.mutex.RLock()
 	targetPlayer, exists := m.state.Players[hitData.TargetID]
@@ -367,128 +529,60 @@ func (m *Manager) ProcessTankHit(hitData HitData) error {
 	return nil
 }
 
-// RespawnTank handles tank respawn events
+// RespawnTank respawns a destroyed tank once the respawn delay has passed
 func (m *Manager) RespawnTank(respawnData RespawnData) error {
-	// Update player in game state
 	m.mutex.Lock()
-	if player, exists := m.state.Players[respawnData.PlayerID]; exists {
-		// Reset health and destroyed status
-		player.Health = 100
-		player.IsDestroyed = false
-		player.Status = StatusActive // Set player status to ACTIVE
-
-		// Keep existing kills and deaths (don't reset them on respawn)
-		// Increment death count only happens in ProcessTankHit
-
-		// Reset movement-related properties
-		player.IsMoving = false
-		player.Velocity = 0.0                                 // Start with zero velocity to prevent erratic movement
-		player.TurretRotation = player.TankRotation           // Reset turret to match tank
-		player.Color = m.getPlayerColor(respawnData.PlayerID) // Ensure color is set consistently
-
-		// Update timestamp to ensure state propagation
-		player.Timestamp = m.getTime()
-
-		// Update position - always use the full map range like in UpdatePlayer
-		// Random position anywhere on the 5000x5000 map
-		player.Position = Position{
-			X: -2500.0 + rand.Float64()*5000.0,
-			Y: 0,
-			Z: -2500.0 + rand.Float64()*5000.0,
-		}
-
-		// Save updated player back to game state
-		m.state.Players[respawnData.PlayerID] = player
-
-		log.Info("Tank respawned", 
-			"playerID", respawnData.PlayerID,
-			"health", player.Health,
-			"destroyed", player.IsDestroyed,
-			"x", player.Position.X,
-			"y", player.Position.Y,
-			"z", player.Position.Z)
+	defer m.mutex.Unlock()
 
-		m.mutex.Unlock()
+	player, exists := m.state.Players[respawnData.PlayerID]
+	if !exists {
+		return fmt.Errorf("player %s not found", respawnData.PlayerID)
+	}
+	if !player.IsDestroyed {
+		return fmt.Errorf("player %s is not destroyed", respawnData.PlayerID)
+	}
+	if wait := player.LastDeathTime + respawnDelayMs - m.getTime(); wait > 0 {
+		return fmt.Errorf("player %s can respawn in %dms", respawnData.PlayerID, wait)
+	}
 
-		// Save to KV store
-		if err := m.saveState(); err != nil {
-			log.Error("Error saving game state after tank respawn", "error", err)
-			return err
-		}
+	// Reset health and destroyed status
+	player.Health = 100
+	player.IsDestroyed = false
+	player.Status = StatusActive // Set player status to ACTIVE
 
-		return nil
-	} else {
-		// Player not found, create a new one
-		log.Info("Player not found for respawn", "playerID", respawnData.PlayerID, "action", "creating new entry")
-
-		// Use playerID as both ID and name, like in UpdatePlayer
-		playerID := respawnData.PlayerID
-
-		// Random position anywhere on the 5000x5000 map (same as in UpdatePlayer)
-		posX := -2500.0 + rand.Float64()*5000.0
-		posZ := -2500.0 + rand.Float64()*5000.0
-
-		log.Info("New player joined via respawn", 
-			"playerID", playerID, 
-			"posX", posX, 
-			"posZ", posZ)
-
-		// Create new player with same initialization as UpdatePlayer
-		newPlayer := PlayerState{
-			ID:   playerID,
-			Name: playerID, // Use ID as name like UpdatePlayer would
-			Position: Position{
-				X: posX,
-				Y: 0,
-				Z: posZ,
-			},
-			Health:      100,
-			IsDestroyed: false,
-			Kills:       0,
-			Deaths:      0,
-			Status:      StatusActive, // Player is active
-			Timestamp:   m.getTime(),
-			Color:       m.getPlayerColor(playerID),
-			IsMoving:    false,
-			Velocity:    0.0,
-		}
+	// Keep existing kills and deaths (don't reset them on respawn)
+	// Increment death count only happens in ProcessTankHit
 
-		// Add to game state
-		m.state.Players[playerID] = newPlayer
+	// Reset movement-related properties
+	player.IsMoving = false
+	player.Velocity = 0.0                       // Start with zero velocity to prevent erratic movement
+	player.TurretRotation = player.TankRotation // Reset turret to match tank
+	player.Color = m.getPlayerColor(player)     // Ensure color is set consistently
 
-		log.Info("Created new tank via respawn", 
-			"playerID", playerID,
-			"health", 100,
-			"x", posX,
-			"y", 0.0,
-			"z", posZ)
+	// Update timestamp to ensure state propagation
+	player.Timestamp = m.getTime()
 
-		m.mutex.Unlock()
+	// Update position - always use the full map range like in UpdatePlayer
+	player.Position = m.randomSpawnPosition()
 
-		// Save to KV store
-		if err := m.saveState(); err != nil {
-			log.Error("Error saving game state after new player creation", "error", err)
-			return err
-		}
+	// Save updated player back to game state
+	m.state.Players[respawnData.PlayerID] = player
+	m.emitEvent(MatchEvent{Type: MatchEventRespawn, PlayerID: respawnData.PlayerID, Position: &player.Position})
 
-		return nil
-	}
-}
+	log.Info("Tank respawned", 
+		"playerID", respawnData.PlayerID,
+		"health", player.Health,
+		"destroyed", player.IsDestroyed,
+		"x", player.Position.X,
+		"y", player.Position.Y,
+		"z", player.Position.Z)
 
-// Get player color based on ID
-func (m *Manager) getPlayerColor(id string) string {
-	// Simple hash of the ID to determine color index
-	var sum int
-	for _, char := range id {
-		sum += int(char)
-	}
-	index := sum % len(playerColors)
-	return playerColors[index]
+	return nil
 }
 
 // Load game state from KV store
 func (m *Manager) loadState() error {
-	entry, err := m.kv.Get(m.ctx, "current")
+	entry, err := m.kv.Get(m.ctx, m.stateKey)
 	if err != nil {
 		return err
 	}
@@ -514,21 +608,10 @@ func (m *Manager) saveState() error {
 	var stateJSON []byte
 	var err error
 
-	// Use a copy of state under read lock
+	// Deep copy the state under read lock to avoid concurrent map access issues
 	m.mutex.RLock()
-	// Deep copy the state to avoid concurrent map access issues
-	stateCopy := GameState{
-		Players: make(map[string]PlayerState, len(m.state.Players)),
-		Shells:  make([]ShellState, len(m.state.Shells)),
-	}
-
-	// Copy players map
-	for id, player := range m.state.Players {
-		stateCopy.Players[id] = player
-	}
-
-	// Copy shells slice
-	copy(stateCopy.Shells, m.state.Shells)
+	stateCopy := m.copyState()
+	replay := m.replay
 	m.mutex.RUnlock()
 
 	// Marshal the copied state
@@ -539,12 +622,17 @@ func (m *Manager) saveState() error {
 	}
 
 	// Perform KV operation without holding lock
-	_, err = m.kv.Put(m.ctx, "current", stateJSON)
+	_, err = m.kv.Put(m.ctx, m.stateKey, stateJSON)
 	if err != nil {
 		log.Error("Error saving game state to KV", "error", err)
 		return fmt.Errorf("error saving game state to KV: %v", err)
 	}
 
+	// Every published snapshot goes into the replay of the live match
+	if replay != nil {
+		replay.RecordState(stateCopy)
+	}
+
 	// Log successful save occasionally
 	if time.Now().UnixNano()%100 == 0 {
 		log.Debug("Game state saved", 
@@ -555,25 +643,11 @@ func (m *Manager) saveState() error {
 	return nil
 }
 
-// Cleanup inactive players and expired shells
-func (m *Manager) runStateCleanup() {
-	for {
-		m.cleanupGameState()
-
-		// Save current game state to KV store
-		if err := m.saveState(); err != nil {
-			log.Error("Error saving game state during cleanup", "error", err)
-		}
-
-		time.Sleep(250 * time.Millisecond)
-	}
-}
-
 // WatchState creates a watcher for game state changes
 // Returns the KeyWatcher directly so caller can use its Updates() channel
 func (m *Manager) WatchState(ctx context.Context) (jetstream.KeyWatcher, error) {
 	// Create a watcher for the KV store
-	watcher, err := m.kv.Watch(ctx, "current", jetstream.UpdatesOnly())
+	watcher, err := m.kv.Watch(ctx, m.stateKey, jetstream.UpdatesOnly())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create KV watcher: %v", err)
 	}
@@ -596,22 +670,21 @@ func (m *Manager) RemovePlayer(playerID string) error {
 		return fmt.Errorf("player with ID %s not found", playerID)
 	}
 	
-	// Remove the player from game state
+	// Remove the player from game state, keeping their match stats for the result
 	m.mutex.Lock()
+	if player, exists := m.state.Players[playerID]; exists {
+		m.recordDeparture(player)
+		m.emitEvent(MatchEvent{Type: MatchEventLeave, PlayerID: playerID, PlayerName: player.Name})
+	}
 	delete(m.state.Players, playerID)
 	
-	// Also clean up the lastPlayerFireTime entry for this player
+	// Also clean up the lastPlayerFireTime and input entries for this player
 	delete(m.lastPlayerFireTime, playerID)
+	delete(m.inputs, playerID)
 	
 	log.Info("Player removed from game state", "playerID", playerID)
 	m.mutex.Unlock()
 	
-	// Save to KV store
-	if err := m.saveState(); err != nil {
-		log.Error("Error saving game state after removing player", "error", err)
-		return fmt.Errorf("error saving game state after removing player: %v", err)
-	}
-	
 	return nil
 }
 
@@ -657,13 +730,31 @@ func (m *Manager) RemoveShells(shellIDs []string) error {
 	removeShellsFunc()
 	m.mutex.Unlock()
 
-	// Save state without holding the lock
-	if err := m.saveState(); err != nil {
-		log.Error("Error saving game state after removing shells", "error", err)
-		return err
+	return nil
+}
+
+// UpdateShellPositions stores where the physics engine moved shells. Shells no
+// longer in the game state, such as ones removed in the meantime, are ignored.
+func (m *Manager) UpdateShellPositions(shells []ShellState) {
+	if len(shells) == 0 {
+		return
 	}
 
-	return nil
+	moved := make(map[string]ShellState, len(shells))
+	for _, shell := range shells {
+		moved[shell.ID] = shell
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, shell := range m.state.Shells {
+		if update, exists := moved[shell.ID]; exists {
+			m.state.Shells[i].Position = update.Position
+			m.state.Shells[i].Direction = update.Direction
+			m.state.Shells[i].Speed = update.Speed
+		}
+	}
 }
 
 // cleanupGameState removes inactive players and expired shells
@@ -678,10 +769,14 @@ func (m *Manager) cleanupGameState() {
 		// If player hasn't updated in 10 seconds, remove them
 		if now-player.Timestamp > 10000 {
 			log.Info("Removing inactive player", "playerID", id)
+			m.recordDeparture(player)
+			m.emitEvent(MatchEvent{Type: MatchEventLeave, PlayerID: id, PlayerName: player.Name})
 			delete(m.state.Players, id)
 
-			// Also clean up the lastPlayerFireTime entry for this player
+			// Also clean up the lastPlayerFireTime and input entries for this player
 			delete(m.lastPlayerFireTime, id)
+			delete(m.inputs, id)
+			continue
 		}
 
 		// Check if player has inconsistent state (destroyed but positive health)
@@ -696,7 +791,7 @@ func (m *Manager) cleanupGameState() {
 		// Auto-respawn destroyed players after 5 seconds
 		if player.IsDestroyed && player.Status == StatusDestroyed {
 			// Check if 5 seconds have passed since death
-			if player.LastDeathTime > 0 && now-player.LastDeathTime >= 5000 {
+			if player.LastDeathTime > 0 && now-player.LastDeathTime >= respawnDelayMs {
 				log.Info("Auto-respawning player", "playerID", id, "delay", "5 seconds")
 
 				// Reset health and destroyed status
@@ -705,11 +800,7 @@ func (m *Manager) cleanupGameState() {
 				player.Status = StatusActive // Set player status to ACTIVE immediately
 
 				// Random position anywhere on the 5000x5000 map
-				player.Position = Position{
-					X: -2500.0 + rand.Float64()*5000.0,
-					Y: 0,
-					Z: -2500.0 + rand.Float64()*5000.0,
-				}
+				player.Position = m.randomSpawnPosition()
 
 				// Reset movement state
 				player.IsMoving = false
@@ -720,6 +811,7 @@ func (m *Manager) cleanupGameState() {
 
 				// Save back to player map
 				m.state.Players[id] = player
+				m.emitEvent(MatchEvent{Type: MatchEventRespawn, PlayerID: id, Position: &player.Position})
 
 				log.Debug("Tank auto-respawned", 
 					"playerID", id, 
@@ -757,4 +849,6 @@ func (m *Manager) cleanupGameState() {
 
 	// Update shells in game state
 	m.state.Shells = activeShells
+
+	m.pruneImpactsLocked(now)
 }
@@ -0,0 +1,319 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Map validation settings
+const (
+	reachCellSize    = tankCollisionRadius * 2 // Resolution of the spawn reachability check
+	overlapTolerance = 0.5                     // Colliders may overlap by this fraction of the smaller radius
+	minMapFileSize   = 500.0                   // Smallest map file edge length; unlike generated maps there is no layout to crowd
+)
+
+// MapProblemSeverity says whether a problem stops a map from loading
+type MapProblemSeverity string
+
+const (
+	MapError   MapProblemSeverity = "error"   // The map cannot be played
+	MapWarning MapProblemSeverity = "warning" // The map plays, but probably not as intended
+)
+
+// MapProblem is one finding of map validation
+type MapProblem struct {
+	Severity MapProblemSeverity `json:"severity"`
+	Message  string             `json:"message"`
+}
+
+// MapProblems are all findings of map validation
+type MapProblems []MapProblem
+
+// HasErrors reports whether any problem stops the map from loading
+func (p MapProblems) HasErrors() bool {
+	for _, problem := range p {
+		if problem.Severity == MapError {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors joins the errors, ignoring warnings
+func (p MapProblems) Errors() error {
+	var errs []error
+	for _, problem := range p {
+		if problem.Severity == MapError {
+			errs = append(errs, errors.New(problem.Message))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *MapProblems) addError(format string, args ...interface{}) {
+	*p = append(*p, MapProblem{Severity: MapError, Message: fmt.Sprintf(format, args...)})
+}
+
+func (p *MapProblems) addWarning(format string, args ...interface{}) {
+	*p = append(*p, MapProblem{Severity: MapWarning, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks a map file for settings out of range, objects outside the bounds,
+// overlapping colliders and spawn points tanks cannot use
+func (f *MapFile) Validate() MapProblems {
+	var problems MapProblems
+
+	if f.Version < 1 || f.Version > MapFileVersion {
+		problems.addError("unsupported version %d", f.Version)
+	}
+	if f.Bounds.Size != 0 && !inRange(f.Bounds.Size, minMapFileSize, maxMapSize) {
+		problems.addError("bounds size %.0f out of range, must be %.0f to %.0f", f.Bounds.Size, minMapFileSize, maxMapSize)
+	}
+	if f.Terrain.CellSize < 0 {
+		problems.addError("terrain cell size %.1f is negative", f.Terrain.CellSize)
+	}
+	for i, mountain := range f.Terrain.Mountains {
+		if mountain.Width <= 0 || mountain.Depth <= 0 {
+			problems.addError("mountain %d has no footprint", i)
+		}
+	}
+
+	gameMap := f.Build()
+	halfSize := gameMap.HalfSize()
+	outside := func(p Position) bool {
+		return math.Abs(p.X) > halfSize || math.Abs(p.Z) > halfSize
+	}
+
+	// Objects
+	for i, tree := range f.Trees {
+		if outside(tree.Position) {
+			problems.addError("tree %d at (%.0f, %.0f) is outside the map", i, tree.Position.X, tree.Position.Z)
+		}
+		if tree.Radius <= 0 {
+			problems.addError("tree %d has no collider", i)
+		}
+	}
+	for i, rock := range f.Rocks {
+		if outside(rock.Position) {
+			problems.addError("rock %d at (%.0f, %.0f) is outside the map", i, rock.Position.X, rock.Position.Z)
+		}
+		if rock.Radius <= 0 {
+			problems.addError("rock %d has no collider", i)
+		}
+	}
+	for i, formation := range f.Formations {
+		problems = append(problems, validateFormation(i, formation, outside)...)
+	}
+	for i, zone := range f.ControlZones {
+		if outside(zone.Position) {
+			problems.addError("control zone %d at (%.0f, %.0f) is outside the map", i, zone.Position.X, zone.Position.Z)
+		}
+		if zone.Radius <= 0 {
+			problems.addError("control zone %d has no radius", i)
+		}
+	}
+
+	if f.FlagBases != nil {
+		if outside(f.FlagBases.Red) {
+			problems.addError("red flag base at (%.0f, %.0f) is outside the map", f.FlagBases.Red.X, f.FlagBases.Red.Z)
+		}
+		if outside(f.FlagBases.Blue) {
+			problems.addError("blue flag base at (%.0f, %.0f) is outside the map", f.FlagBases.Blue.X, f.FlagBases.Blue.Z)
+		}
+	}
+
+	problems = append(problems, findOverlaps(gameMap)...)
+	problems = append(problems, checkSpawns(gameMap)...)
+	return problems
+}
+
+// validateFormation checks that a formation has the settings its type needs
+func validateFormation(i int, f MapFormation, outside func(Position) bool) MapProblems {
+	var problems MapProblems
+
+	if outside(f.Position) {
+		problems.addError("formation %d at (%.0f, %.0f) is outside the map", i, f.Position.X, f.Position.Z)
+	}
+
+	switch f.Type {
+	case RockClusterFormation:
+	case StoneCircleFormation, GroveFormation:
+		if f.Radius <= 0 || f.Count <= 0 {
+			problems.addError("%s formation %d needs a radius and count", f.Type, i)
+		}
+	case RockSpireFormation, BalancedRockFormation:
+		if f.Height <= 0 {
+			problems.addError("%s formation %d needs a height", f.Type, i)
+		}
+	case RockPeakFormation:
+		if f.Height <= 0 || f.Radius <= 0 {
+			problems.addError("peak formation %d needs a height and radius", i)
+		}
+	case RockArchFormation:
+		if f.Width <= 0 || f.Height <= 0 || f.Depth <= 0 {
+			problems.addError("arch formation %d needs a width, height and depth", i)
+		}
+	case RockWallFormation:
+		if f.Height <= 0 {
+			problems.addError("wall formation %d needs a height", i)
+		}
+		if outside(f.End) {
+			problems.addError("wall formation %d ends outside the map", i)
+		}
+	default:
+		problems.addError("formation %d has unknown type %q", i, f.Type)
+	}
+	return problems
+}
+
+// findOverlaps warns about trees that sit inside other trees or rocks. Rock
+// formations are built from overlapping rocks, so rocks are not checked against each other.
+func findOverlaps(gameMap *GameMap) MapProblems {
+	var problems MapProblems
+
+	grid := gameMap.Grid()
+	obstacles := grid.Obstacles()
+	for i := range obstacles {
+		a := &obstacles[i]
+		grid.Near(a.Position, a.Radius, func(b *GridObstacle) bool {
+			// Report each pair once
+			if b.Index <= a.Index {
+				return true
+			}
+			if a.Type == ObstacleRock && b.Type == ObstacleRock {
+				return true
+			}
+
+			dx, dz := a.Position.X-b.Position.X, a.Position.Z-b.Position.Z
+			overlap := a.Radius + b.Radius - math.Sqrt(dx*dx+dz*dz)
+			if overlap > overlapTolerance*math.Min(a.Radius, b.Radius) {
+				problems.addWarning("%s overlaps %s at (%.0f, %.0f)", a.ID, b.ID, b.Position.X, b.Position.Z)
+			}
+			return true
+		})
+	}
+	return problems
+}
+
+// checkSpawns reports spawn points that are off the map, too steep, blocked by an
+// obstacle or cut off from the first spawn point
+func checkSpawns(gameMap *GameMap) MapProblems {
+	var problems MapProblems
+	if len(gameMap.SpawnPoints) == 0 {
+		return problems
+	}
+
+	halfSize := gameMap.HalfSize()
+	cells := newReachGrid(gameMap)
+	start := -1
+
+	for i, spawn := range gameMap.SpawnPoints {
+		if math.Abs(spawn.X) > halfSize || math.Abs(spawn.Z) > halfSize {
+			problems.addError("spawn point %d at (%.0f, %.0f) is outside the map", i, spawn.X, spawn.Z)
+			continue
+		}
+
+		normal := gameMap.terrain().NormalAt(spawn.X, spawn.Z)
+		if math.Sqrt(normal.X*normal.X+normal.Z*normal.Z) > maxSpawnSlope*normal.Y {
+			problems.addError("spawn point %d at (%.0f, %.0f) is too steep", i, spawn.X, spawn.Z)
+		}
+
+		cell := cells.cell(spawn)
+		if !cells.open[cell] {
+			problems.addError("spawn point %d at (%.0f, %.0f) is inside an obstacle", i, spawn.X, spawn.Z)
+			continue
+		}
+
+		// Every spawn must be reachable from the first usable one
+		if start < 0 {
+			start = cell
+			cells.fill(start)
+			continue
+		}
+		if !cells.reached[cell] {
+			problems.addError("spawn point %d at (%.0f, %.0f) cannot be reached from the other spawns", i, spawn.X, spawn.Z)
+		}
+	}
+	return problems
+}
+
+// reachGrid is a coarse grid of the places a tank fits, for reachability checks
+type reachGrid struct {
+	halfSize float64
+	size     int
+	open     []bool // Cells a tank centre can occupy
+	reached  []bool // Cells connected to the filled cell
+}
+
+// newReachGrid marks every cell whose centre is clear of obstacles that block tanks
+func newReachGrid(gameMap *GameMap) *reachGrid {
+	halfSize := gameMap.HalfSize()
+	size := int(math.Ceil(halfSize * 2 / reachCellSize))
+	r := &reachGrid{
+		halfSize: halfSize,
+		size:     size,
+		open:     make([]bool, size*size),
+		reached:  make([]bool, size*size),
+	}
+	for i := range r.open {
+		r.open[i] = true
+	}
+
+	for _, obstacle := range gameMap.Grid().Obstacles() {
+		if !obstacle.BlocksTanks() {
+			continue
+		}
+		reach := obstacle.Radius + tankCollisionRadius
+		minCol, minRow := r.coords(obstacle.Position.X-reach, obstacle.Position.Z-reach)
+		maxCol, maxRow := r.coords(obstacle.Position.X+reach, obstacle.Position.Z+reach)
+		for row := minRow; row <= maxRow; row++ {
+			for col := minCol; col <= maxCol; col++ {
+				x, z := r.centre(col, row)
+				dx, dz := x-obstacle.Position.X, z-obstacle.Position.Z
+				if dx*dx+dz*dz < reach*reach {
+					r.open[row*size+col] = false
+				}
+			}
+		}
+	}
+	return r
+}
+
+// coords returns the clamped column and row containing a point
+func (r *reachGrid) coords(x, z float64) (int, int) {
+	col := int((x + r.halfSize) / reachCellSize)
+	row := int((z + r.halfSize) / reachCellSize)
+	return max(0, min(r.size-1, col)), max(0, min(r.size-1, row))
+}
+
+func (r *reachGrid) cell(p Position) int {
+	col, row := r.coords(p.X, p.Z)
+	return row*r.size + col
+}
+
+func (r *reachGrid) centre(col, row int) (float64, float64) {
+	return -r.halfSize + (float64(col)+0.5)*reachCellSize, -r.halfSize + (float64(row)+0.5)*reachCellSize
+}
+
+// fill marks every open cell connected to start
+func (r *reachGrid) fill(start int) {
+	queue := []int{start}
+	r.reached[start] = true
+	for len(queue) > 0 {
+		cell := queue[0]
+		queue = queue[1:]
+		col, row := cell%r.size, cell/r.size
+
+		for _, next := range [4][2]int{{col - 1, row}, {col + 1, row}, {col, row - 1}, {col, row + 1}} {
+			if next[0] < 0 || next[1] < 0 || next[0] >= r.size || next[1] >= r.size {
+				continue
+			}
+			i := next[1]*r.size + next[0]
+			if r.open[i] && !r.reached[i] {
+				r.reached[i] = true
+				queue = append(queue, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+)
+
+// MapFileVersion is the version of the map file format written by the exporter
+const MapFileVersion = 1
+
+// MapFormat is the encoding of a map file
+type MapFormat string
+
+const (
+	MapFormatJSON MapFormat = "json"
+	MapFormatYAML MapFormat = "yaml"
+)
+
+// MapFormationType is a procedural group of trees or rocks placed by a map file
+type MapFormationType string
+
+const (
+	RockClusterFormation  MapFormationType = "cluster"     // Five breakable rocks
+	StoneCircleFormation  MapFormationType = "stoneCircle" // Ring of rock clusters
+	RockSpireFormation    MapFormationType = "spire"       // Stack of rocks
+	BalancedRockFormation MapFormationType = "balanced"    // Rocks balanced on a base
+	RockPeakFormation     MapFormationType = "peak"        // Rock mountain peak
+	RockArchFormation     MapFormationType = "arch"        // Arch of rocks
+	RockWallFormation     MapFormationType = "wall"        // Wall of rocks from Position to End
+	GroveFormation        MapFormationType = "grove"       // Ring of alternating pine and round trees
+)
+
+// MapFile is the hand-editable description of a map. Trees and rocks are placed
+// as they are; formations are expanded into trees and rocks when the map is built.
+type MapFile struct {
+	Version      int            `json:"version"`
+	Name         string         `json:"name"`
+	Bounds       MapBounds      `json:"bounds"`
+	Terrain      MapTerrain     `json:"terrain"`
+	Trees        []Tree         `json:"trees,omitempty"`
+	Rocks        []Rock         `json:"rocks,omitempty"`
+	Formations   []MapFormation `json:"formations,omitempty"`
+	SpawnPoints  []Position     `json:"spawnPoints,omitempty"`  // Random spawns anywhere on the map if empty
+	ControlZones []ControlZone  `json:"controlZones,omitempty"` // The stone circle formations, or the centre, if empty
+	FlagBases    *FlagBases     `json:"flagBases,omitempty"`    // Capture-the-flag bases, on the X axis if unset
+}
+
+// MapBounds is the playable area of a map, a square centred on the origin
+type MapBounds struct {
+	Size float64 `json:"size"` // Edge length in world units
+}
+
+// MapTerrain describes the mountains the heightmap is sampled from. No mountains
+// means flat ground.
+type MapTerrain struct {
+	Seed      int64      `json:"seed,omitempty"`
+	CellSize  float64    `json:"cellSize,omitempty"` // Heightmap sample spacing, DefaultTerrainCellSize if zero
+	Mountains []Mountain `json:"mountains,omitempty"`
+}
+
+// MapFormation places a procedural formation. Which fields are used depends on the type.
+type MapFormation struct {
+	Type     MapFormationType `json:"type"`
+	Position Position         `json:"position"`
+	End      Position         `json:"end"`                // Far end of a wall
+	Radius   float64          `json:"radius,omitempty"`   // Stone circles, peaks and groves
+	Count    int              `json:"count,omitempty"`    // Clusters in a stone circle or trees in a grove
+	Width    float64          `json:"width,omitempty"`    // Arches
+	Height   float64          `json:"height,omitempty"`   // Spires, balanced rocks, peaks, arches and walls
+	Depth    float64          `json:"depth,omitempty"`    // Arches
+	Rotation float64          `json:"rotation,omitempty"` // Arches, in radians
+	Seed     int              `json:"seed,omitempty"`
+}
+
+// ControlZone is a King of the Hill zone on the map
+type ControlZone struct {
+	Position Position `json:"position"`
+	Radius   float64  `json:"radius"`
+}
+
+// ParseMapFile decodes a map file. The file is not validated.
+func ParseMapFile(data []byte, format MapFormat) (*MapFile, error) {
+	// YAML is decoded through JSON so both formats share the JSON field names
+	if format == MapFormatYAML {
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse map YAML: %v", err)
+		}
+		converted, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert map YAML: %v", err)
+		}
+		data = converted
+	}
+
+	var file MapFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse map: %v", err)
+	}
+	if file.Version == 0 {
+		return nil, fmt.Errorf("map has no version")
+	}
+	if file.Version > MapFileVersion {
+		return nil, fmt.Errorf("map version %d is newer than supported version %d", file.Version, MapFileVersion)
+	}
+	return &file, nil
+}
+
+// Marshal encodes the map file
+func (f *MapFile) Marshal(format MapFormat) ([]byte, error) {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode map: %v", err)
+	}
+	if format != MapFormatYAML {
+		return data, nil
+	}
+
+	// JSON is valid YAML, so re-encoding its node tree keeps the field names and order
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("failed to convert map to YAML: %v", err)
+	}
+	clearYAMLStyle(&node)
+	return yaml.Marshal(&node)
+}
+
+// clearYAMLStyle switches nodes decoded from JSON to block style
+func clearYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearYAMLStyle(child)
+	}
+}
+
+// MapFormatOf returns the format of a map file from its extension
+func MapFormatOf(path string) (MapFormat, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return MapFormatJSON, true
+	case ".yaml", ".yml":
+		return MapFormatYAML, true
+	}
+	return "", false
+}
+
+// LoadMapFile reads and validates a map file. Files without a name are named after the file.
+func LoadMapFile(path string) (*MapFile, error) {
+	format, ok := MapFormatOf(path)
+	if !ok {
+		return nil, fmt.Errorf("unknown map format: %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read map: %v", err)
+	}
+	file, err := ParseMapFile(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if file.Name == "" {
+		file.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	problems := file.Validate()
+	if problems.HasErrors() {
+		return nil, fmt.Errorf("%s: invalid map: %v", path, problems.Errors())
+	}
+	if len(problems) > 0 {
+		log.Warn("Map has warnings", "map", path, "warnings", len(problems))
+		for _, problem := range problems {
+			log.Debug("Map warning", "map", path, "warning", problem.Message)
+		}
+	}
+	return file, nil
+}
+
+// LoadMapDir loads every map file in a directory, keyed by map name. A missing
+// directory has no maps.
+func LoadMapDir(dir string) (map[string]*MapFile, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return map[string]*MapFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read map directory: %v", err)
+	}
+
+	maps := make(map[string]*MapFile)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if _, ok := MapFormatOf(entry.Name()); !ok {
+			continue
+		}
+
+		file, err := LoadMapFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if file.Name == DefaultMapName {
+			return nil, fmt.Errorf("%s: map name %q is reserved for the generated map", entry.Name(), DefaultMapName)
+		}
+		if _, exists := maps[file.Name]; exists {
+			return nil, fmt.Errorf("%s: duplicate map name %q", entry.Name(), file.Name)
+		}
+		maps[file.Name] = file
+	}
+	return maps, nil
+}
+
+// Build creates the game map described by the file
+func (f *MapFile) Build() *GameMap {
+	cfg := MapConfig{Seed: f.Terrain.Seed, Size: f.Bounds.Size}.withDefaults()
+
+	g := &mapGenerator{
+		cfg:      cfg,
+		halfSize: cfg.Size / 2,
+		trees:    &TreeMap{Trees: append([]Tree{}, f.Trees...)},
+		rocks:    &RockMap{Rocks: append([]Rock{}, f.Rocks...)},
+	}
+	zones := append([]ControlZone{}, f.ControlZones...)
+	for _, formation := range f.Formations {
+		g.createFormation(formation)
+
+		// Without zones of its own the map is played in its stone circles
+		if len(f.ControlZones) == 0 && formation.Type == StoneCircleFormation {
+			zones = append(zones, ControlZone{Position: formation.Position, Radius: formation.Radius})
+		}
+	}
+
+	return &GameMap{
+		Name:        f.Name,
+		Config:      cfg,
+		Trees:       *g.trees,
+		Rocks:       *g.rocks,
+		Terrain:     NewHeightmap(f.Terrain.Seed, f.Terrain.Mountains, f.Terrain.CellSize),
+		SpawnPoints: append([]Position{}, f.SpawnPoints...),
+		Zones:       zones,
+		Bases:       f.FlagBases,
+	}
+}
+
+// createFormation expands a formation into trees and rocks
+func (g *mapGenerator) createFormation(f MapFormation) {
+	x, z := f.Position.X, f.Position.Z
+	switch f.Type {
+	case RockClusterFormation:
+		g.createRockCluster(x, z, f.Seed)
+	case StoneCircleFormation:
+		g.createStoneCircle(x, z, f.Radius, f.Count, f.Seed)
+	case RockSpireFormation:
+		g.createRockSpire(x, z, f.Height, f.Seed)
+	case BalancedRockFormation:
+		g.createBalancedRocks(x, z, f.Height, f.Seed)
+	case RockPeakFormation:
+		g.createRockMountainPeak(x, z, f.Height, f.Radius, f.Seed)
+	case RockArchFormation:
+		g.createRockArch(x, z, f.Width, f.Height, f.Depth, f.Rotation, f.Seed)
+	case RockWallFormation:
+		g.createRockWall(x, z, f.End.X, f.End.Z, f.Height, f.Seed)
+	case GroveFormation:
+		g.createSacredGrove(x, z, f.Radius, f.Count)
+	}
+}
+
+// ExportMap describes a map as a map file. Formations are exported as the trees
+// and rocks they expanded into.
+func ExportMap(gm *GameMap, name string) *MapFile {
+	bases := gm.FlagBases()
+	file := &MapFile{
+		Version:      MapFileVersion,
+		Name:         name,
+		Bounds:       MapBounds{Size: gm.HalfSize() * 2},
+		Trees:        append([]Tree{}, gm.Trees.Trees...),
+		Rocks:        append([]Rock{}, gm.Rocks.Rocks...),
+		SpawnPoints:  append([]Position{}, gm.SpawnPoints...),
+		ControlZones: gm.ControlZones(),
+		FlagBases:    &bases,
+	}
+	if gm.Terrain != nil {
+		file.Terrain = MapTerrain{
+			Seed:      gm.Terrain.Seed,
+			CellSize:  gm.Terrain.CellSize,
+			Mountains: append([]Mountain{}, gm.Terrain.Mountains...),
+		}
+	}
+	return file
+}
+
+// ControlZones returns the King of the Hill zones of the map. Generated maps default
+// to one in each stone circle; maps without stone circles have one in the centre.
+func (gm *GameMap) ControlZones() []ControlZone {
+	if gm != nil && len(gm.Zones) > 0 {
+		return append([]ControlZone{}, gm.Zones...)
+	}
+	// Map files place their own stone circles, which Build already made zones of
+	if gm != nil && (gm.Name != "" || gm.Config.Landmarks.NoStoneCircles) {
+		return []ControlZone{{Radius: StoneCircleRadius}}
+	}
+	return stoneCircleZones(gm.HalfSize())
+}
+
+// stoneCircleZones returns one control zone in each stone circle
+func stoneCircleZones(halfSize float64) []ControlZone {
+	centers := stoneCircleCenters(halfSize)
+	zones := make([]ControlZone, len(centers))
+	for i, center := range centers {
+		zones[i] = ControlZone{Position: center, Radius: StoneCircleRadius}
+	}
+	return zones
+}
+
+// MapNames returns the names of a set of maps in order
+func MapNames(maps map[string]*MapFile) []string {
+	names := make([]string, 0, len(maps))
+	for name := range maps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,163 @@
+package game
+
+import (
+	"fmt"
+	"math"
+)
+
+// Map generation defaults
+const (
+	defaultMapSize     = mapHalfSize * 2 // Edge length of the square map
+	defaultDensity     = 1.0             // Standard number of trees or rocks
+	defaultBiomeScale  = 1.0             // Standard size of forests and rock regions
+	baseNoiseFrequency = 0.005           // Noise frequency of the standard biome scale
+	roadHalfLength     = 1000.0          // Roads run this far from the centre, within the map
+)
+
+// Limits of the map generation settings, which bound generation time and memory
+const (
+	minMapSize    = 2500.0  // Smallest map edge length, below which the scaled layout crowds the centre landmarks
+	maxMapSize    = 10000.0 // Largest map edge length
+	maxDensity    = 4.0     // Most trees or rocks, relative to standard
+	minBiomeScale = 0.25    // Smallest forests and rock regions, relative to standard
+	maxBiomeScale = 4.0     // Largest forests and rock regions, relative to standard
+)
+
+// MapLandmarks switches off hand-placed features of a map. The zero value keeps them all.
+type MapLandmarks struct {
+	NoStartingArea  bool `json:"noStartingArea"`  // Tree rings and rocks around the centre
+	NoGroves        bool `json:"noGroves"`        // Sacred groves
+	NoRoads         bool `json:"noRoads"`         // Tree-lined roads through the centre
+	NoTreeLandmarks bool `json:"noTreeLandmarks"` // Giant pine, ring of round trees and spiral
+	NoStoneCircles  bool `json:"noStoneCircles"`  // Stone circles, also used as King of the Hill zones
+	NoMountains     bool `json:"noMountains"`     // Terrain heightmap
+}
+
+// MapConfig holds the parameters a map is generated from. The same config always
+// generates the same map. Zero values use the defaults.
+type MapConfig struct {
+	Seed        int64        `json:"seed"`        // Varies every noise pattern of the map
+	Size        float64      `json:"size"`        // Edge length of the square map in world units, the layout scales with it
+	TreeDensity float64      `json:"treeDensity"` // Forest density, 1 is standard
+	RockDensity float64      `json:"rockDensity"` // Rock formation density, 1 is standard
+	BiomeScale  float64      `json:"biomeScale"`  // Size of forests and rock regions, 1 is standard
+	Landmarks   MapLandmarks `json:"landmarks"`
+}
+
+// DefaultMapConfig returns the config of the standard map
+func DefaultMapConfig() MapConfig {
+	return MapConfig{}.withDefaults()
+}
+
+// Validate checks that the settings are within the generation limits. Zero values
+// are valid and use the defaults.
+func (c MapConfig) Validate() error {
+	if c.Size != 0 && !inRange(c.Size, minMapSize, maxMapSize) {
+		return fmt.Errorf("map size %.0f out of range, must be %.0f to %.0f", c.Size, minMapSize, maxMapSize)
+	}
+	if c.TreeDensity != 0 && !inRange(c.TreeDensity, 0, maxDensity) {
+		return fmt.Errorf("tree density %.2f out of range, must be above 0 and at most %.0f", c.TreeDensity, maxDensity)
+	}
+	if c.RockDensity != 0 && !inRange(c.RockDensity, 0, maxDensity) {
+		return fmt.Errorf("rock density %.2f out of range, must be above 0 and at most %.0f", c.RockDensity, maxDensity)
+	}
+	if c.BiomeScale != 0 && !inRange(c.BiomeScale, minBiomeScale, maxBiomeScale) {
+		return fmt.Errorf("biome scale %.2f out of range, must be %.2f to %.2f", c.BiomeScale, minBiomeScale, maxBiomeScale)
+	}
+	return nil
+}
+
+// inRange reports whether min <= v <= max, which is false for NaN
+func inRange(v, min, max float64) bool {
+	return v >= min && v <= max
+}
+
+// withDefaults fills in zero values
+func (c MapConfig) withDefaults() MapConfig {
+	if c.Size <= 0 {
+		c.Size = defaultMapSize
+	}
+	if c.TreeDensity <= 0 {
+		c.TreeDensity = defaultDensity
+	}
+	if c.RockDensity <= 0 {
+		c.RockDensity = defaultDensity
+	}
+	if c.BiomeScale <= 0 {
+		c.BiomeScale = defaultBiomeScale
+	}
+	return c
+}
+
+// mapGenerator builds the trees and rocks of one map
+type mapGenerator struct {
+	cfg      MapConfig
+	halfSize float64
+	trees    *TreeMap
+	rocks    *RockMap
+}
+
+// GenerateMap builds a map from a config
+func GenerateMap(cfg MapConfig) *GameMap {
+	cfg = cfg.withDefaults()
+
+	g := &mapGenerator{
+		cfg:      cfg,
+		halfSize: cfg.Size / 2,
+		trees:    &TreeMap{Trees: []Tree{}},
+		rocks:    &RockMap{Rocks: []Rock{}},
+	}
+	g.generateTrees()
+	g.generateRocks()
+
+	var mountains []Mountain
+	if !cfg.Landmarks.NoMountains {
+		mountains = GenerateMountains(cfg.Seed, g.halfSize)
+	}
+
+	return &GameMap{
+		Config:  cfg,
+		Trees:   *g.trees,
+		Rocks:   *g.rocks,
+		Terrain: NewHeightmap(cfg.Seed, mountains, DefaultTerrainCellSize),
+	}
+}
+
+// at scales a coordinate of the standard map's layout to this map's size. The
+// landmarks around the centre keep their size so the starting area plays the same.
+func (g *mapGenerator) at(v float64) float64 {
+	return scaleToMap(v, g.halfSize)
+}
+
+// scaleToMap scales a length of the standard map's layout to a map of the given half size
+func scaleToMap(v, halfSize float64) float64 {
+	return v * (halfSize / mapHalfSize)
+}
+
+// inBounds reports whether a point lies on the map
+func (g *mapGenerator) inBounds(x, z float64) bool {
+	return math.Abs(x) <= g.halfSize && math.Abs(z) <= g.halfSize
+}
+
+// seeded offsets a fixed generation seed by the map seed
+func (g *mapGenerator) seeded(seed int) int {
+	return seed + int(g.cfg.Seed)
+}
+
+// treeStep and rockStep return the sampling step of a noise-placed region, so
+// that the number of samples scales with the density
+func (g *mapGenerator) treeStep(step float64) float64 {
+	return step / math.Sqrt(g.cfg.TreeDensity)
+}
+
+func (g *mapGenerator) rockStep(step float64) float64 {
+	return step / math.Sqrt(g.cfg.RockDensity)
+}
+
+// HalfSize returns half the edge length of the map
+func (gm *GameMap) HalfSize() float64 {
+	if gm == nil || gm.Config.Size <= 0 {
+		return mapHalfSize
+	}
+	return gm.Config.Size / 2
+}
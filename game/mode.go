@@ -0,0 +1,368 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/charmbracelet/log"
+)
+
+// GameModeType identifies a set of game rules
+type GameModeType string
+
+const (
+	ModeFreeForAll     GameModeType = "ffa"
+	ModeTeamDeathmatch GameModeType = "tdm"
+	ModeCaptureTheFlag GameModeType = "ctf"
+	ModeKingOfTheHill  GameModeType = "koth"
+)
+
+// Match defaults
+const (
+	defaultKillLimit       = 25 // Free-for-all kills that end the match
+	defaultTeamScoreLimit  = 50
+	defaultMatchTimeLimitS = 600 // 10 minute matches
+	TeamRed                = "red"
+	TeamBlue               = "blue"
+	teamRedColor           = "#f44336"
+	teamBlueColor          = "#2196f3"
+)
+
+// ModeConfig holds the rules a game mode is created with.
+// Zero limits use the mode defaults.
+type ModeConfig struct {
+	Type         GameModeType `json:"type"`
+	ScoreLimit   int          `json:"scoreLimit"`   // Team score, or player score in solo modes, that ends the match
+	TimeLimit    int          `json:"timeLimit"`    // Match length in seconds
+	FriendlyFire bool         `json:"friendlyFire"` // Whether teammates can damage each other
+	Solo         bool         `json:"solo"`         // Players score individually instead of in teams (King of the Hill)
+}
+
+// TeamState is the broadcast state of one team
+type TeamState struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+	Score int    `json:"score"`
+}
+
+// MatchState is the broadcast state of the current match
+type MatchState struct {
+	Mode        GameModeType `json:"mode"`
+	ScoreLimit  int          `json:"scoreLimit,omitempty"`
+	EndsAt      int64        `json:"endsAt,omitempty"`      // Server time the match ends, 0 for no time limit
+	Over        bool         `json:"over,omitempty"`        // True once the score or time limit is reached
+	EndedAt     int64        `json:"endedAt,omitempty"`     // Server time the match ended
+	Winner      string       `json:"winner,omitempty"`      // Winning team, or player in solo modes, empty for a draw
+	Phase       MatchPhase   `json:"phase"`                 // Lifecycle phase the room is in
+	PhaseEndsAt int64        `json:"phaseEndsAt,omitempty"` // Server time the current phase ends, 0 if it has no timer
+	Countdown   int          `json:"countdown,omitempty"`   // Whole seconds until the current phase ends
+	Number      int          `json:"number,omitempty"`      // Matches started in this room, counting from 1
+	StartedAt   int64        `json:"startedAt,omitempty"`   // Server time the match went live
+	Map         string       `json:"map,omitempty"`         // Map the match is played on
+}
+
+// GameMode is the rule layer on top of the core simulation.
+// All methods are called with the manager's mutex held.
+type GameMode interface {
+	// Type returns the mode identifier
+	Type() GameModeType
+	// Start resets teams, scores and limits when a match goes live
+	Start(state *GameState, now int64)
+	// AssignPlayer picks a team for a player joining the game
+	AssignPlayer(state *GameState, player *PlayerState)
+	// CanDamage reports whether source is allowed to damage target
+	CanDamage(state *GameState, source, target PlayerState) bool
+	// OnKill is called when source destroys target
+	OnKill(state *GameState, source, target PlayerState)
+	// OnDeath is called when a tank is destroyed, whoever destroyed it
+	OnDeath(state *GameState, victim PlayerState)
+	// Update runs once per tick while the match is live to check limits
+	Update(state *GameState, now int64)
+}
+
+// NewGameMode creates a game mode from its config
+func NewGameMode(cfg ModeConfig) (GameMode, error) {
+	switch cfg.Type {
+	case "", ModeFreeForAll:
+		if cfg.ScoreLimit <= 0 {
+			cfg.ScoreLimit = defaultKillLimit
+		}
+		if cfg.TimeLimit <= 0 {
+			cfg.TimeLimit = defaultMatchTimeLimitS
+		}
+		return &freeForAllMode{config: cfg}, nil
+	case ModeTeamDeathmatch:
+		if cfg.ScoreLimit <= 0 {
+			cfg.ScoreLimit = defaultTeamScoreLimit
+		}
+		if cfg.TimeLimit <= 0 {
+			cfg.TimeLimit = defaultMatchTimeLimitS
+		}
+		return &teamDeathmatchMode{config: cfg}, nil
+	case ModeCaptureTheFlag:
+		if cfg.ScoreLimit <= 0 {
+			cfg.ScoreLimit = defaultCaptureLimit
+		}
+		if cfg.TimeLimit <= 0 {
+			cfg.TimeLimit = defaultMatchTimeLimitS
+		}
+		return &captureTheFlagMode{teamDeathmatchMode: teamDeathmatchMode{config: cfg}}, nil
+	case ModeKingOfTheHill:
+		if cfg.ScoreLimit <= 0 {
+			cfg.ScoreLimit = defaultZoneScoreLimit
+		}
+		if cfg.TimeLimit <= 0 {
+			cfg.TimeLimit = defaultMatchTimeLimitS
+		}
+		return &kingOfTheHillMode{teamDeathmatchMode: teamDeathmatchMode{config: cfg}}, nil
+	default:
+		return nil, fmt.Errorf("unknown game mode %q", cfg.Type)
+	}
+}
+
+// SetMode switches the game rules and sends the room back to waiting for a new match.
+// Existing players are reassigned to teams.
+func (m *Manager) SetMode(cfg ModeConfig) error {
+	mode, err := NewGameMode(cfg)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.applyMapToMode(mode)
+
+	now := m.getTime()
+	m.mode = mode
+	mode.Start(&m.state, now)
+	m.enterPhase(MatchPhaseWaiting, now)
+
+	// Reassign players in a stable order so teams stay balanced
+	ids := make([]string, 0, len(m.state.Players))
+	for id := range m.state.Players {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		player := m.state.Players[id]
+		player.Team = ""
+		m.state.Players[id] = player
+	}
+	for _, id := range ids {
+		player := m.state.Players[id]
+		mode.AssignPlayer(&m.state, &player)
+		player.Color = m.getPlayerColor(player)
+		m.state.Players[id] = player
+	}
+
+	log.Info("Game mode set", "mode", mode.Type(), "players", len(ids))
+	return nil
+}
+
+// applyMapToMode hands the parts of the room's map a mode plays on to it. Caller must hold the mutex.
+func (m *Manager) applyMapToMode(mode GameMode) {
+	// King of the Hill plays on the zones of the room's map
+	if koth, ok := mode.(*kingOfTheHillMode); ok {
+		koth.zones = m.gameMap.ControlZones()
+	}
+	// Capture the Flag plays between the bases of the room's map
+	if ctf, ok := mode.(*captureTheFlagMode); ok {
+		bases := m.gameMap.FlagBases()
+		ctf.bases = &bases
+	}
+}
+
+// startMatch resets the mode-owned match fields, keeping the lifecycle fields
+func startMatch(state *GameState, mode GameModeType, cfg ModeConfig, now int64) {
+	state.Match.Mode = mode
+	state.Match.ScoreLimit = cfg.ScoreLimit
+	state.Match.EndsAt = now + int64(cfg.TimeLimit)*1000
+	state.Match.Over = false
+	state.Match.EndedAt = 0
+	state.Match.Winner = ""
+}
+
+// getPlayerColor returns the team color for team modes, or a color derived from the player ID
+func (m *Manager) getPlayerColor(player PlayerState) string {
+	if team, exists := m.state.Teams[player.Team]; exists {
+		return team.Color
+	}
+
+	// Simple hash of the ID to determine color index
+	var sum int
+	for _, char := range player.ID {
+		sum += int(char)
+	}
+	index := sum % len(playerColors)
+	return playerColors[index]
+}
+
+// freeForAllMode is the classic everyone-against-everyone mode, won on kills
+type freeForAllMode struct {
+	config ModeConfig
+}
+
+func (f *freeForAllMode) Type() GameModeType {
+	return ModeFreeForAll
+}
+
+func (f *freeForAllMode) Start(state *GameState, now int64) {
+	state.Teams = nil
+	startMatch(state, ModeFreeForAll, f.config, now)
+}
+
+func (f *freeForAllMode) AssignPlayer(state *GameState, player *PlayerState) {
+	player.Team = ""
+}
+
+func (f *freeForAllMode) CanDamage(state *GameState, source, target PlayerState) bool {
+	// No damage on the post-match scoreboard
+	return !state.Match.Over
+}
+
+// OnKill scores a point for every kill that isn't a suicide
+func (f *freeForAllMode) OnKill(state *GameState, source, target PlayerState) {
+	if source.ID == target.ID {
+		return
+	}
+	source.Score++
+	state.Players[source.ID] = source
+}
+
+func (f *freeForAllMode) OnDeath(state *GameState, victim PlayerState) {}
+
+func (f *freeForAllMode) Update(state *GameState, now int64) {
+	if state.Match.Over {
+		return
+	}
+	endSoloMatchIfLimitReached(state, now, f.config.ScoreLimit)
+}
+
+// teamDeathmatchMode splits players into two teams that score a point per enemy kill
+type teamDeathmatchMode struct {
+	config ModeConfig
+}
+
+func (t *teamDeathmatchMode) Type() GameModeType {
+	return ModeTeamDeathmatch
+}
+
+func (t *teamDeathmatchMode) Start(state *GameState, now int64) {
+	state.Teams = map[string]TeamState{
+		TeamRed:  {ID: TeamRed, Name: "Red", Color: teamRedColor},
+		TeamBlue: {ID: TeamBlue, Name: "Blue", Color: teamBlueColor},
+	}
+	startMatch(state, ModeTeamDeathmatch, t.config, now)
+}
+
+// AssignPlayer puts the player on the team with fewer players, or the lower score on a tie
+func (t *teamDeathmatchMode) AssignPlayer(state *GameState, player *PlayerState) {
+	counts := make(map[string]int, len(state.Teams))
+	for id, other := range state.Players {
+		if id != player.ID && other.Team != "" {
+			counts[other.Team]++
+		}
+	}
+
+	red, blue := state.Teams[TeamRed], state.Teams[TeamBlue]
+	switch {
+	case counts[TeamRed] < counts[TeamBlue]:
+		player.Team = TeamRed
+	case counts[TeamBlue] < counts[TeamRed]:
+		player.Team = TeamBlue
+	case blue.Score < red.Score:
+		player.Team = TeamBlue
+	default:
+		player.Team = TeamRed
+	}
+
+	log.Debug("Assigned player to team", "playerID", player.ID, "team", player.Team, "red", counts[TeamRed], "blue", counts[TeamBlue])
+}
+
+func (t *teamDeathmatchMode) CanDamage(state *GameState, source, target PlayerState) bool {
+	// No damage on the post-match scoreboard
+	if state.Match.Over {
+		return false
+	}
+
+	if source.ID != target.ID && source.Team == target.Team && !t.config.FriendlyFire {
+		return false
+	}
+	return true
+}
+
+func (t *teamDeathmatchMode) OnKill(state *GameState, source, target PlayerState) {
+	team, exists := state.Teams[source.Team]
+	if !exists || source.ID == target.ID {
+		return
+	}
+
+	// Team kills cost a point instead of scoring one
+	if source.Team == target.Team {
+		team.Score--
+	} else {
+		team.Score++
+	}
+	state.Teams[source.Team] = team
+}
+
+func (t *teamDeathmatchMode) OnDeath(state *GameState, victim PlayerState) {}
+
+func (t *teamDeathmatchMode) Update(state *GameState, now int64) {
+	if state.Match.Over {
+		return
+	}
+
+	endMatchIfLimitReached(state, now, t.config.ScoreLimit)
+}
+
+// endMatchIfLimitReached ends a two-team match when either team reaches the score limit or time runs out
+func endMatchIfLimitReached(state *GameState, now int64, scoreLimit int) {
+	red, blue := state.Teams[TeamRed], state.Teams[TeamBlue]
+	scoreReached := red.Score >= scoreLimit || blue.Score >= scoreLimit
+	timeUp := state.Match.EndsAt > 0 && now >= state.Match.EndsAt
+	if !scoreReached && !timeUp {
+		return
+	}
+
+	state.Match.Over = true
+	state.Match.EndedAt = now
+	switch {
+	case red.Score > blue.Score:
+		state.Match.Winner = TeamRed
+	case blue.Score > red.Score:
+		state.Match.Winner = TeamBlue
+	}
+
+	log.Info("Team match ended", "mode", state.Match.Mode, "winner", state.Match.Winner, "red", red.Score, "blue", blue.Score, "timeUp", timeUp)
+}
+
+// endSoloMatchIfLimitReached ends a solo match when a player reaches the score limit or time runs out
+func endSoloMatchIfLimitReached(state *GameState, now int64, scoreLimit int) {
+	var leader string
+	best, tied := 0, false
+	for id, player := range state.Players {
+		switch {
+		case player.Score > best:
+			leader, best, tied = id, player.Score, false
+		case player.Score == best && best > 0:
+			tied = true
+		}
+	}
+
+	scoreReached := best >= scoreLimit
+	timeUp := state.Match.EndsAt > 0 && now >= state.Match.EndsAt
+	if !scoreReached && !timeUp {
+		return
+	}
+
+	state.Match.Over = true
+	state.Match.EndedAt = now
+	if !tied {
+		state.Match.Winner = leader
+	}
+
+	log.Info("Solo match ended", "mode", state.Match.Mode, "winner", state.Match.Winner, "score", best, "timeUp", timeUp)
+}
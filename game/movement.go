@@ -0,0 +1,205 @@
+package game
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/charmbracelet/log"
+)
+
+// Movement constants for the server-side tank simulation.
+// Speeds are expressed in client units per frame at 60fps to match tank.ts,
+// and scaled by the elapsed time of each simulation tick.
+const (
+	movementFrameRate   = 60.0         // Client frame rate the movement constants are tuned for
+	tankMaxSpeed        = 3.0          // Max speed per frame (matches tank.ts maxSpeed)
+	tankMaxAcceleration = 0.15         // Max acceleration per frame (matches tank.ts maxAcceleration)
+	tankFriction        = 0.92         // Velocity multiplier per frame when no throttle is applied
+	tankRotationSpeed   = 0.04         // Hull rotation per frame in radians
+	tankCollisionRadius = 2.5          // Tank collision radius (matches the physics tank colliders)
+	tankHeight          = 2.0          // Height below which obstacles block tanks
+	minBarrelElevation  = -math.Pi / 4 // Lowest barrel elevation (matches tank.ts)
+	maxBarrelElevation  = 0.0          // Highest barrel elevation (horizontal)
+	turretHeight        = 1.0          // Turret pivot above the tank's position (matches tank.ts)
+	barrelLength        = 1.5          // Turret pivot to muzzle (matches tank.ts BARREL_END_OFFSET)
+	shellSpeed          = 10.0         // Muzzle speed of player shells (matches tank.ts SHELL_SPEED)
+	mapHalfSize         = 2500.0       // Half of the default 5000x5000 map
+	inputDeadzone       = 0.05         // Inputs below this are treated as zero
+	inputStaleAfterMs   = 1000         // Inputs older than this are treated as released
+)
+
+// ApplyInput records the latest control input for a client-controlled tank.
+// New players are spawned on their first input; the tank then moves only through simulateMovement.
+func (m *Manager) ApplyInput(input PlayerInput, playerID string, playerName string) error {
+	if playerID == "" {
+		return fmt.Errorf("playerID cannot be empty")
+	}
+
+	// Clamp inputs to valid ranges so a modified client cannot exceed them
+	input.Throttle = clampFloat(input.Throttle, -1, 1)
+	input.Steer = clampFloat(input.Steer, -1, 1)
+	input.BarrelElevation = clampFloat(input.BarrelElevation, minBarrelElevation, maxBarrelElevation)
+	if math.IsNaN(input.TurretRotation) || math.IsInf(input.TurretRotation, 0) {
+		input.TurretRotation = 0
+	}
+
+	now := m.getTime()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	player, exists := m.state.Players[playerID]
+	if !exists {
+		// Spectators watch without a tank
+		if m.spectators[playerID] > 0 {
+			return fmt.Errorf("spectator %s cannot control a tank", playerID)
+		}
+
+		player = PlayerState{
+			ID:       playerID,
+			Name:     playerName,
+			Position: m.randomSpawnPosition(),
+			Health:   100,
+			Status:   StatusReady, // New player starts in READY state
+		}
+		m.mode.AssignPlayer(&m.state, &player)
+		player.Color = m.getPlayerColor(player)
+
+		log.Info("New player joined", "playerID", playerID, "posX", player.Position.X, "posZ", player.Position.Z)
+		m.emitEvent(MatchEvent{Type: MatchEventJoin, PlayerID: playerID, PlayerName: playerName})
+	}
+
+	// Ignore out-of-order inputs
+	if last, ok := m.inputs[playerID]; ok && input.Sequence != 0 && input.Sequence < last.Sequence {
+		return nil
+	}
+
+	// Store the input with server receive time so stale inputs can be released
+	input.Timestamp = now
+	m.inputs[playerID] = input
+
+	// Aim is applied immediately; hull movement is integrated on the next tick
+	player.Name = playerName
+	player.TurretRotation = input.TurretRotation
+	player.BarrelElevation = input.BarrelElevation
+	player.LastInputSeq = input.Sequence
+	player.Timestamp = now
+	m.state.Players[playerID] = player
+
+	return nil
+}
+
+// simulateMovement advances every input-driven tank by dt seconds.
+// Returns true if any tank state changed. Caller must hold the mutex.
+func (m *Manager) simulateMovement(dt float64) bool {
+	if dt <= 0 {
+		return false
+	}
+
+	// Clamp dt so a stalled tick cannot teleport tanks
+	dt = math.Min(dt, 0.25)
+	frames := dt * movementFrameRate
+	now := m.getTime()
+	changed := false
+
+	for id, input := range m.inputs {
+		player, exists := m.state.Players[id]
+		if !exists {
+			delete(m.inputs, id)
+			continue
+		}
+
+		// Destroyed tanks do not move
+		if player.IsDestroyed {
+			if player.Velocity != 0 || player.IsMoving {
+				player.Velocity = 0
+				player.IsMoving = false
+				m.state.Players[id] = player
+				changed = true
+			}
+			continue
+		}
+
+		// Release controls if the client stopped sending input
+		throttle, steer := input.Throttle, input.Steer
+		if now-input.Timestamp > inputStaleAfterMs {
+			throttle, steer = 0, 0
+		}
+
+		before := player
+		integrateTankMovement(&player, throttle, steer, frames, m.gameMap.HalfSize())
+		m.resolveObstacleCollisions(&player)
+		player.Position.Y = m.gameMap.GroundHeight(player.Position.X, player.Position.Z)
+
+		if player.Position != before.Position || player.TankRotation != before.TankRotation ||
+			player.Velocity != before.Velocity || player.IsMoving != before.IsMoving {
+			player.Timestamp = now
+			m.state.Players[id] = player
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// integrateTankMovement applies throttle and steering to a tank for the given number of
+// frames, keeping it within halfSize of the map centre
+func integrateTankMovement(player *PlayerState, throttle, steer, frames, halfSize float64) {
+	// Accelerate toward the throttle target, or apply friction when idle
+	if math.Abs(throttle) >= inputDeadzone {
+		player.Velocity += tankMaxAcceleration * throttle * frames
+	} else {
+		player.Velocity *= math.Pow(tankFriction, frames)
+	}
+
+	// Clamp to max speed
+	player.Velocity = clampFloat(player.Velocity, -tankMaxSpeed, tankMaxSpeed)
+	if math.Abs(player.Velocity) < 0.001 {
+		player.Velocity = 0
+	}
+
+	// Turn slower at high speed, like the client
+	if math.Abs(steer) >= inputDeadzone {
+		rotationModifier := math.Max(0.1, 1.0-math.Abs(player.Velocity)/tankMaxSpeed*0.5)
+		player.TankRotation = normalizeAngle(player.TankRotation + tankRotationSpeed*steer*rotationModifier*frames)
+	}
+
+	// Move along the hull direction using the client's axis convention
+	player.Position.X += math.Sin(player.TankRotation) * player.Velocity * frames
+	player.Position.Z += math.Cos(player.TankRotation) * player.Velocity * frames
+
+	// Keep tanks inside the map
+	player.Position.X = clampFloat(player.Position.X, -halfSize, halfSize)
+	player.Position.Z = clampFloat(player.Position.Z, -halfSize, halfSize)
+
+	player.IsMoving = player.Velocity != 0 || math.Abs(steer) >= inputDeadzone
+	player.TrackRotation = player.Velocity * 0.5 // Same track animation factor as tank.ts
+}
+
+// resolveObstacleCollisions pushes a tank out of any overlapping tree or rock and slows
+// it. Caller must hold the mutex.
+func (m *Manager) resolveObstacleCollisions(player *PlayerState) {
+	if m.gameMap == nil {
+		return
+	}
+
+	m.gameMap.Grid().Near(player.Position, tankCollisionRadius, func(obstacle *GridObstacle) bool {
+		if !obstacle.BlocksTanks() {
+			return true
+		}
+		// Broken rocks no longer block
+		if obstacle.HitPoints > 0 && m.obstacleDamage[obstacle.ID] >= obstacle.HitPoints {
+			return true
+		}
+		ResolveObstacleContact(player, obstacle.Position, obstacle.Radius+tankCollisionRadius)
+		return true
+	})
+}
+
+// clampFloat limits v to the range [min, max], treating NaN as zero
+func clampFloat(v, min, max float64) float64 {
+	if math.IsNaN(v) {
+		v = 0
+	}
+	return math.Max(min, math.Min(max, v))
+}
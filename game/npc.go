@@ -1,8 +1,6 @@
 package game
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
@@ -12,7 +10,6 @@ import (
 
 	"github.com/charmbracelet/log"
 	"tank-game/game/shared"
-	"github.com/nats-io/nats.go/jetstream"
 )
 
 // NPCController manages NPC tanks
@@ -22,9 +19,9 @@ type NPCController struct {
 	mutex          sync.RWMutex
 	gameMap        *GameMap
 	isRunning      bool
-	quit           chan struct{}                  // Channel to signal shutdown
 	physicsManager shared.PhysicsManagerInterface // Reference to physics manager for targeting
-	watcher        jetstream.KeyWatcher           // KV watcher for game state changes
+	tickRegistered bool                           // Whether the NPC phase has been added to the manager's tick
+	sinceUpdate    float64                        // Seconds accumulated since the last NPC AI update
 }
 
 // Movement patterns
@@ -79,7 +76,6 @@ func NewNPCController(manager *Manager, gameMap *GameMap, physicsManager shared.
 		mutex:          sync.RWMutex{},
 		gameMap:        gameMap,
 		isRunning:      false,
-		quit:           make(chan struct{}),
 		physicsManager: physicsManager,
 	}
 }
@@ -92,24 +88,16 @@ func (c *NPCController) Start() {
 		return
 	}
 	c.isRunning = true
+	register := !c.tickRegistered
+	c.tickRegistered = true
 	c.mutex.Unlock()
 
-	// Set up KV watcher for game state changes
-	var err error
-	ctx := context.Background()
-	c.watcher, err = c.manager.WatchState(ctx)
-	if err != nil {
-		log.Error("Failed to create KV watcher for NPCs", "error", err)
-		c.mutex.Lock()
-		c.isRunning = false
-		c.mutex.Unlock()
-		return
+	// Run NPC AI as a phase of the manager's tick, after player movement and before physics
+	if register {
+		c.manager.RegisterTickPhase(PhaseNPC, c.runSimulation)
 	}
 
-	// Start the main NPC simulation loop
-	go c.runSimulation()
-
-	log.Info("NPC Controller started with KV watcher")
+	log.Info("NPC Controller started", "tickRate", c.manager.TickRate())
 }
 
 // Stop halts the NPC simulation
@@ -121,16 +109,37 @@ func (c *NPCController) Stop() {
 		return
 	}
 
-	// Close watcher if it exists
-	if c.watcher != nil {
-		c.watcher.Stop()
-	}
-
-	close(c.quit)
 	c.isRunning = false
 	log.Info("NPC Controller stopped")
 }
 
+// SetMap moves the NPCs onto a new map. The manager has already respawned them,
+// so their patrol routes and targets are rebuilt around the new positions.
+func (c *NPCController) SetMap(gameMap *GameMap) {
+	gameState := c.manager.GetState()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.gameMap = gameMap
+	for _, npc := range c.npcs {
+		if serverState, exists := gameState.Players[npc.ID]; exists {
+			npc.State.Position = serverState.Position
+			npc.State.Velocity = serverState.Velocity
+			npc.State.IsMoving = serverState.IsMoving
+		}
+		if npc.MovementPattern == PatrolMovement {
+			npc.PatrolPoints = patrolRoute(npc.State.Position.X, npc.State.Position.Z)
+		}
+		npc.CurrentPoint = 0
+		npc.TargetID = ""
+		npc.AimingAt = nil
+		npc.CanSeeTarget = false
+		npc.LastAttackerID = ""
+		npc.LastAttackTime = time.Time{}
+	}
+}
+
 // NPCPersonality defines a set of personality parameters for an NPC tank
 type NPCPersonality struct {
 	MoveSpeed      float64       // How fast the NPC moves (0.0-1.0)
@@ -212,8 +221,8 @@ var npcAdjectives = []string{"Sneaky", "Rusty", "Furious", "Clever", "Angry", "B
 
 var npcVerbs = []string{"Tiger", "Dragon", "Hawk", "Fox", "Panther", "Wolf", "Eagle", "Lion", "Viper", "Shark", "Hunter", "Cobra", "Rhino", "Bear", "Falcon", "Scorpion", "Mantis", "Jaguar", "Sentinel", "Stalker", "Crusher", "Phantom", "Assassin", "Guardian"}
 
-// generateNPCName generates a name in the format "Adjective Verb"
-func generateNPCName() string {
+// GenerateNPCName generates a name in the format "Adjective Verb"
+func GenerateNPCName() string {
 	adjective := npcAdjectives[rand.Intn(len(npcAdjectives))]
 	verb := npcVerbs[rand.Intn(len(npcVerbs))]
 	return adjective + " " + verb
@@ -222,10 +231,46 @@ func generateNPCName() string {
 // SpawnNPC creates a new NPC tank with randomized characteristics
 func (c *NPCController) SpawnNPC(name string, movementPattern MovementPattern) *NPCTank {
 	// Generate a proper NPC name in the "Adjective Verb" format
-	npcName := generateNPCName()
+	npcName := GenerateNPCName()
 	return c.SpawnCustomNPC(npcName, movementPattern, 0.5) // Default medium difficulty
 }
 
+// patrolRoute returns the patrol points of a tank spawned at the given position
+func patrolRoute(offsetX, offsetZ float64) []Position {
+	// Calculate distance from center
+	distFromCenter := math.Sqrt(offsetX*offsetX + offsetZ*offsetZ)
+
+	// For spawns very far from center, make one patrol point near center
+	if distFromCenter > 1000 {
+		// Calculate angle toward center
+		centerAngle := math.Atan2(-offsetZ, -offsetX)
+
+		// Create patrol points with one near center and others around spawn
+		size := 100.0 + rand.Float64()*200.0
+
+		// Calculate a point that's closer to the center
+		moveTowardCenterDist := distFromCenter * 0.6 // Move 60% toward center
+		centerX := offsetX + math.Cos(centerAngle)*moveTowardCenterDist
+		centerZ := offsetZ + math.Sin(centerAngle)*moveTowardCenterDist
+
+		return []Position{
+			{X: offsetX + size, Y: 0, Z: offsetZ + size},
+			{X: centerX, Y: 0, Z: centerZ}, // This point is closer to center
+			{X: offsetX - size, Y: 0, Z: offsetZ - size},
+			{X: offsetX - size, Y: 0, Z: offsetZ + size},
+		}
+	}
+
+	// Regular patrol route for tanks already near center
+	size := 100.0 + rand.Float64()*200.0
+	return []Position{
+		{X: offsetX + size, Y: 0, Z: offsetZ + size},
+		{X: offsetX + size, Y: 0, Z: offsetZ - size},
+		{X: offsetX - size, Y: 0, Z: offsetZ - size},
+		{X: offsetX - size, Y: 0, Z: offsetZ + size},
+	}
+}
+
 // SpawnCustomNPC creates a new NPC tank with specified difficulty level
 func (c *NPCController) SpawnCustomNPC(name string, movementPattern MovementPattern, difficultyLevel float64) *NPCTank {
 	c.mutex.Lock()
@@ -234,14 +279,17 @@ func (c *NPCController) SpawnCustomNPC(name string, movementPattern MovementPatt
 	// Generate an NPC ID without NPC prefix but still distinguishable from players
 	npcID := fmt.Sprintf("bot_%d", time.Now().UnixNano())
 
-	// Bias NPC spawning toward center, within 1000 unit radius
-	// Use polar coordinates to ensure even distribution within circle
-	radius := rand.Float64() * 1000.0     // Random radius up to 1000 units
-	angle := rand.Float64() * 2 * math.Pi // Random angle 0-2π
+	// Bias NPC spawning toward center, within 1000 unit radius, on gentle ground
+	spawn := c.gameMap.terrain().SpawnPosition(func() (float64, float64) {
+		// Use polar coordinates to ensure even distribution within circle
+		radius := rand.Float64() * 1000.0     // Random radius up to 1000 units
+		angle := rand.Float64() * 2 * math.Pi // Random angle 0-2π
 
-	// Convert polar to cartesian coordinates
-	offsetX := math.Cos(angle) * radius
-	offsetZ := math.Sin(angle) * radius
+		// Convert polar to cartesian coordinates
+		return math.Cos(angle) * radius, math.Sin(angle) * radius
+	})
+	offsetX := spawn.X
+	offsetZ := spawn.Z
 
 	// Select a random color scheme
 	colorScheme := DefaultNPCColorSchemes[rand.Intn(len(DefaultNPCColorSchemes))]
@@ -250,7 +298,7 @@ func (c *NPCController) SpawnCustomNPC(name string, movementPattern MovementPatt
 	state := PlayerState{
 		ID:             npcID,
 		Name:           name, // Use clean name format without NPC prefix
-		Position:       Position{X: offsetX, Y: 0, Z: offsetZ},
+		Position:       spawn,
 		TankRotation:   rand.Float64() * 2 * math.Pi,
 		TurretRotation: rand.Float64() * 2 * math.Pi,
 		Health:         100,
@@ -264,38 +312,7 @@ func (c *NPCController) SpawnCustomNPC(name string, movementPattern MovementPatt
 	// Create patrol points if using patrol pattern
 	var patrolPoints []Position
 	if movementPattern == PatrolMovement {
-		// Calculate distance from center
-		distFromCenter := math.Sqrt(offsetX*offsetX + offsetZ*offsetZ)
-
-		// For spawns very far from center, make one patrol point near center
-		if distFromCenter > 1000 {
-			// Calculate angle toward center
-			centerAngle := math.Atan2(-offsetZ, -offsetX)
-
-			// Create patrol points with one near center and others around spawn
-			size := 100.0 + rand.Float64()*200.0
-
-			// Calculate a point that's closer to the center
-			moveTowardCenterDist := distFromCenter * 0.6 // Move 60% toward center
-			centerX := offsetX + math.Cos(centerAngle)*moveTowardCenterDist
-			centerZ := offsetZ + math.Sin(centerAngle)*moveTowardCenterDist
-
-			patrolPoints = []Position{
-				{X: offsetX + size, Y: 0, Z: offsetZ + size},
-				{X: centerX, Y: 0, Z: centerZ}, // This point is closer to center
-				{X: offsetX - size, Y: 0, Z: offsetZ - size},
-				{X: offsetX - size, Y: 0, Z: offsetZ + size},
-			}
-		} else {
-			// Regular patrol route for tanks already near center
-			size := 100.0 + rand.Float64()*200.0
-			patrolPoints = []Position{
-				{X: offsetX + size, Y: 0, Z: offsetZ + size},
-				{X: offsetX + size, Y: 0, Z: offsetZ - size},
-				{X: offsetX - size, Y: 0, Z: offsetZ - size},
-				{X: offsetX - size, Y: 0, Z: offsetZ + size},
-			}
-		}
+		patrolPoints = patrolRoute(offsetX, offsetZ)
 	}
 
 	// Generate randomized personality based on difficulty level
@@ -364,46 +381,25 @@ func (c *NPCController) SpawnCustomNPC(name string, movementPattern MovementPatt
 	return npc
 }
 
-// runSimulation is the main NPC simulation loop
-func (c *NPCController) runSimulation() {
-	// Create a fallback ticker that runs occasionally to ensure NPCs stay responsive
-	// even if KV updates are infrequent
-	fallbackTicker := time.NewTicker(1 * time.Second)
-	defer fallbackTicker.Stop()
-
-	// Process state updates using KV watcher
-	updates := c.watcher.Updates()
-	lastProcessed := time.Now()
-
-	// Only update NPCs if they have real targets or it's been a while
-	minUpdateInterval := 100 * time.Millisecond
-
-	for {
-		select {
-		case <-c.quit:
-			return
-		case update := <-updates:
-			// Process KV update
-			if update.Operation() != jetstream.KeyValueDelete { // Skip delete operations
-				var gameState GameState
-				if err := json.Unmarshal(update.Value(), &gameState); err != nil {
-					log.Error("Error unmarshaling game state from KV", "error", err)
-					continue
-				}
+// runSimulation is the NPC phase of the game tick
+func (c *NPCController) runSimulation(tick uint64, dt float64) {
+	// Only update NPCs at a reasonable rate regardless of the tick rate
+	const npcUpdateInterval = 0.1
 
-				// Only process updates at a reasonable rate
-				if time.Since(lastProcessed) > minUpdateInterval {
-					c.processGameState(gameState)
-					lastProcessed = time.Now()
-				}
-			}
-		case <-fallbackTicker.C:
-			// Fallback update in case KV updates are infrequent
-			// This ensures NPCs keep moving even if no state changes happen
-			gameState := c.manager.GetState()
-			c.processGameState(gameState)
-		}
+	c.mutex.Lock()
+	if !c.isRunning {
+		c.mutex.Unlock()
+		return
+	}
+	c.sinceUpdate += dt
+	if c.sinceUpdate < npcUpdateInterval {
+		c.mutex.Unlock()
+		return
 	}
+	c.sinceUpdate = 0
+	c.mutex.Unlock()
+
+	c.processGameState(c.manager.GetState())
 }
 
 // processGameState updates NPCs based on current game state
@@ -521,7 +517,8 @@ func (c *NPCController) processGameState(gameState GameState) {
 				npc.LastAttackerID = ""
 				npc.LastAttackTime = time.Time{}
 			} else {
-				// For normal updates: Only update position if significant movement happened on server side
+				// For normal updates: adopt the server position, since collision response
+				// pushes NPCs out of obstacles and other tanks and slows them down
 				dx := npc.State.Position.X - serverState.Position.X
 				dz := npc.State.Position.Z - serverState.Position.Z
 				dist := math.Sqrt(dx*dx + dz*dz)
@@ -532,8 +529,9 @@ func (c *NPCController) processGameState(gameState GameState) {
 						"oldZ", npc.State.Position.Z,
 						"newX", serverState.Position.X,
 						"newZ", serverState.Position.Z)
-					npc.State.Position = serverState.Position
 				}
+				npc.State.Position = serverState.Position
+				npc.State.Velocity = serverState.Velocity
 			}
 		} else {
 			log.Info("NPC not found in server state, re-registering", "id", npc.ID)
@@ -588,7 +586,10 @@ func (c *NPCController) updateNPCAI(npc *NPCTank, gameState GameState) {
 
 	// Decide whether to pursue target or follow movement pattern
 	// Higher TacticalIQ NPCs make smarter decisions about when to pursue vs patrol
-	if npc.TargetID != "" {
+	if objective, ok := c.modeObjective(npc, gameState); ok {
+		// Mode objectives take priority over fighting
+		c.moveToward(npc, &state, objective)
+	} else if npc.TargetID != "" {
 		// Calculate pursuit likelihood based on multiple factors
 		pursuitLikelihood := npc.Aggressiveness
 
@@ -776,6 +777,112 @@ func (c *NPCController) pursueTarget(npc *NPCTank, state *PlayerState, gameState
 	}
 }
 
+// modeObjective returns where the NPC should head to play the current mode's objective, if anywhere
+func (c *NPCController) modeObjective(npc *NPCTank, gameState GameState) (Position, bool) {
+	if objective, ok := c.flagObjective(npc, gameState); ok {
+		return objective, true
+	}
+	return c.zoneObjective(npc, gameState)
+}
+
+// zoneObjective sends less aggressive NPCs to the nearest control zone their side doesn't hold.
+// NPCs already inside such a zone stay put to capture it.
+func (c *NPCController) zoneObjective(npc *NPCTank, gameState GameState) (Position, bool) {
+	if len(gameState.Zones) == 0 || npc.Aggressiveness >= 0.7 {
+		return Position{}, false
+	}
+
+	self := gameState.Players[npc.ID]
+	side := self.Team
+	if side == "" {
+		side = npc.ID
+	}
+
+	var best *ZoneState
+	bestDist := math.MaxFloat64
+	for i := range gameState.Zones {
+		zone := &gameState.Zones[i]
+		if zone.Owner == side {
+			continue
+		}
+		dist := math.Hypot(zone.Position.X-self.Position.X, zone.Position.Z-self.Position.Z)
+		if dist < bestDist {
+			best, bestDist = zone, dist
+		}
+	}
+
+	// Inside the zone: let the normal behavior take over and fight for it
+	if best == nil || bestDist < best.Radius*0.5 {
+		return Position{}, false
+	}
+	return best.Position, true
+}
+
+// flagObjective returns where the NPC should head in capture-the-flag, if anywhere.
+// Carriers run home; less aggressive NPCs play the objective while the rest hunt enemies.
+func (c *NPCController) flagObjective(npc *NPCTank, gameState GameState) (Position, bool) {
+	if len(gameState.Flags) == 0 {
+		return Position{}, false
+	}
+
+	myTeam := gameState.Players[npc.ID].Team
+	ownFlag, hasOwnFlag := gameState.Flags[myTeam]
+	if !hasOwnFlag {
+		return Position{}, false
+	}
+
+	// Carrying an enemy flag: bring it home
+	for team, flag := range gameState.Flags {
+		if team != myTeam && flag.CarrierID == npc.ID {
+			return ownFlag.Base, true
+		}
+	}
+
+	if npc.Aggressiveness >= 0.7 {
+		return Position{}, false
+	}
+
+	// Recover our own flag if it has been taken, otherwise go for the enemy flag
+	if !ownFlag.AtBase() {
+		return ownFlag.Position, true
+	}
+	for team, flag := range gameState.Flags {
+		if team != myTeam && flag.CarrierID == "" {
+			return flag.Position, true
+		}
+	}
+
+	return Position{}, false
+}
+
+// moveToward turns the NPC gradually toward a destination and drives forward
+func (c *NPCController) moveToward(npc *NPCTank, state *PlayerState, destination Position) {
+	dx := destination.X - state.Position.X
+	dz := destination.Z - state.Position.Z
+	targetAngle := math.Atan2(dz, dx)
+
+	// Turn smoothly rather than snapping to the new heading
+	angleDiff := normalizeAngle(targetAngle - state.TankRotation)
+	rotationSpeed := 0.03
+	if math.Abs(angleDiff) < rotationSpeed {
+		state.TankRotation = targetAngle
+	} else if angleDiff > 0 {
+		state.TankRotation = normalizeAngle(state.TankRotation + rotationSpeed)
+	} else {
+		state.TankRotation = normalizeAngle(state.TankRotation - rotationSpeed)
+	}
+
+	// Drive forward at normal speed
+	npc.MovingBackward = false
+	state.IsMoving = true
+	state.Velocity = 0.2 * npc.MoveSpeed
+
+	moveX := math.Cos(state.TankRotation) * state.Velocity
+	moveZ := math.Sin(state.TankRotation) * state.Velocity
+	state.Position.X += moveX
+	state.Position.Z += moveZ
+}
+
 // findTarget looks for the best player to target, prioritizing recent attackers
 func (c *NPCController) findTarget(npc *NPCTank, gameState GameState) {
 	var bestTargetID string
@@ -785,10 +892,22 @@ func (c *NPCController) findTarget(npc *NPCTank, gameState GameState) {
 	// Check if we have line of sight to potential targets
 	hasLineOfSight := map[string]bool{}
 
+	// Our own team, if the game mode uses teams
+	myTeam := gameState.Players[npc.ID].Team
+
 	// Find best target considering multiple factors
 	for playerID, player := range gameState.Players {
-		// Skip self, other NPCs, and destroyed tanks
-		if playerID == npc.ID || strings.HasPrefix(playerID, "bot_") || player.IsDestroyed {
+		// Skip self and destroyed tanks
+		if playerID == npc.ID || player.IsDestroyed {
+			continue
+		}
+
+		// In team modes target anyone on the other team, otherwise leave other NPCs alone
+		if myTeam != "" {
+			if player.Team == myTeam {
+				continue
+			}
+		} else if strings.HasPrefix(playerID, "bot_") {
 			continue
 		}
 
@@ -1942,8 +2061,10 @@ func (c *NPCController) FireNPCShell(npc *NPCTank, shellData ShellData) bool {
 	// Note: this function is called from updateAimingAndFiring, which is called from updateNPCAI
 	// The calling function already handles temporarily releasing and re-acquiring the mutex
 
-	// Fire the shell through the manager (which has its own debouncing)
-	_, err := c.manager.FireShell(shellData, npc.ID)
+	// NPCs aim on the server, so their shell is added as is (the manager has its own debouncing)
+	c.manager.mutex.Lock()
+	_, err := c.manager.addShell(shellData, npc.ID)
+	c.manager.mutex.Unlock()
 
 	// If there was an error (like debounce rejection), return false
 	if err != nil {
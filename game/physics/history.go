@@ -0,0 +1,56 @@
+package physics
+
+import (
+	"tank-game/game"
+)
+
+// historySize is the number of ticks of tank positions kept per tank. At the
+// highest tick rate of 60Hz this covers about half a second, twice game.MaxRewindMs.
+const historySize = 32
+
+// HistoryRecorder is implemented by physics engines that support lag compensation
+type HistoryRecorder interface {
+	// RecordHistory stores the tank positions of a tick
+	RecordHistory(tick uint64, players map[string]game.PlayerState)
+}
+
+// tankSample is a tank's position and rotation at one tick
+type tankSample struct {
+	tick     uint64
+	position game.Position
+	rotation float64
+}
+
+// positionHistory is a ring buffer of a tank's most recent samples
+type positionHistory struct {
+	samples [historySize]tankSample
+	next    int // Index the next sample is written to
+	count   int // Number of samples written, up to historySize
+}
+
+// record adds a sample, overwriting the oldest once the buffer is full
+func (h *positionHistory) record(tick uint64, position game.Position, rotation float64) {
+	h.samples[h.next] = tankSample{tick: tick, position: position, rotation: rotation}
+	h.next = (h.next + 1) % historySize
+	if h.count < historySize {
+		h.count++
+	}
+}
+
+// at returns the latest sample at or before tick. If the tick is older than the
+// history the oldest sample is returned; false means there are no samples.
+func (h *positionHistory) at(tick uint64) (tankSample, bool) {
+	if h.count == 0 {
+		return tankSample{}, false
+	}
+
+	// Walk back from the newest sample
+	var sample tankSample
+	for i := 1; i <= h.count; i++ {
+		sample = h.samples[(h.next-i+historySize)%historySize]
+		if sample.tick <= tick {
+			break
+		}
+	}
+	return sample, true
+}
@@ -24,25 +24,21 @@ type PhysicsIntegration struct {
 	watcher        jetstream.KeyWatcher
 	ctx            context.Context
 	cancelFunc     context.CancelFunc
+	tickRegistered bool    // Whether the physics phase has been added to the manager's tick
+	accumulator    float64 // Seconds of simulation time not yet consumed by fixed physics steps
+	updateCount    int
 
 	// Map to track previous tank positions for detecting movement
 	previousPositions map[string]game.Position
 }
 
-// NewPhysicsIntegration creates a new physics integration
-func NewPhysicsIntegration(gameManager *game.Manager) *PhysicsIntegration {
-	// Get game map initialized
-	gameMap := game.GetGameMap()
-
-	// Create physics manager with reference to game manager for callbacks
-	// Note: We don't create a physics manager here anymore
-	// It should be set to PhysicsManagerInstance which is created in main.go
-
+// NewPhysicsIntegration creates a new physics integration for one game manager and its map
+func NewPhysicsIntegration(gameManager *game.Manager, gameMap *game.GameMap, physicsManager PhysicsEngine) *PhysicsIntegration {
 	// Create context with cancel function
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &PhysicsIntegration{
-		physicsManager:    PhysicsManagerInstance, // Use the global physics manager instance
+		physicsManager:    physicsManager,
 		gameManager:       gameManager,
 		gameMap:           gameMap,
 		mutex:             sync.RWMutex{},
@@ -71,8 +67,8 @@ func (pi *PhysicsIntegration) Start() {
 
 	// Debug: Verify the game map has trees and rocks loaded
 	if pi.gameMap == nil {
-		log.Error("Critical error: Game map is nil, initializing now")
-		pi.gameMap = game.GetGameMap()
+		log.Error("Critical error: Game map is nil, generating the default map")
+		pi.gameMap = game.GenerateMap(game.DefaultMapConfig())
 	}
 
 	// Log tree info
@@ -88,14 +84,7 @@ func (pi *PhysicsIntegration) Start() {
 				"radius", tree.Radius)
 		}
 	} else {
-		log.Error("Physics: No trees found in game map")
-		// Attempt to reinitialize game map
-		log.Info("Attempting to initialize game map")
-		game.InitGameMap()
-		pi.gameMap = game.GetGameMap()
-		log.Info("Game map reinitialized", 
-			"trees", len(pi.gameMap.Trees.Trees), 
-			"rocks", len(pi.gameMap.Rocks.Rocks))
+		log.Warn("Physics: No trees found in game map")
 	}
 
 	// Log rock info
@@ -127,8 +116,14 @@ func (pi *PhysicsIntegration) Start() {
 	// Run watcher loop in background
 	go pi.watchLoop()
 
-	// Run physics updates in a separate goroutine
-	go pi.runPhysicsLoop()
+	// Run physics updates as a phase of the manager's tick
+	pi.mutex.Lock()
+	register := !pi.tickRegistered
+	pi.tickRegistered = true
+	pi.mutex.Unlock()
+	if register {
+		pi.gameManager.RegisterTickPhase(game.PhasePhysics, pi.runPhysicsStep)
+	}
 
 	log.Info("Physics system started successfully", 
 		"trees", len(pi.gameMap.Trees.Trees),
@@ -330,9 +325,6 @@ func (pi *PhysicsIntegration) processUpdatedState(state game.GameState) {
 				"to", fmt.Sprintf("(%.2f, %.2f, %.2f)", player.Position.X, player.Position.Y, player.Position.Z),
 				"distance", fmt.Sprintf("%.2f units", moveDistance))
 
-			// Check for collisions with environment
-			pi.checkTankCollisions(&player)
-
 			// Store the current position for next time (only after processing movement)
 			pi.previousPositions[id] = player.Position
 		} else {
@@ -365,78 +357,76 @@ func hasMoved(prev, current game.Position) bool {
 	return (dx*dx + dz*dz) > moveThreshold*moveThreshold
 }
 
-// checkTankCollisions checks for collisions when tank movement is detected
-func (pi *PhysicsIntegration) checkTankCollisions(tank *game.PlayerState) {
-	log.Debug("Checking collisions for tank", 
-		"id", tank.ID, 
-		"name", tank.Name, 
-		"position", fmt.Sprintf("(%.2f, %.2f, %.2f)", tank.Position.X, tank.Position.Y, tank.Position.Z))
+// resolveTankCollisions separates overlapping tanks from each other and from the
+// environment, and writes the corrected positions back through the manager so
+// clients cannot drive through obstacles. Returns true if any tank was corrected.
+func (pi *PhysicsIntegration) resolveTankCollisions(players map[string]game.PlayerState) bool {
+	// Resolve in ID order so the result does not depend on map iteration
+	ids := make([]string, 0, len(players))
+	for id, player := range players {
+		if !player.IsDestroyed {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
 
-	collisionsFound := 0
+	tanks := make([]game.PlayerState, len(ids))
+	for i, id := range ids {
+		tanks[i] = players[id]
+	}
 
-	// Function to check collision based on physics manager type
-	checkCollision := func(pos1 game.Position, radius1 float64, pos2 game.Position, radius2 float64) bool {
-		// Create colliders for the spheres
-		a := &Collider{
-			Position: pos1,
-			Radius:   radius1,
-			Type:     ColliderTank,
-			ID:       tank.ID,
+	// Tank against tank
+	for i := range tanks {
+		for j := i + 1; j < len(tanks); j++ {
+			if game.ResolveTankOverlap(&tanks[i], &tanks[j]) {
+				log.Debug("Tank collision resolved", "tank", tanks[i].ID, "other", tanks[j].ID)
+			}
 		}
+	}
 
-		b := &Collider{
-			Position: pos2,
-			Radius:   radius2,
-			Type:     ColliderTree,
-			ID:       "environment",
+	// Tank against trees and rocks
+	destroyed := pi.gameManager.DestroyedObstacles()
+	corrections := make(map[string]game.TankCorrection)
+	for i := range tanks {
+		pi.checkTankCollisions(&tanks[i], destroyed)
+
+		before := players[tanks[i].ID]
+		if tanks[i].Position != before.Position || tanks[i].Velocity != before.Velocity {
+			corrections[tanks[i].ID] = game.TankCorrection{
+				Position: tanks[i].Position,
+				Velocity: tanks[i].Velocity,
+			}
 		}
-
-		return CheckCollision(a, b)
 	}
 
-	// Check for collisions with trees (using a larger radius of 2.5)
-	for _, tree := range pi.gameMap.Trees.Trees {
-		if checkCollision(tank.Position, 2.5, tree.Position, tree.Radius) {
-			collisionsFound++
+	pi.gameManager.CorrectTanks(corrections)
+	return len(corrections) > 0
+}
 
-			// Calculate collision point (average of positions)
-			collisionX := (tank.Position.X + tree.Position.X) / 2
-			collisionZ := (tank.Position.Z + tree.Position.Z) / 2
+// checkTankCollisions pushes a tank out of the trees and rocks it overlaps along the
+// contact normals and slows it down. Returns the number of contacts.
+func (pi *PhysicsIntegration) checkTankCollisions(tank *game.PlayerState, destroyed map[string]bool) int {
+	contacts := 0
 
-			log.Info("Tree collision detected", 
-				"tank", fmt.Sprintf("%s (%s)", tank.ID, tank.Name),
-				"tree", fmt.Sprintf("%s (scale: %.2f)", tree.Type, tree.Scale),
-				"collisionPoint", fmt.Sprintf("(%.2f, %.2f)", collisionX, collisionZ),
-				"tankRadius", 1.5,
-				"treeRadius", tree.Radius)
+	pi.gameMap.Grid().Near(tank.Position, game.TankCollisionRadius, func(obstacle *game.GridObstacle) bool {
+		// Arch tops and broken rocks do not block tanks
+		if !obstacle.BlocksTanks() || destroyed[obstacle.ID] {
+			return true
 		}
-	}
 
-	// Check for collisions with rocks (using a larger radius of 2.5)
-	for _, rock := range pi.gameMap.Rocks.Rocks {
-		if checkCollision(tank.Position, 2.5, rock.Position, rock.Radius) {
-			collisionsFound++
-
-			// Calculate collision point (average of positions)
-			collisionX := (tank.Position.X + rock.Position.X) / 2
-			collisionZ := (tank.Position.Z + rock.Position.Z) / 2
-
-			log.Info("Rock collision detected", 
+		if game.ResolveObstacleContact(tank, obstacle.Position, obstacle.Radius+game.TankCollisionRadius) {
+			contacts++
+			log.Debug("Environment collision resolved", 
 				"tank", fmt.Sprintf("%s (%s)", tank.ID, tank.Name),
-				"rock", fmt.Sprintf("%s (size: %.2f)", rock.Type, rock.Size),
-				"collisionPoint", fmt.Sprintf("(%.2f, %.2f)", collisionX, collisionZ),
-				"tankRadius", 1.5,
-				"rockRadius", rock.Radius)
+				"type", obstacle.Type,
+				"obstacle", obstacle.ID,
+				"position", fmt.Sprintf("(%.2f, %.2f)", tank.Position.X, tank.Position.Z),
+				"velocity", tank.Velocity)
 		}
-	}
-
-	if collisionsFound == 0 {
-		log.Debug("No collisions detected", "tank", tank.Name)
-	} else {
-		log.Debug("Collisions found", "count", collisionsFound, "tank", tank.Name)
-	}
+		return true
+	})
 
-	// Check for collisions with other tanks is done in the runPhysicsLoop
+	return contacts
 }
 
 // checkCollisionsForced checks for collisions on every update regardless of movement
@@ -467,101 +457,59 @@ func (pi *PhysicsIntegration) checkCollisionsForced(tank *game.PlayerState) {
 		return CheckCollision(a, b)
 	}
 
-	closestTreeDist := 1000.0
-	closestTreeIndex := -1
-
-	// First check all trees and find the closest one
-	for i, tree := range pi.gameMap.Trees.Trees {
-		// Calculate distance
-		dist := math.Sqrt(
-			math.Pow(tank.Position.X-tree.Position.X, 2) +
-				math.Pow(tank.Position.Z-tree.Position.Z, 2))
-
-		// Track closest tree
-		if dist < closestTreeDist {
-			closestTreeDist = dist
-			closestTreeIndex = i
-		}
-
-		// Check for collision with a larger detection radius (2.5 instead of 1.5)
-		if checkCollision(tank.Position, 2.5, tree.Position, tree.Radius) {
-			// Calculate collision point
-			collisionX := (tank.Position.X + tree.Position.X) / 2
-			collisionZ := (tank.Position.Z + tree.Position.Z) / 2
-
-			// Super prominent collision alert
-			log.Warn("Tree collision detected", 
-				"tank", fmt.Sprintf("%s (%s) at (%.2f, %.2f, %.2f)", tank.ID, tank.Name, tank.Position.X, tank.Position.Y, tank.Position.Z),
-				"tree", fmt.Sprintf("%s (scale: %.2f) at (%.2f, %.2f, %.2f)", tree.Type, tree.Scale, tree.Position.X, tree.Position.Y, tree.Position.Z),
-				"collisionPoint", fmt.Sprintf("(%.2f, %.2f)", collisionX, collisionZ),
-				"distance", dist,
-				"combinedRadius", 1.5+tree.Radius)
-
-			// Only report one collision at a time to avoid log spam
-			return
-		}
-	}
-
-	// If we didn't find a collision but have trees, report the closest tree
-	if closestTreeIndex >= 0 {
-		tree := pi.gameMap.Trees.Trees[closestTreeIndex]
-		combinedRadius := 1.5 + tree.Radius
-		log.Debug("Closest tree info", 
-			"distance", fmt.Sprintf("%.2f units", closestTreeDist), 
-			"combinedRadius", combinedRadius,
-			"tree", fmt.Sprintf("#%d: Type=%s at (%.2f, %.2f, %.2f) with radius %.2f",
-				closestTreeIndex, tree.Type, tree.Position.X, tree.Position.Y, tree.Position.Z, tree.Radius),
-			"noCollision", fmt.Sprintf("%.2f > %.2f", closestTreeDist, combinedRadius))
-	}
-
-	closestRockDist := 1000.0
-	closestRockIndex := -1
+	var closest *game.GridObstacle
+	closestDist := proximityRadius
 
-	// Then check all rocks and find the closest one
-	for i, rock := range pi.gameMap.Rocks.Rocks {
+	// Check the trees and rocks around the tank and find the closest one
+	collided := false
+	pi.gameMap.Grid().Near(tank.Position, proximityRadius, func(obstacle *game.GridObstacle) bool {
 		// Calculate distance
 		dist := math.Sqrt(
-			math.Pow(tank.Position.X-rock.Position.X, 2) +
-				math.Pow(tank.Position.Z-rock.Position.Z, 2))
+			math.Pow(tank.Position.X-obstacle.Position.X, 2) +
+				math.Pow(tank.Position.Z-obstacle.Position.Z, 2))
 
-		// Track closest rock
-		if dist < closestRockDist {
-			closestRockDist = dist
-			closestRockIndex = i
+		// Track closest obstacle
+		if dist < closestDist {
+			closestDist = dist
+			closest = obstacle
 		}
 
 		// Check for collision with a larger detection radius (2.5 instead of 1.5)
-		if checkCollision(tank.Position, 2.5, rock.Position, rock.Radius) {
+		if checkCollision(tank.Position, 2.5, obstacle.Position, obstacle.Radius) {
 			// Calculate collision point
-			collisionX := (tank.Position.X + rock.Position.X) / 2
-			collisionZ := (tank.Position.Z + rock.Position.Z) / 2
+			collisionX := (tank.Position.X + obstacle.Position.X) / 2
+			collisionZ := (tank.Position.Z + obstacle.Position.Z) / 2
 
 			// Super prominent collision alert
-			log.Warn("Rock collision detected", 
+			log.Warn("Environment collision detected", 
 				"tank", fmt.Sprintf("%s (%s) at (%.2f, %.2f, %.2f)", tank.ID, tank.Name, tank.Position.X, tank.Position.Y, tank.Position.Z),
-				"rock", fmt.Sprintf("%s (size: %.2f) at (%.2f, %.2f, %.2f)", rock.Type, rock.Size, rock.Position.X, rock.Position.Y, rock.Position.Z),
+				"obstacle", fmt.Sprintf("%s %s at (%.2f, %.2f, %.2f)", obstacle.Type, obstacle.ID, obstacle.Position.X, obstacle.Position.Y, obstacle.Position.Z),
 				"collisionPoint", fmt.Sprintf("(%.2f, %.2f)", collisionX, collisionZ),
 				"distance", dist,
-				"combinedRadius", 1.5+rock.Radius)
+				"combinedRadius", 1.5+obstacle.Radius)
 
 			// Only report one collision at a time to avoid log spam
-			return
+			collided = true
+			return false
 		}
-	}
+		return true
+	})
 
-	// If we didn't find a collision but have rocks, report the closest rock
-	if closestRockIndex >= 0 {
-		rock := pi.gameMap.Rocks.Rocks[closestRockIndex]
-		combinedRadius := 1.5 + rock.Radius
-		log.Debug("Closest rock info", 
-			"distance", fmt.Sprintf("%.2f units", closestRockDist), 
+	// If we didn't find a collision but have obstacles nearby, report the closest one
+	if !collided && closest != nil {
+		combinedRadius := 1.5 + closest.Radius
+		log.Debug("Closest obstacle info", 
+			"distance", fmt.Sprintf("%.2f units", closestDist), 
 			"combinedRadius", combinedRadius,
-			"rock", fmt.Sprintf("#%d: Type=%s at (%.2f, %.2f, %.2f) with radius %.2f",
-				closestRockIndex, rock.Type, rock.Position.X, rock.Position.Y, rock.Position.Z, rock.Radius),
-			"noCollision", fmt.Sprintf("%.2f > %.2f", closestRockDist, combinedRadius))
+			"obstacle", fmt.Sprintf("%s %s at (%.2f, %.2f, %.2f) with radius %.2f",
+				closest.Type, closest.ID, closest.Position.X, closest.Position.Y, closest.Position.Z, closest.Radius),
+			"noCollision", fmt.Sprintf("%.2f > %.2f", closestDist, combinedRadius))
 	}
 }
 
+// proximityRadius is how far around a tank obstacles are considered in debug reports
+const proximityRadius = 100.0
+
 // logEnvironmentProximity logs the proximity of tanks to environment objects
 func (pi *PhysicsIntegration) logEnvironmentProximity(tankPositions []game.Position) {
 	if len(tankPositions) == 0 {
@@ -585,46 +533,27 @@ func (pi *PhysicsIntegration) logEnvironmentProximity(tankPositions []game.Posit
 	// Track all environment-tank pairs
 	allProximities := []proximityInfo{}
 
-	// Check trees
-	for i, tree := range pi.gameMap.Trees.Trees {
-		for j, tankPos := range tankPositions {
+	// Check the trees and rocks around each tank
+	grid := pi.gameMap.Grid()
+	for j, tankPos := range tankPositions {
+		grid.Near(tankPos, proximityRadius, func(obstacle *game.GridObstacle) bool {
 			// Calculate distance
-			dx := tree.Position.X - tankPos.X
-			dz := tree.Position.Z - tankPos.Z
+			dx := obstacle.Position.X - tankPos.X
+			dz := obstacle.Position.Z - tankPos.Z
 			dist := math.Sqrt(dx*dx + dz*dz)
 
 			allProximities = append(allProximities, proximityInfo{
-				objType:    "tree",
-				objIndex:   i,
+				objType:    obstacle.Type,
+				objIndex:   obstacle.Index,
 				tankIndex:  j,
 				distance:   dist,
-				objPos:     tree.Position,
+				objPos:     obstacle.Position,
 				tankPos:    tankPos,
-				objRadius:  tree.Radius,
+				objRadius:  obstacle.Radius,
 				tankRadius: 2.5, // Increased from 1.5
 			})
-		}
-	}
-
-	// Check rocks
-	for i, rock := range pi.gameMap.Rocks.Rocks {
-		for j, tankPos := range tankPositions {
-			// Calculate distance
-			dx := rock.Position.X - tankPos.X
-			dz := rock.Position.Z - tankPos.Z
-			dist := math.Sqrt(dx*dx + dz*dz)
-
-			allProximities = append(allProximities, proximityInfo{
-				objType:    "rock",
-				objIndex:   i,
-				tankIndex:  j,
-				distance:   dist,
-				objPos:     rock.Position,
-				tankPos:    tankPos,
-				objRadius:  rock.Radius,
-				tankRadius: 2.5, // Increased from 1.5
-			})
-		}
+			return true
+		})
 	}
 
 	// Sort by distance
@@ -661,32 +590,50 @@ func (pi *PhysicsIntegration) logEnvironmentProximity(tankPositions []game.Posit
 	}
 }
 
-// runPhysicsLoop is the main physics update loop
-func (pi *PhysicsIntegration) runPhysicsLoop() {
-	log.Info("Tank-to-tank collision detection loop started")
+// physicsStepSeconds is the fixed physics step; shell integration assumes 100ms steps
+const physicsStepSeconds = 0.1
 
-	updateCount := 0
+// SetMap moves the simulation onto a new map
+func (pi *PhysicsIntegration) SetMap(gameMap *game.GameMap) {
+	pi.mutex.Lock()
+	defer pi.mutex.Unlock()
 
-	for {
-		pi.mutex.RLock()
-		running := pi.isRunning
-		pi.mutex.RUnlock()
+	pi.gameMap = gameMap
+	pi.previousPositions = make(map[string]game.Position)
+	pi.physicsManager.SetMap(gameMap)
+}
 
-		if !running {
-			log.Info("Physics simulation stopped")
-			return
-		}
+// runPhysicsStep is the physics phase of the game tick.
+// It consumes tick time in fixed 100ms steps so shell motion is independent of the tick rate.
+func (pi *PhysicsIntegration) runPhysicsStep(tick uint64, dt float64) {
+	pi.mutex.Lock()
+	if !pi.isRunning {
+		pi.mutex.Unlock()
+		return
+	}
+	pi.accumulator += dt
+	steps := 0
+	for pi.accumulator >= physicsStepSeconds && steps < 3 {
+		pi.accumulator -= physicsStepSeconds
+		steps++
+	}
+	// Drop time we could not catch up on instead of spiralling
+	if pi.accumulator >= physicsStepSeconds {
+		pi.accumulator = 0
+	}
+	pi.updateCount += steps
+	if steps > 0 && pi.updateCount%50 < steps {
+		log.Debug("Physics loop heartbeat", "updates", pi.updateCount, "tick", tick)
+	}
+	pi.mutex.Unlock()
 
-		updateCount++
-		if updateCount%50 == 0 {
-			log.Debug("Physics loop heartbeat", "updates", updateCount)
-		}
+	// Keep every tick's tank positions so shells can be tested against the past
+	if recorder, ok := pi.physicsManager.(HistoryRecorder); ok {
+		recorder.RecordHistory(tick, pi.gameManager.GetState().Players)
+	}
 
-		// Update physics
+	for i := 0; i < steps; i++ {
 		pi.updatePhysics()
-
-		// Sleep to limit updates to a reasonable rate
-		time.Sleep(100 * time.Millisecond)
 	}
 }
 
@@ -695,6 +642,11 @@ func (pi *PhysicsIntegration) updatePhysics() {
 	// Get current game state
 	gameState := pi.gameManager.GetState()
 
+	// Push tanks out of obstacles and each other before anything is tested against them
+	if pi.resolveTankCollisions(gameState.Players) {
+		gameState = pi.gameManager.GetState()
+	}
+
 	// Register/update all tanks with physics manager
 	for _, player := range gameState.Players {
 		if !player.IsDestroyed {
@@ -713,59 +665,62 @@ func (pi *PhysicsIntegration) updatePhysics() {
 			log.Debug("Processing shells for collisions", "count", len(gameState.Shells))
 		}
 
-		// Make a copy of shells to avoid modifying the original state
-		shellsCopy := make([]game.ShellState, len(gameState.Shells))
-		copy(shellsCopy, gameState.Shells)
-
 		// Log shell positions before physics update
-		if len(shellsCopy) > 0 {
-			log.Debug("Shell position before physics", 
-				"shellID", shellsCopy[0].ID, 
-				"position", fmt.Sprintf("(%.2f,%.2f,%.2f)", 
-					shellsCopy[0].Position.X, 
-					shellsCopy[0].Position.Y, 
-					shellsCopy[0].Position.Z))
-		}
+		log.Debug("Shell position before physics", 
+			"shellID", gameState.Shells[0].ID, 
+			"position", fmt.Sprintf("(%.2f,%.2f,%.2f)", 
+				gameState.Shells[0].Position.X, 
+				gameState.Shells[0].Position.Y, 
+				gameState.Shells[0].Position.Z))
 
-		// Update shells with physics simulation
-		pi.physicsManager.UpdateShells(shellsCopy)
-
-		// Log shell positions after physics update to see if they changed
-		if len(shellsCopy) > 0 {
-			log.Debug("Shell position after physics", 
-				"shellID", shellsCopy[0].ID, 
-				"position", fmt.Sprintf("(%.2f,%.2f,%.2f)", 
-					shellsCopy[0].Position.X, 
-					shellsCopy[0].Position.Y, 
-					shellsCopy[0].Position.Z))
-		}
+	}
 
-		// Check if any shells were modified by physics (hit ground or expired)
-		// and need to be removed from game state
-		shellsToRemove := []string{}
-		for i, shell := range shellsCopy {
-			// Check if the shell hit ground (Y <= 0) or was marked as collided (Y < 0)
-			if shell.Position.Y <= 0 {
-				shellsToRemove = append(shellsToRemove, shell.ID)
-				log.Debug("Shell marked for removal", "shellID", shell.ID, "reason", "hit ground or collision")
-			} else {
-				// Update the shell position in game state for next frame
-				gameState.Shells[i] = shell
-			}
-		}
+	// Hand the shells to the physics simulation, even none, so shells removed from
+	// the game state are not stepped again
+	shellsCopy := make([]game.ShellState, len(gameState.Shells))
+	copy(shellsCopy, gameState.Shells)
+	pi.physicsManager.UpdateShells(shellsCopy)
+
+	// Run physics update: moves shells, sweeps them for hits and resolves tank collisions
+	pi.physicsManager.Update()
+
+	if len(gameState.Shells) == 0 {
+		return
+	}
 
-		// Ask game manager to remove expired/hit shells
-		if len(shellsToRemove) > 0 && pi.gameManager != nil {
-			pi.gameManager.RemoveShells(shellsToRemove)
+	// Shells that hit something or landed are below ground and removed from game state;
+	// the rest keep flying from where physics moved them
+	shells := pi.physicsManager.ShellStates()
+	shellsToRemove := []string{}
+	moved := make([]game.ShellState, 0, len(shells))
+	for _, shell := range shells {
+		if shell.Position.Y <= 0 {
+			shellsToRemove = append(shellsToRemove, shell.ID)
+			log.Debug("Shell marked for removal", "shellID", shell.ID, "reason", "hit ground or collision")
+		} else {
+			moved = append(moved, shell)
 		}
+	}
 
-		// Log the results of processing shells
-		log.Debug("Physics cycle results", 
-			"total", len(gameState.Shells), 
-			"removed", len(shellsToRemove), 
-			"active", len(gameState.Shells)-len(shellsToRemove))
+	if len(moved) > 0 {
+		log.Debug("Shell position after physics",
+			"shellID", moved[0].ID,
+			"position", fmt.Sprintf("(%.2f,%.2f,%.2f)",
+				moved[0].Position.X,
+				moved[0].Position.Y,
+				moved[0].Position.Z))
 	}
 
-	// Run physics update for tank-to-tank collisions
-	pi.physicsManager.Update()
-}
\ No newline at end of file
+	pi.gameManager.UpdateShellPositions(moved)
+
+	// Ask game manager to remove expired/hit shells
+	if len(shellsToRemove) > 0 {
+		pi.gameManager.RemoveShells(shellsToRemove)
+	}
+
+	// Log the results of processing shells
+	log.Debug("Physics cycle results",
+		"total", len(shells),
+		"removed", len(shellsToRemove),
+		"active", len(moved))
+}
@@ -34,6 +34,13 @@ func NewPhysicsManager(gameMap *game.GameMap, gameManager *game.Manager) *Physic
 	}
 }
 
+// SetMap switches to a new map and drops the shells of the previous one
+func (pm *PhysicsManager) SetMap(gameMap *game.GameMap) {
+	pm.gameMap = gameMap
+	pm.shells = make([]game.ShellState, 0)
+	pm.hits = make([]game.HitData, 0)
+}
+
 // RegisterTank registers a tank for collision detection
 func (pm *PhysicsManager) RegisterTank(tank *game.PlayerState) {
 	pm.tanks[tank.ID] = tank
@@ -278,6 +285,14 @@ func (pm *PhysicsManager) Update() {
 	// but we could re-check here if needed
 }
 
+// ShellStates returns the shells after the last update. Shells are not moved
+// here, so only the ones that hit something have changed.
+func (pm *PhysicsManager) ShellStates() []game.ShellState {
+	shells := make([]game.ShellState, len(pm.shells))
+	copy(shells, pm.shells)
+	return shells
+}
+
 // GetHits returns the detected hits since the last update
 func (pm *PhysicsManager) GetHits() []game.HitData {
 	return pm.hits
@@ -13,11 +13,14 @@ type PhysicsEngine interface {
 	UpdateTank(tank *game.PlayerState)
 	UpdateShells(shells []game.ShellState)
 	Update()
+	ShellStates() []game.ShellState
 	GetHits() []game.HitData
 	CheckLineOfSight(fromPos, toPos shared.Position) bool
+	SetMap(gameMap *game.GameMap)
 }
 
 // PhysicsManagerInstance is the singleton instance of the physics manager
+// DEPRECATED: Each room owns its own physics manager; this is no longer set
 var PhysicsManagerInstance PhysicsEngine
 
 // Initialize initializes the physics package
@@ -25,8 +28,8 @@ var PhysicsManagerInstance PhysicsEngine
 func Initialize() {
 	log.Info("Initializing physics system")
 
-	// Explicitly initialize the game map first to ensure it's populated
-	gameMap := game.InitGameMap()
+	// Generate the default map to verify it's populated
+	gameMap := game.GenerateMap(game.DefaultMapConfig())
 
 	// Verify the game map has data
 	treeCount := len(gameMap.Trees.Trees)
@@ -8,19 +8,24 @@ import (
 	"tank-game/game/shared"
 )
 
-// Global physics manager instance is defined in physics.go
-
 // VuPhysicsManager is a physics manager that uses a simplified physics engine
 type VuPhysicsManager struct {
 	gameMap      *game.GameMap
 	tanks        map[string]*TankBody
 	shells       map[string]*ShellBody
-	obstacles    []*ObstacleBody  // Trees, rocks, and other static objects
-	hits         []game.HitData   // Shell hits to process
-	manager      *game.Manager    // Reference to game manager for callbacks
-	shellPhysics *ShellPhysics    // Shell physics calculator
+	obstacles    []*ObstacleBody             // Trees, rocks, and other static objects
+	hits         []game.HitData              // Shell hits to process
+	manager      *game.Manager               // Reference to game manager for callbacks
+	shellPhysics *ShellPhysics               // Shell physics calculator
+	history      map[string]*positionHistory // Recent tank positions by tick, for lag compensation
+	tick         uint64                      // Latest tick recorded in the history
+	destroyed    map[string]bool             // Obstacles broken by shells this match, refreshed every update
+	grid         *game.SpatialGrid           // Broadphase index of the obstacles
 }
 
+// obstacleRadiusScale makes physics obstacles slightly larger than the map colliders
+const obstacleRadiusScale = 1.2
+
 // TankBody represents a tank physics body
 type TankBody struct {
 	State    *game.PlayerState
@@ -31,62 +36,85 @@ type TankBody struct {
 type ShellBody struct {
 	State    game.ShellState
 	Collider *Collider
+	Previous game.Position // Position before the last step, the start of the swept segment
+	Landed   bool          // The shell reached the ground during the last step
 }
 
 // ObstacleBody represents a static obstacle physics body (tree, rock)
 type ObstacleBody struct {
-	Position game.Position
-	Radius   float64
-	Type     ColliderType
-	ID       string
+	Position  game.Position
+	Radius    float64
+	Type      ColliderType
+	ID        string
+	HitPoints int // Shell hits before the obstacle breaks, 0 if indestructible
+}
+
+// segmentObstacles calls visit for every intact obstacle whose collider, grown by
+// padding, may touch the segment. Returning false from visit stops the query.
+func (pm *VuPhysicsManager) segmentObstacles(start, end game.Position, padding float64, visit func(obstacle *ObstacleBody) bool) {
+	// The grid indexes the unscaled map colliders
+	padding += (obstacleRadiusScale - 1) * pm.grid.MaxRadius()
+
+	pm.grid.Segment(start, end, padding, func(obstacle *game.GridObstacle) bool {
+		body := pm.obstacles[obstacle.Index]
+		if pm.destroyed[body.ID] {
+			return true
+		}
+		return visit(body)
+	})
 }
 
 // NewVuPhysicsManager creates a new physics manager using a simplified physics engine
 func NewVuPhysicsManager(gameMap *game.GameMap, gameManager *game.Manager) *VuPhysicsManager {
 	pm := &VuPhysicsManager{
-		gameMap:      gameMap,
 		tanks:        make(map[string]*TankBody),
 		shells:       make(map[string]*ShellBody),
-		obstacles:    make([]*ObstacleBody, 0),
 		hits:         make([]game.HitData, 0),
 		manager:      gameManager,
 		shellPhysics: NewShellPhysics(),
+		history:      make(map[string]*positionHistory),
+		destroyed:    make(map[string]bool),
 	}
+	pm.loadObstacles(gameMap)
 
-	// Initialize obstacle bodies for trees
-	for _, tree := range gameMap.Trees.Trees {
-		// Increased collision radius for trees to 1.5x the visual size
-		collisionRadius := tree.Radius * 1.2
-
-		// Add body to the list of obstacle bodies
-		pm.obstacles = append(pm.obstacles, &ObstacleBody{
-			Position: tree.Position,
-			Radius:   collisionRadius,
-			Type:     ColliderTree,
-			ID:       string(tree.Type),
-		})
-	}
+	return pm
+}
 
-	// Initialize obstacle bodies for rocks
-	for _, rock := range gameMap.Rocks.Rocks {
-		// Increased collision radius for rocks
-		collisionRadius := rock.Radius * 1.2
+// loadObstacles builds the obstacle bodies of a map
+func (pm *VuPhysicsManager) loadObstacles(gameMap *game.GameMap) {
+	pm.gameMap = gameMap
+	pm.grid = gameMap.Grid()
+	pm.obstacles = make([]*ObstacleBody, 0, len(pm.grid.Obstacles()))
+
+	// Initialize obstacle bodies for trees and rocks, in grid order so grid
+	// query results index straight into the bodies
+	for _, obstacle := range pm.grid.Obstacles() {
+		colliderType := ColliderTree
+		if obstacle.Type == game.ObstacleRock {
+			colliderType = ColliderRock
+		}
 
 		// Add body to the list of obstacle bodies
 		pm.obstacles = append(pm.obstacles, &ObstacleBody{
-			Position: rock.Position,
-			Radius:   collisionRadius,
-			Type:     ColliderRock,
-			ID:       string(rock.Type),
+			Position:  obstacle.Position,
+			Radius:    obstacle.Radius * obstacleRadiusScale,
+			Type:      colliderType,
+			ID:        obstacle.ID,
+			HitPoints: obstacle.HitPoints,
 		})
 	}
 
 	log.Debug("Physics: Initialized obstacle bodies", "count", len(pm.obstacles))
+}
 
-	// Set as global instance (using the one defined in physics.go)
-	PhysicsManagerInstance = pm
-
-	return pm
+// SetMap replaces the obstacles and terrain with those of a new map.
+// Shells, hits and the tank history of the previous map are dropped.
+func (pm *VuPhysicsManager) SetMap(gameMap *game.GameMap) {
+	pm.loadObstacles(gameMap)
+	pm.shells = make(map[string]*ShellBody)
+	pm.hits = make([]game.HitData, 0)
+	pm.history = make(map[string]*positionHistory)
+	pm.destroyed = make(map[string]bool)
 }
 
 // RegisterTank registers a tank with the physics manager
@@ -169,6 +197,7 @@ func (pm *VuPhysicsManager) UpdateShells(shells []game.ShellState) {
 		shellBody := &ShellBody{
 			State:    shell,
 			Collider: collider,
+			Previous: shell.Position,
 		}
 
 		// Add to shells map
@@ -184,12 +213,27 @@ func (pm *VuPhysicsManager) Update() {
 	// Clear previous hits
 	pm.hits = make([]game.HitData, 0)
 
-	// Check for tank-shell collisions
-	pm.checkShellCollisions()
+	// Obstacles come back when a new match starts
+	if pm.manager != nil {
+		pm.destroyed = pm.manager.DestroyedObstacles()
+	}
 
 	// Apply gravity and other forces
 	// Run the physics simulation
 	pm.applyGravityToShells()
+
+	// Sweep each shell's path over the step against tanks and obstacles
+	pm.checkShellCollisions()
+}
+
+// ShellStates returns the shells after the last update. Shells that hit
+// something or landed are below ground and should be removed.
+func (pm *VuPhysicsManager) ShellStates() []game.ShellState {
+	shells := make([]game.ShellState, 0, len(pm.shells))
+	for _, shell := range pm.shells {
+		shells = append(shells, shell.State)
+	}
+	return shells
 }
 
 // GetHits returns the hits detected during the last update
@@ -203,32 +247,90 @@ func (pm *VuPhysicsManager) CheckLineOfSight(from, to shared.Position) bool {
 	fromPos := game.Position{X: from.X, Y: from.Y, Z: from.Z}
 	toPos := game.Position{X: to.X, Y: to.Y, Z: to.Z}
 
+	// Hills and mountains block the view
+	if pm.gameMap.Terrain.BlocksSight(fromPos, toPos) {
+		return false
+	}
+
 	// Check if there's a clear line of sight between two positions
-	// by checking if the line intersects with any obstacles
-	// Simple implementation using direct ray to obstacles
-	for _, obstacle := range pm.obstacles {
+	// by checking if the line intersects with any obstacles near it
+	visible := true
+	pm.segmentObstacles(fromPos, toPos, 0, func(obstacle *ObstacleBody) bool {
 		// Check if the line from-to intersects with the obstacle
 		if lineSphereIntersection(fromPos, toPos, obstacle.Position, obstacle.Radius) {
-			return false
+			visible = false
 		}
+		return visible
+	})
+
+	return visible
+}
+
+// RecordHistory stores the positions of all live tanks at a tick and forgets tanks that are gone
+func (pm *VuPhysicsManager) RecordHistory(tick uint64, players map[string]game.PlayerState) {
+	pm.tick = tick
+
+	for id, player := range players {
+		if player.IsDestroyed {
+			continue
+		}
+		history, ok := pm.history[id]
+		if !ok {
+			history = &positionHistory{}
+			pm.history[id] = history
+		}
+		history.record(tick, player.Position, player.TankRotation)
 	}
 
-	return true
+	for id := range pm.history {
+		if _, ok := players[id]; !ok {
+			delete(pm.history, id)
+		}
+	}
+}
+
+// rewind returns where a tank was the given number of ticks ago, or its current
+// position and rotation if there is no history that far back
+func (pm *VuPhysicsManager) rewind(tank *TankBody, ticks uint64) (game.Position, float64) {
+	if ticks == 0 || ticks > pm.tick {
+		return tank.State.Position, tank.State.TankRotation
+	}
+
+	history, ok := pm.history[tank.State.ID]
+	if !ok {
+		return tank.State.Position, tank.State.TankRotation
+	}
+	sample, ok := history.at(pm.tick - ticks)
+	if !ok {
+		return tank.State.Position, tank.State.TankRotation
+	}
+	return sample.position, sample.rotation
+}
+
+// shellContact is the first thing a shell's swept path touches
+type shellContact struct {
+	distance float64       // Distance along the path from the previous position
+	point    game.Position // Impact point on the surface that was hit
+	tank     *TankBody     // Tank that was hit, if any
+	obstacle *ObstacleBody // Obstacle that was hit, if any
 }
 
-// checkShellCollisions checks for collisions between shells and tanks
+// checkShellCollisions sweeps every shell from its previous to its new position and
+// resolves the earliest contact with a tank or obstacle. Testing the whole segment
+// keeps fast shells from tunnelling through colliders between steps.
 func (pm *VuPhysicsManager) checkShellCollisions() {
 	log.Debug("Checking shells against tanks", "shells", len(pm.shells), "tanks", len(pm.tanks))
 
-	// Check each shell against each tank
 	for shellID, shell := range pm.shells {
-		shellPos := shell.State.Position
-
-		// Skip shells that are below ground level (already hit ground)
-		if shellPos.Y < 0 {
+		// Skip shells that already hit something in an earlier step
+		if shell.Previous.Y < 0 {
 			continue
 		}
 
+		start := shell.Previous
+		end := shell.State.Position
+		var contact *shellContact
+
 		for tankID, tank := range pm.tanks {
 			// Skip if the shell belongs to this tank (don't hit self)
 			if shell.State.PlayerID == tankID {
@@ -240,98 +342,185 @@ func (pm *VuPhysicsManager) checkShellCollisions() {
 				continue
 			}
 
-			tankPos := tank.State.Position
-
-			// Check for collision
-			if CheckCollision(shell.Collider, tank.Collider) {
-				// Determine hit location (front, side, rear, top)
-				hitLocation := determineHitLocation(shellPos, tankPos, tank.State.TankRotation)
-
-				// Calculate damage based on hit location and shell properties
-				damageAmount := calculateDamage(hitLocation)
-
-				log.Info("Shell hit detected", 
-					"shellID", shellID,
-					"sourceID", shell.State.PlayerID,
-					"targetID", tankID,
-					"targetName", tank.State.Name,
-					"hitLocation", hitLocation,
-					"damage", damageAmount)
-
-				// Create hit data
-				hit := game.HitData{
-					SourceID:     shell.State.PlayerID,
-					TargetID:     tankID,
-					HitLocation:  hitLocation,
-					DamageAmount: damageAmount,
-					Timestamp:    shell.State.Timestamp,
-				}
+			// Lag compensation: test against where the tank was when the shooter fired
+			tankPos, _ := pm.rewind(tank, shell.State.RewindTicks)
+			radius := tank.Collider.Radius + shell.Collider.Radius
+			if distance, ok := game.SegmentSphereContact(start, end, tankPos, radius); ok && (contact == nil || distance < contact.distance) {
+				contact = &shellContact{distance: distance, tank: tank}
+			}
+		}
 
-				// Immediately process the hit if we have a manager
-				if pm.manager != nil {
-					err := pm.manager.ProcessTankHit(hit)
-					if err != nil {
-						log.Error("Error processing tank hit", "error", err)
-					} else {
-						log.Debug("Successfully processed hit on tank", "targetID", hit.TargetID)
-					}
-				} else {
-					// Add to hits for later processing
-					pm.hits = append(pm.hits, hit)
-				}
+		pm.segmentObstacles(start, end, shell.Collider.Radius, func(obstacle *ObstacleBody) bool {
+			radius := obstacle.Radius + shell.Collider.Radius
+			if distance, ok := game.SegmentSphereContact(start, end, obstacle.Position, radius); ok && (contact == nil || distance < contact.distance) {
+				contact = &shellContact{distance: distance, obstacle: obstacle}
+			}
+			return true
+		})
 
-				// Mark the shell as hit by setting its Y position negative
+		if contact == nil {
+			// Nothing was hit before the shell reached the ground
+			if shell.Landed {
+				log.Debug("Shell hit ground", "shellID", shellID, "position", shell.State.Position)
 				shell.State.Position.Y = -1
-				shell.Collider.Position.Y = -1
-				break
+				shell.Collider.Position = shell.State.Position
 			}
+			continue
 		}
+		contact.point = pointAlong(start, end, contact.distance)
+
+		if contact.tank != nil {
+			pm.applyShellHit(shellID, shell, contact)
+		} else {
+			pm.applyObstacleImpact(shellID, shell, contact)
+		}
+
+		// Mark the shell as hit by setting its Y position negative
+		shell.State.Position = contact.point
+		shell.State.Position.Y = -1
+		shell.Collider.Position = shell.State.Position
+	}
+}
+
+// applyShellHit damages a tank at the contact point of a shell
+func (pm *VuPhysicsManager) applyShellHit(shellID string, shell *ShellBody, contact *shellContact) {
+	tank := contact.tank
+	tankID := tank.State.ID
+	tankPos, tankRotation := pm.rewind(tank, shell.State.RewindTicks)
+
+	// Determine hit location (front, side, rear, top) from the actual impact point
+	hitLocation := determineHitLocation(contact.point, tankPos, tankRotation)
+
+	// Calculate damage based on hit location and shell properties
+	damageAmount := calculateDamage(hitLocation)
+
+	log.Info("Shell hit detected",
+		"shellID", shellID,
+		"sourceID", shell.State.PlayerID,
+		"targetID", tankID,
+		"targetName", tank.State.Name,
+		"hitLocation", hitLocation,
+		"damage", damageAmount,
+		"rewindTicks", shell.State.RewindTicks)
+
+	// Create hit data
+	hit := game.HitData{
+		SourceID:     shell.State.PlayerID,
+		TargetID:     tankID,
+		HitLocation:  hitLocation,
+		DamageAmount: damageAmount,
+		Timestamp:    shell.State.Timestamp,
+	}
+
+	// Immediately process the hit if we have a manager
+	if pm.manager != nil {
+		if err := pm.manager.ProcessTankHit(hit); err != nil {
+			log.Error("Error processing tank hit", "error", err)
+		} else {
+			log.Debug("Successfully processed hit on tank", "targetID", hit.TargetID)
+		}
+	} else {
+		// Add to hits for later processing
+		pm.hits = append(pm.hits, hit)
+	}
+}
+
+// applyObstacleImpact reports a shell striking a tree or rock so clients can render
+// debris, and breaks destructible obstacles that have taken enough hits
+func (pm *VuPhysicsManager) applyObstacleImpact(shellID string, shell *ShellBody, contact *shellContact) {
+	obstacle := contact.obstacle
+
+	log.Debug("Shell hit obstacle",
+		"shellID", shellID,
+		"obstacle", obstacle.ID,
+		"type", obstacle.Type,
+		"point", contact.point)
+
+	if pm.manager == nil {
+		return
+	}
+
+	impact := game.ImpactState{
+		ID:       shellID,
+		PlayerID: shell.State.PlayerID,
+		Position: contact.point,
+		Obstacle: game.ObstacleState{
+			ID:       obstacle.ID,
+			Type:     string(obstacle.Type),
+			Position: obstacle.Position,
+		},
+	}
+	if pm.manager.RecordImpact(impact, obstacle.HitPoints) {
+		pm.destroyed[obstacle.ID] = true
 	}
 }
 
-// applyGravityToShells applies gravity to all shells
+// Shell ballistics match the client, which moves shells once per rendered frame
+// with speed in world units per frame
+const (
+	shellFrameRate   = 60.0   // Client frames per second
+	shellGravity     = 0.005  // Downward velocity added per frame, scaled up with speed
+	shellDrag        = 0.001  // Drag coefficient, proportional to speed squared
+	shellWindPerAxis = 0.0005 // Wind drift added to X and Z velocity per frame
+)
+
+// applyGravityToShells advances all shells by one physics step, remembering where
+// each one started so the step can be swept for collisions
 func (pm *VuPhysicsManager) applyGravityToShells() {
+	frames := int(math.Round(physicsStepSeconds * shellFrameRate))
+
 	for _, shell := range pm.shells {
+		shell.Previous = shell.State.Position
+		shell.Landed = false
+
 		// Skip shells that have already hit something
 		if shell.State.Position.Y < 0 {
 			continue
 		}
 
-		// Apply gravity - shells fall over time
-		const GRAVITY = 9.8 // m/s^2
-
-		// Adjust speed and direction based on gravity for 100ms
-		// In a real simulation, we'd use time delta
-		velocityY := shell.State.Direction.Y * shell.State.Speed
-		velocityY -= GRAVITY * 0.1 // Apply gravity for 100ms
-
-		// Calculate new speed (magnitude of velocity)
-		velocityX := shell.State.Direction.X * shell.State.Speed
-		velocityZ := shell.State.Direction.Z * shell.State.Speed
-		newSpeed := math.Sqrt(velocityX*velocityX + velocityY*velocityY + velocityZ*velocityZ)
-
-		// Calculate new normalized direction
-		if newSpeed > 0 {
-			shell.State.Direction.X = velocityX / newSpeed
-			shell.State.Direction.Y = velocityY / newSpeed
-			shell.State.Direction.Z = velocityZ / newSpeed
-			shell.State.Speed = newSpeed
+		vx := shell.State.Direction.X * shell.State.Speed
+		vy := shell.State.Direction.Y * shell.State.Speed
+		vz := shell.State.Direction.Z * shell.State.Speed
+		pos := shell.State.Position
+
+		for i := 0; i < frames; i++ {
+			speed := math.Sqrt(vx*vx + vy*vy + vz*vz)
+
+			// Gravity increases slightly with velocity, drag opposes motion
+			vy -= shellGravity * (1 + speed*0.01)
+			if speed > 0 {
+				drag := shellDrag * speed
+				vx -= vx * drag
+				vy -= vy * drag
+				vz -= vz * drag
+			}
+			vx += shellWindPerAxis
+			vz += shellWindPerAxis
+
+			next := game.Position{X: pos.X + vx, Y: pos.Y + vy, Z: pos.Z + vz}
+
+			// Stop at the terrain; the sweep still covers the path down to it
+			above := pos.Y - pm.gameMap.GroundHeight(pos.X, pos.Z)
+			nextAbove := next.Y - pm.gameMap.GroundHeight(next.X, next.Z)
+			if nextAbove <= 0 {
+				t := 0.0
+				if above > 0 {
+					t = above / (above - nextAbove)
+				}
+				pos = game.Position{X: pos.X + vx*t, Z: pos.Z + vz*t}
+				pos.Y = pm.gameMap.GroundHeight(pos.X, pos.Z)
+				shell.Landed = true
+				break
+			}
+			pos = next
 		}
 
-		// Update position based on velocity for 100ms
-		shell.State.Position.X += shell.State.Direction.X * shell.State.Speed * 0.1
-		shell.State.Position.Y += shell.State.Direction.Y * shell.State.Speed * 0.1
-		shell.State.Position.Z += shell.State.Direction.Z * shell.State.Speed * 0.1
-
-		// Check if shell hit the ground
-		if shell.State.Position.Y <= 0 {
-			shell.State.Position.Y = 0 // Clamp to ground
-			// Shell hit the ground, mark it as hit
-			shell.Collider.Position = shell.State.Position
-		} else {
-			// Update shell collider position
-			shell.Collider.Position = shell.State.Position
+		if speed := math.Sqrt(vx*vx + vy*vy + vz*vz); speed > 0 {
+			shell.State.Direction = game.Position{X: vx / speed, Y: vy / speed, Z: vz / speed}
+			shell.State.Speed = speed
 		}
+		shell.State.Position = pos
+		shell.Collider.Position = pos
 	}
 }
 
@@ -394,49 +583,20 @@ func calculateDamage(hitLocation string) int {
 
 // lineSphereIntersection checks if a line intersects with a sphere
 func lineSphereIntersection(start, end, center game.Position, radius float64) bool {
-	// Convert to a ray intersection problem
-	// Direction vector of the ray
+	_, hit := game.SegmentSphereContact(start, end, center, radius)
+	return hit
+}
+
+// pointAlong returns the point at a distance along the segment from start to end
+func pointAlong(start, end game.Position, distance float64) game.Position {
 	dx := end.X - start.X
 	dy := end.Y - start.Y
 	dz := end.Z - start.Z
-
-	// Length of the ray
-	rayLength := math.Sqrt(dx*dx + dy*dy + dz*dz)
-	if rayLength < 0.001 {
-		// The ray has zero length, just check if start is inside the sphere
-		sx := start.X - center.X
-		sy := start.Y - center.Y
-		sz := start.Z - center.Z
-		return sx*sx+sy*sy+sz*sz <= radius*radius
-	}
-
-	// Normalize the direction vector
-	dx /= rayLength
-	dy /= rayLength
-	dz /= rayLength
-
-	// Vector from ray origin to sphere center
-	ox := start.X - center.X
-	oy := start.Y - center.Y
-	oz := start.Z - center.Z
-
-	// Calculate coefficients for quadratic equation
-	a := dx*dx + dy*dy + dz*dz // Always 1 for normalized ray
-	b := 2 * (ox*dx + oy*dy + oz*dz)
-	c := ox*ox + oy*oy + oz*oz - radius*radius
-
-	// Calculate discriminant
-	discriminant := b*b - 4*a*c
-
-	// If discriminant is negative, the ray doesn't intersect the sphere
-	if discriminant < 0 {
-		return false
+	length := math.Sqrt(dx*dx + dy*dy + dz*dz)
+	if length == 0 {
+		return start
 	}
 
-	// Calculate intersection points
-	t1 := (-b - math.Sqrt(discriminant)) / (2 * a)
-	t2 := (-b + math.Sqrt(discriminant)) / (2 * a)
-
-	// Check if any intersection point is within the line segment
-	return (t1 >= 0 && t1 <= rayLength) || (t2 >= 0 && t2 <= rayLength)
-}
\ No newline at end of file
+	t := distance / length
+	return game.Position{X: start.X + dx*t, Y: start.Y + dy*t, Z: start.Z + dz*t}
+}
@@ -1,6 +1,7 @@
 package game
 
 import (
+	"fmt"
 	"math"
 )
 
@@ -30,29 +31,30 @@ type Rock struct {
 	Scale     Position          `json:"scale"`
 	Radius    float64           `json:"radius"`
 	Formation RockFormationType `json:"formation,omitempty"`
+	HitPoints int               `json:"hitPoints,omitempty"` // Shell hits the rock takes before breaking, 0 if indestructible
 }
 
+// RockID identifies the rock at an index of the rock map
+func RockID(index int) string {
+	return fmt.Sprintf("rock_%d", index)
+}
+
+// ClusterRockHitPoints is how many shell hits the small rocks of a cluster take
+// before breaking. They are the only destructible cover on the map.
+const ClusterRockHitPoints = 3
+
 // RockMap holds all rocks in the game
 type RockMap struct {
 	Rocks []Rock `json:"rocks"`
 }
 
-// Initialize the rock map
-func InitRockMap() *RockMap {
-	rockMap := &RockMap{
-		Rocks: []Rock{},
-	}
-	generateRocks(rockMap)
-	return rockMap
-}
-
 // Update the GameMap to include rocks
 func (gm *GameMap) AddRocks(rockMap *RockMap) {
 	gm.Rocks = *rockMap
 }
 
 // Create a rock
-func createRock(rockMap *RockMap, size float64, deformSeed float64, x, y, z float64,
+func (g *mapGenerator) createRock(size float64, deformSeed float64, x, y, z float64,
 	rotation Position, scale Position, rockType RockType, colliderPosition *Position) Rock {
 
 	// Use the largest scale dimension to determine collision radius
@@ -76,12 +78,19 @@ func createRock(rockMap *RockMap, size float64, deformSeed float64, x, y, z floa
 		Radius:   collisionRadius,
 	}
 
-	rockMap.Rocks = append(rockMap.Rocks, rock)
+	g.addRock(rock)
 	return rock
 }
 
+// addRock adds a rock to the map unless it lies off the map
+func (g *mapGenerator) addRock(rock Rock) {
+	if g.inBounds(rock.Position.X, rock.Position.Z) {
+		g.rocks.Rocks = append(g.rocks.Rocks, rock)
+	}
+}
+
 // Create a rock cluster
-func createRockCluster(rockMap *RockMap, centerX, centerZ float64, seed int) {
+func (g *mapGenerator) createRockCluster(centerX, centerZ float64, seed int) {
 	// Create 5 rocks in a deterministic pattern
 	rockCount := 5
 
@@ -120,8 +129,8 @@ func createRockCluster(rockMap *RockMap, centerX, centerZ float64, seed int) {
 
 		// Create the rock
 		colliderPosition := Position{X: absX, Y: absY, Z: absZ}
-		createRock(
-			rockMap,
+		before := len(g.rocks.Rocks)
+		g.createRock(
 			0.5+math.Sin(float64(seed)+float64(i*7))*0.3, // Size
 			float64(seed)+float64(i),                     // Deform seed
 			x, y, z,                                      // Local Position
@@ -130,21 +139,45 @@ func createRockCluster(rockMap *RockMap, centerX, centerZ float64, seed int) {
 			rockType,
 			&colliderPosition,
 		)
+
+		// Rocks off the map are dropped
+		if len(g.rocks.Rocks) > before {
+			g.rocks.Rocks[before].HitPoints = ClusterRockHitPoints
+		}
+	}
+}
+
+// StoneCircleRadius is the radius of the ring of rocks in each stone circle
+const StoneCircleRadius = 50.0
+
+// stoneCircleOffset is how far the stone circles are from the centre along each
+// axis on the standard map
+const stoneCircleOffset = 500.0
+
+// stoneCircleCenters returns the positions of the stone circles on a map of the
+// given half size. They double as King of the Hill control zones.
+func stoneCircleCenters(halfSize float64) []Position {
+	offset := scaleToMap(stoneCircleOffset, halfSize)
+	return []Position{
+		{X: offset, Y: 0, Z: offset},
+		{X: -offset, Y: 0, Z: offset},
+		{X: offset, Y: 0, Z: -offset},
+		{X: -offset, Y: 0, Z: -offset},
 	}
 }
 
 // Create a stone circle
-func createStoneCircle(rockMap *RockMap, centerX, centerZ, radius float64, count, seed int) {
+func (g *mapGenerator) createStoneCircle(centerX, centerZ, radius float64, count, seed int) {
 	for i := 0; i < count; i++ {
 		angle := float64(i) / float64(count) * math.Pi * 2
 		x := centerX + math.Cos(angle)*radius
 		z := centerZ + math.Sin(angle)*radius
-		createRockCluster(rockMap, x, z, seed+i)
+		g.createRockCluster(x, z, seed+i)
 	}
 }
 
 // Create a rock spire
-func createRockSpire(rockMap *RockMap, x, z, height float64, seed int) {
+func (g *mapGenerator) createRockSpire(x, z, height float64, seed int) {
 	// Create a series of stacked rocks with decreasing size
 	segments := 8
 	baseSize := 2.0
@@ -168,8 +201,7 @@ func createRockSpire(rockMap *RockMap, x, z, height float64, seed int) {
 		}
 
 		// Create the rock with deterministic variation
-		createRock(
-			rockMap,
+		g.createRock(
 			segmentSize,
 			float64(seed+i),
 			xOffset, y, zOffset,
@@ -185,8 +217,7 @@ func createRockSpire(rockMap *RockMap, x, z, height float64, seed int) {
 	}
 
 	// Add a distinctive top piece
-	createRock(
-		rockMap,
+	g.createRock(
 		baseSize*0.3,
 		float64(seed+100),
 		0, height, 0,
@@ -260,12 +291,13 @@ func rockNoise2D(x, y float64, seed int) float64 {
 	return (result + 1) * 0.5
 }
 
-// Fractal Brownian Motion (fBm) specific for rocks
-func rockFbm(x, y float64, octaves int, lacunarity, persistence float64, seed int) float64 {
+// Fractal Brownian Motion (fBm) specific for rocks, seeded by the map seed
+func (g *mapGenerator) rockFbm(x, y float64, octaves int, lacunarity, persistence float64, seed int) float64 {
 	var total float64 = 0
-	frequency := 0.005 // Base frequency - controls pattern scale
+	frequency := baseNoiseFrequency / g.cfg.BiomeScale // Base frequency - controls pattern scale
 	amplitude := 1.0
 	var maxValue float64 = 0
+	seed = g.seeded(seed)
 
 	for i := 0; i < octaves; i++ {
 		// Add noise at current frequency and amplitude
@@ -290,16 +322,16 @@ type RockNoiseResult struct {
 }
 
 // Calculate rock formation density at a given position
-func rockNoiseValue(x, y float64, biomeScale float64, heightScale float64) RockNoiseResult {
+func (g *mapGenerator) rockNoiseValue(x, y float64, biomeScale float64, heightScale float64) RockNoiseResult {
 	// Use different seeds from tree noise to create distinct patterns
 	// Large-scale mountain ranges and geological features
-	mountainRangeNoise := rockFbm(x, y, 2, 2.0, 0.5, 234)
+	mountainRangeNoise := g.rockFbm(x, y, 2, 2.0, 0.5, 234)
 
 	// Medium-scale rock formations
-	formationNoise := rockFbm(x, y, 3, 2.2, 0.5, 567)
+	formationNoise := g.rockFbm(x, y, 3, 2.2, 0.5, 567)
 
 	// Small-scale rock clusters and details
-	clusterNoise := rockFbm(x, y, 4, 2.5, 0.6, 789)
+	clusterNoise := g.rockFbm(x, y, 4, 2.5, 0.6, 789)
 
 	// Combine noise layers with different weights
 	combinedNoise :=
@@ -311,15 +343,15 @@ func rockNoiseValue(x, y float64, biomeScale float64, heightScale float64) RockN
 	scaledNoise := combinedNoise * biomeScale
 
 	// Determine rock size based on noise
-	sizeNoise := rockFbm(x, y, 2, 2.0, 0.5, 987)
+	sizeNoise := g.rockFbm(x, y, 2, 2.0, 0.5, 987)
 	size := (0.7 + sizeNoise*1.3) * biomeScale
 
 	// Determine rock height based on separate noise
-	heightNoise := rockFbm(x, y, 3, 1.8, 0.6, 654)
+	heightNoise := g.rockFbm(x, y, 3, 1.8, 0.6, 654)
 	height := (0.5 + heightNoise*0.8) * heightScale
 
 	// Determine rock type based on position
-	typeNoise := rockFbm(x, y, 2, 2.5, 0.5, 321)
+	typeNoise := g.rockFbm(x, y, 2, 2.5, 0.5, 321)
 	rockType := StandardRock
 	if typeNoise > 0.5 {
 		rockType = StandardRock
@@ -336,9 +368,9 @@ func rockNoiseValue(x, y float64, biomeScale float64, heightScale float64) RockN
 }
 
 // Create a rock formation based on noise patterns
-func createRockFormationFromNoise(rockMap *RockMap, x, z, densityThreshold, biomeScale, heightScale float64, formationType RockFormationType) {
+func (g *mapGenerator) createRockFormationFromNoise(x, z, densityThreshold, biomeScale, heightScale float64, formationType RockFormationType) {
 	// Get noise value at this position
-	noise := rockNoiseValue(x, z, biomeScale, heightScale)
+	noise := g.rockNoiseValue(x, z, biomeScale, heightScale)
 
 	// Only place rocks where noise value exceeds threshold
 	if noise.Value > densityThreshold {
@@ -346,28 +378,27 @@ func createRockFormationFromNoise(rockMap *RockMap, x, z, densityThreshold, biom
 		seed := int(math.Floor((x*1000 + z) * noise.Value))
 
 		if formationType == ClusterFormation {
-			createRockCluster(rockMap, x, z, seed)
+			g.createRockCluster(x, z, seed)
 		} else if formationType == SpireFormation && noise.Value > densityThreshold+0.1 {
 			// For spires, use a higher threshold to make them more rare
 			spireHeight := 5 + noise.Height*15
-			createRockSpire(rockMap, x, z, spireHeight, seed)
+			g.createRockSpire(x, z, spireHeight, seed)
 		} else if formationType == MountainFormation && noise.Value > densityThreshold+0.2 {
 			// For mountains, use an even higher threshold
-			if rockFbm(x, z, 2, 2.0, 0.5, 111) > 0.75 {
+			if g.rockFbm(x, z, 2, 2.0, 0.5, 111) > 0.75 {
 				// Create a mountain peak
-				createRockMountainPeak(rockMap, x, z, 80+noise.Height*150, 40+noise.Size*60, seed)
-			} else if rockFbm(x, z, 2, 2.0, 0.5, 222) > 0.85 {
+				g.createRockMountainPeak(x, z, 80+noise.Height*150, 40+noise.Size*60, seed)
+			} else if g.rockFbm(x, z, 2, 2.0, 0.5, 222) > 0.85 {
 				// Create balanced rocks
-				createBalancedRocks(rockMap, x, z, 10+noise.Height*10, seed)
+				g.createBalancedRocks(x, z, 10+noise.Height*10, seed)
 			} else {
 				// Create a rock arch
-				createRockArch(
-					rockMap,
+				g.createRockArch(
 					x, z,
 					10+noise.Size*20,  // width
 					5+noise.Height*10, // height
 					5+noise.Size*10,   // depth
-					rockFbm(x, z, 1, 1.0, 0.5, 333)*math.Pi*2, // rotation
+					g.rockFbm(x, z, 1, 1.0, 0.5, 333)*math.Pi*2, // rotation
 					seed,
 				)
 			}
@@ -376,7 +407,7 @@ func createRockFormationFromNoise(rockMap *RockMap, x, z, densityThreshold, biom
 }
 
 // Create a mountain peak
-func createRockMountainPeak(rockMap *RockMap, x, z, height, radius float64, seed int) {
+func (g *mapGenerator) createRockMountainPeak(x, z, height, radius float64, seed int) {
 	// Add a collider for the mountain
 	colliderPosition := Position{X: x, Y: height * 0.5, Z: z}
 
@@ -391,14 +422,13 @@ func createRockMountainPeak(rockMap *RockMap, x, z, height, radius float64, seed
 		Formation: MountainFormation,
 	}
 
-	rockMap.Rocks = append(rockMap.Rocks, rock)
+	g.addRock(rock)
 }
 
 // Create balanced rocks
-func createBalancedRocks(rockMap *RockMap, x, z, height float64, seed int) {
+func (g *mapGenerator) createBalancedRocks(x, z, height float64, seed int) {
 	// Base rock - larger, flatter
-	createRock(
-		rockMap,
+	g.createRock(
 		3.0, // Size
 		float64(seed),
 		0, 1.5, 0, // Position
@@ -409,8 +439,7 @@ func createBalancedRocks(rockMap *RockMap, x, z, height float64, seed int) {
 	)
 
 	// Middle rock - medium sized, slightly offset
-	createRock(
-		rockMap,
+	g.createRock(
 		2.0, // Size
 		float64(seed+10),
 		math.Sin(float64(seed))*0.5, 3.0, math.Cos(float64(seed))*0.5, // Slight offset
@@ -425,8 +454,7 @@ func createBalancedRocks(rockMap *RockMap, x, z, height float64, seed int) {
 	)
 
 	// Top rock - smaller, more precariously balanced
-	createRock(
-		rockMap,
+	g.createRock(
 		1.5, // Size
 		float64(seed+20),
 		math.Sin(float64(seed+10))*0.8, 5.0, math.Cos(float64(seed+10))*0.8, // More offset
@@ -442,8 +470,7 @@ func createBalancedRocks(rockMap *RockMap, x, z, height float64, seed int) {
 
 	// Optional: extremely small rock on very top for dramatic effect
 	if math.Sin(float64(seed+30)) > 0 { // 50% chance based on seed
-		createRock(
-			rockMap,
+		g.createRock(
 			0.7, // Size
 			float64(seed+30),
 			math.Sin(float64(seed+20))*0.3, 6.0, math.Cos(float64(seed+20))*0.3,
@@ -460,7 +487,7 @@ func createBalancedRocks(rockMap *RockMap, x, z, height float64, seed int) {
 }
 
 // Create a rock arch
-func createRockArch(rockMap *RockMap, x, z, width, height, depth, rotation float64, seed int) {
+func (g *mapGenerator) createRockArch(x, z, width, height, depth, rotation float64, seed int) {
 	// Create a simplified representation of the arch
 	// Add colliders for the pillars
 	leftColliderPos := Position{
@@ -476,8 +503,7 @@ func createRockArch(rockMap *RockMap, x, z, width, height, depth, rotation float
 	}
 
 	// Left pillar
-	createRock(
-		rockMap,
+	g.createRock(
 		width*0.15, // Size based on arch width
 		float64(seed),
 		0, 0, 0, // Position - using collider position
@@ -488,8 +514,7 @@ func createRockArch(rockMap *RockMap, x, z, width, height, depth, rotation float
 	)
 
 	// Right pillar
-	createRock(
-		rockMap,
+	g.createRock(
 		width*0.15, // Size based on arch width
 		float64(seed+1),
 		0, 0, 0, // Position - using collider position
@@ -506,8 +531,7 @@ func createRockArch(rockMap *RockMap, x, z, width, height, depth, rotation float
 		Z: z,
 	}
 
-	createRock(
-		rockMap,
+	g.createRock(
 		width*0.4, // Size based on arch width
 		float64(seed+2),
 		0, 0, 0, // Position - using collider position
@@ -519,9 +543,9 @@ func createRockArch(rockMap *RockMap, x, z, width, height, depth, rotation float
 }
 
 // Create smaller individual rock based on noise
-func createSmallRockFromNoise(rockMap *RockMap, x, z, densityThreshold, biomeScale float64) {
+func (g *mapGenerator) createSmallRockFromNoise(x, z, densityThreshold, biomeScale float64) {
 	// Get noise value at this position
-	noise := rockNoiseValue(x, z, biomeScale, 1.0)
+	noise := g.rockNoiseValue(x, z, biomeScale, 1.0)
 
 	// Only place rocks where noise value exceeds threshold
 	if noise.Value > densityThreshold {
@@ -538,13 +562,12 @@ func createSmallRockFromNoise(rockMap *RockMap, x, z, densityThreshold, biomeSca
 		rotZ := math.Sin(float64(seed)*0.3) * math.Pi
 
 		// Scale variation
-		scaleX := 0.8 + rockFbm(x, z, 2, 2.0, 0.5, 444)*0.4
-		scaleY := 0.8 + rockFbm(x, z, 2, 2.0, 0.5, 555)*0.4
-		scaleZ := 0.8 + rockFbm(x, z, 2, 2.0, 0.5, 666)*0.4
+		scaleX := 0.8 + g.rockFbm(x, z, 2, 2.0, 0.5, 444)*0.4
+		scaleY := 0.8 + g.rockFbm(x, z, 2, 2.0, 0.5, 555)*0.4
+		scaleZ := 0.8 + g.rockFbm(x, z, 2, 2.0, 0.5, 666)*0.4
 
 		// Create the rock
-		createRock(
-			rockMap,
+		g.createRock(
 			size,
 			float64(seed),
 			x, y, z,
@@ -557,7 +580,7 @@ func createSmallRockFromNoise(rockMap *RockMap, x, z, densityThreshold, biomeSca
 }
 
 // Create a rock wall segment
-func createRockWall(rockMap *RockMap, startX, startZ, endX, endZ, height float64, seed int) {
+func (g *mapGenerator) createRockWall(startX, startZ, endX, endZ, height float64, seed int) {
 	// Calculate direction and length
 	dirX := endX - startX
 	dirZ := endZ - startZ
@@ -580,8 +603,7 @@ func createRockWall(rockMap *RockMap, startX, startZ, endX, endZ, height float64
 		segmentLength := length / float64(segments)
 
 		// Create a rock for each segment
-		createRock(
-			rockMap,
+		g.createRock(
 			segmentLength/2, // Size - radius covers half the segment length
 			float64(seed+i),
 			x, height/2, z, // Position
@@ -599,90 +621,91 @@ func createRockWall(rockMap *RockMap, startX, startZ, endX, endZ, height float64
 }
 
 // Generate all rocks in the game map
-func generateRocks(rockMap *RockMap) {
-	// 1. Rocks near the tank starting area
-	// Keep the deterministic circle of rocks for gameplay consistency
-	for i := 0; i < 8; i++ {
-		angle := float64(i) / 8.0 * math.Pi * 2
-		x := math.Cos(angle) * 20 // Closer to center than trees
-		z := math.Sin(angle) * 20
-		createRockCluster(rockMap, x, z, i)
-	}
-
-	// 2. Rock formations in geometric patterns
-	// Keep important gameplay landmarks
-
-	// Square formation at corners
-	for i := 0; i < 4; i++ {
-		x := -100.0
-		if i < 2 {
-			x = -100.0
-		} else {
-			x = 100.0
+func (g *mapGenerator) generateRocks() {
+	if !g.cfg.Landmarks.NoStartingArea {
+		// 1. Rocks near the tank starting area
+		// Keep the deterministic circle of rocks for gameplay consistency
+		for i := 0; i < 8; i++ {
+			angle := float64(i) / 8.0 * math.Pi * 2
+			x := math.Cos(angle) * 20 // Closer to center than trees
+			z := math.Sin(angle) * 20
+			g.createRockCluster(x, z, g.seeded(i))
 		}
 
-		z := -100.0
-		if i%2 == 0 {
-			z = -100.0
-		} else {
-			z = 100.0
-		}
+		// 2. Rock formations in geometric patterns
+		// Keep important gameplay landmarks
+
+		// Square formation at corners
+		for i := 0; i < 4; i++ {
+			x := -100.0
+			if i < 2 {
+				x = -100.0
+			} else {
+				x = 100.0
+			}
+
+			z := -100.0
+			if i%2 == 0 {
+				z = -100.0
+			} else {
+				z = 100.0
+			}
 
-		createRockCluster(rockMap, x, z, i+10)
+			g.createRockCluster(x, z, g.seeded(i+10))
+		}
 	}
 
 	// 3. Mountain Ranges and Rock Formations - using fractal noise patterns
 
 	// Northern mountain region
-	for x := -400.0; x <= 400.0; x += 30 {
-		for z := 280.0; z <= 400.0; z += 30 {
-			createRockFormationFromNoise(rockMap, x, z, 0.65, 1.2, 1.1, ClusterFormation)
+	for x := g.at(-400); x <= g.at(400); x += g.rockStep(30) {
+		for z := g.at(280); z <= g.at(400); z += g.rockStep(30) {
+			g.createRockFormationFromNoise(x, z, 0.65, 1.2, 1.1, ClusterFormation)
 		}
 	}
 
 	// Northern mountain peaks (more sparse)
-	for x := -350.0; x <= 350.0; x += 60 {
-		for z := 420.0; z <= 550.0; z += 60 {
-			createRockFormationFromNoise(rockMap, x, z, 0.7, 1.0, 1.2, MountainFormation)
+	for x := g.at(-350); x <= g.at(350); x += g.rockStep(60) {
+		for z := g.at(420); z <= g.at(550); z += g.rockStep(60) {
+			g.createRockFormationFromNoise(x, z, 0.7, 1.0, 1.2, MountainFormation)
 		}
 	}
 
 	// Eastern mountain region
-	for x := 280.0; x <= 400.0; x += 30 {
-		for z := -400.0; z <= 400.0; z += 30 {
-			createRockFormationFromNoise(rockMap, x, z, 0.65, 1.2, 1.1, ClusterFormation)
+	for x := g.at(280); x <= g.at(400); x += g.rockStep(30) {
+		for z := g.at(-400); z <= g.at(400); z += g.rockStep(30) {
+			g.createRockFormationFromNoise(x, z, 0.65, 1.2, 1.1, ClusterFormation)
 		}
 	}
 
 	// Eastern mountain peaks (more sparse)
-	for x := 420.0; x <= 550.0; x += 60 {
-		for z := -350.0; z <= 350.0; z += 60 {
-			createRockFormationFromNoise(rockMap, x, z, 0.7, 1.0, 1.2, MountainFormation)
+	for x := g.at(420); x <= g.at(550); x += g.rockStep(60) {
+		for z := g.at(-350); z <= g.at(350); z += g.rockStep(60) {
+			g.createRockFormationFromNoise(x, z, 0.7, 1.0, 1.2, MountainFormation)
 		}
 	}
 
 	// Southern rock region
-	for x := -400.0; x <= 400.0; x += 30 {
-		for z := -400.0; z >= -550.0; z -= 30 {
-			createRockFormationFromNoise(rockMap, x, z, 0.68, 0.9, 0.9, ClusterFormation)
+	for x := g.at(-400); x <= g.at(400); x += g.rockStep(30) {
+		for z := g.at(-400); z >= g.at(-550); z -= g.rockStep(30) {
+			g.createRockFormationFromNoise(x, z, 0.68, 0.9, 0.9, ClusterFormation)
 		}
 	}
 
 	// Western rock region
-	for x := -400.0; x >= -550.0; x -= 30 {
-		for z := -400.0; z <= 400.0; z += 30 {
-			createRockFormationFromNoise(rockMap, x, z, 0.68, 0.9, 0.9, ClusterFormation)
+	for x := g.at(-400); x >= g.at(-550); x -= g.rockStep(30) {
+		for z := g.at(-400); z <= g.at(400); z += g.rockStep(30) {
+			g.createRockFormationFromNoise(x, z, 0.68, 0.9, 0.9, ClusterFormation)
 		}
 	}
 
 	// Scattered rock spires in all regions
-	for x := -600.0; x <= 600.0; x += 150 {
-		for z := -600.0; z <= 600.0; z += 150 {
+	for x := g.at(-600); x <= g.at(600); x += g.rockStep(150) {
+		for z := g.at(-600); z <= g.at(600); z += g.rockStep(150) {
 			// Use a higher threshold to make them more rare
-			offsetX := rockFbm(x, z, 2, 2.0, 0.5, 777)*50 - 25
-			offsetZ := rockFbm(z, x, 2, 2.0, 0.5, 888)*50 - 25
-			createRockFormationFromNoise(
-				rockMap,
+			offsetX := g.rockFbm(x, z, 2, 2.0, 0.5, 777)*50 - 25
+			offsetZ := g.rockFbm(z, x, 2, 2.0, 0.5, 888)*50 - 25
+			g.createRockFormationFromNoise(
 				x+offsetX,
 				z+offsetZ,
 				0.75, 0.8, 1.3, SpireFormation,
@@ -691,24 +714,24 @@ func generateRocks(rockMap *RockMap) {
 	}
 
 	// 4. Stone Circles - ceremonial-looking formations at key locations (preserved for gameplay)
-	createStoneCircle(rockMap, 500, 500, 50, 12, 400)
-	createStoneCircle(rockMap, -500, 500, 50, 12, 500)
-	createStoneCircle(rockMap, 500, -500, 50, 12, 600)
-	createStoneCircle(rockMap, -500, -500, 50, 12, 700)
+	if !g.cfg.Landmarks.NoStoneCircles {
+		for i, center := range stoneCircleCenters(g.halfSize) {
+			g.createStoneCircle(center.X, center.Z, StoneCircleRadius, 12, g.seeded(400+i*100))
+		}
+	}
 
 	// 5. Scattered small rocks throughout the map using noise pattern
-	gridSize := 100.0 // Size of the grid for small rock distribution
-	for x := -800.0; x <= 800.0; x += gridSize {
-		for z := -800.0; z <= 800.0; z += gridSize {
+	gridSize := g.rockStep(100) // Size of the grid for small rock distribution
+	for x := g.at(-800); x <= g.at(800); x += gridSize {
+		for z := g.at(-800); z <= g.at(800); z += gridSize {
 			// For each grid cell, place several potential rocks
 			for i := 0; i < 5; i++ {
 				// Use noise to offset position within grid cell
-				offsetX := rockFbm(x+float64(i), z, 2, 2.0, 0.5, 999+i) * gridSize
-				offsetZ := rockFbm(x, z+float64(i), 2, 2.0, 0.5, 1000+i) * gridSize
+				offsetX := g.rockFbm(x+float64(i), z, 2, 2.0, 0.5, 999+i) * gridSize
+				offsetZ := g.rockFbm(x, z+float64(i), 2, 2.0, 0.5, 1000+i) * gridSize
 
 				// Create small rock if noise value high enough
-				createSmallRockFromNoise(
-					rockMap,
+				g.createSmallRockFromNoise(
 					x+offsetX,
 					z+offsetZ,
 					0.72, // High threshold for sparse distribution
@@ -720,14 +743,14 @@ func generateRocks(rockMap *RockMap) {
 
 	// 9. Rock ridge lines for more interesting topography
 	// Create ridge lines using noise to determine location and properties
-	for x := -600.0; x <= 600.0; x += 200 {
-		for z := -600.0; z <= 600.0; z += 200 {
+	for x := g.at(-600); x <= g.at(600); x += g.rockStep(200) {
+		for z := g.at(-600); z <= g.at(600); z += g.rockStep(200) {
 			// Only place ridge if noise value high enough
-			ridgeNoise := rockFbm(x, z, 3, 2.0, 0.5, 123)
+			ridgeNoise := g.rockFbm(x, z, 3, 2.0, 0.5, 123)
 			if ridgeNoise > 0.6 {
 				// Use noise to determine ridge direction and length
-				angle := rockFbm(x, z, 2, 2.0, 0.5, 456) * math.Pi * 2
-				length := 50 + rockFbm(x, z, 2, 2.0, 0.5, 789)*100
+				angle := g.rockFbm(x, z, 2, 2.0, 0.5, 456) * math.Pi * 2
+				length := 50 + g.rockFbm(x, z, 2, 2.0, 0.5, 789)*100
 
 				// Calculate start and end points
 				startX := x - math.Cos(angle)*length/2
@@ -736,10 +759,10 @@ func generateRocks(rockMap *RockMap) {
 				endZ := z + math.Sin(angle)*length/2
 
 				// Height based on noise
-				height := 5 + rockFbm(x, z, 2, 2.0, 0.5, 321)*10
+				height := 5 + g.rockFbm(x, z, 2, 2.0, 0.5, 321)*10
 
 				// Create the rock wall
-				createRockWall(rockMap, startX, startZ, endX, endZ, height, int(math.Floor(x*z)))
+				g.createRockWall(startX, startZ, endX, endZ, height, int(math.Floor(x*z)))
 			}
 		}
 	}
@@ -0,0 +1,74 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/charmbracelet/log"
+	"github.com/nats-io/nats.go"
+	"tank-game/game"
+)
+
+// eventPrefix is the NATS subject prefix for room match events, e.g. "events.main"
+const eventPrefix = "events."
+
+// eventBuffer is the number of events queued per subscriber before new ones are dropped
+const eventBuffer = 256
+
+// EventSubject returns the NATS subject a room's match events are published on
+func EventSubject(id string) string {
+	return eventPrefix + id
+}
+
+// roomEvents publishes a room's match events on its subject
+type roomEvents struct {
+	nc      *nats.Conn
+	subject string
+}
+
+func (e roomEvents) PublishEvent(event game.MatchEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Error("Error encoding match event", "subject", e.subject, "error", err)
+		return
+	}
+	// Publish only buffers the message, so it doesn't hold up the tick
+	if err := e.nc.Publish(e.subject, data); err != nil {
+		log.Error("Error publishing match event", "subject", e.subject, "error", err)
+	}
+}
+
+// WatchEvents subscribes to the room's match events until the context is done.
+// Events arrive in the order they happened; a subscriber that falls behind
+// misses events rather than slowing down the others.
+func (r *Room) WatchEvents(ctx context.Context) (<-chan game.MatchEvent, error) {
+	events := make(chan game.MatchEvent, eventBuffer)
+	if r.nc == nil {
+		return events, nil
+	}
+
+	sub, err := r.nc.Subscribe(EventSubject(r.ID), func(msg *nats.Msg) {
+		var event game.MatchEvent
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			log.Error("Error decoding match event", "room", r.ID, "error", err)
+			return
+		}
+		select {
+		case events <- event:
+		default:
+			log.Warn("Match event subscriber is behind, dropping event", "room", r.ID, "type", event.Type)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		if err := sub.Unsubscribe(); err != nil {
+			log.Error("Error unsubscribing from match events", "room", r.ID, "error", err)
+		}
+	}()
+
+	return events, nil
+}
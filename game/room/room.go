@@ -0,0 +1,628 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"tank-game/game"
+	"tank-game/game/physics"
+)
+
+// DefaultRoomID is the room players join from the index page
+const DefaultRoomID = "main"
+
+// keyPrefix is the KV key prefix for room game states, e.g. "rooms.main"
+const keyPrefix = "rooms."
+
+// MaxNPCs caps the number of NPC tanks per room to prevent performance issues
+const MaxNPCs = 10
+
+// DefaultMaxRoomsPerOwner is how many rooms one player may have running at a time
+const DefaultMaxRoomsPerOwner = 2
+
+// Player room reaping
+const (
+	reapInterval   = 30 * time.Second // How often player rooms are checked for players
+	emptyRoomGrace = 2 * time.Minute  // How long a player room may stay without players
+)
+
+// validRoomID restricts room IDs to characters that are safe in a KV key token
+var validRoomID = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// Errors returned by the registry
+var (
+	ErrRoomExists   = errors.New("room already exists")
+	ErrRoomNotFound = errors.New("room not found")
+	ErrTooManyRooms = errors.New("room limit reached")
+	ErrOwnerLimit   = errors.New("room limit per player reached")
+	ErrDefaultRoom  = errors.New("the default room cannot be removed")
+	ErrInvalidID    = errors.New("room ID must be 1-32 characters of a-z, 0-9, _ or -")
+	ErrUnknownMap   = errors.New("unknown map")
+	ErrInvalidMap   = errors.New("invalid map config")
+)
+
+// Config holds the settings a room is created with
+type Config struct {
+	Name      string               `json:"name"`
+	TickRate  int                  `json:"tickRate"`
+	NumNPCs   int                  `json:"npcs"`
+	Mode      game.ModeConfig      `json:"mode"`
+	Lifecycle game.LifecycleConfig `json:"lifecycle"`
+	Map       game.MapConfig       `json:"map"`     // Generation settings of the default map
+	MapName   string               `json:"mapName"` // Map file to play, or game.DefaultMapName for a generated map
+	Owner     string               `json:"owner"`   // User who created the room, empty for server rooms
+}
+
+// Info is the public summary of a room shown in room listings
+type Info struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Mode       game.GameModeType `json:"mode"`
+	Phase      game.MatchPhase   `json:"phase"`
+	Map        string            `json:"map"`
+	Players    int               `json:"players"`
+	NPCs       int               `json:"npcs"`
+	Spectators int               `json:"spectators"`
+	TickRate   int               `json:"tickRate"`
+	Owner      string            `json:"owner,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt"`
+}
+
+// Room is one match arena with its own simulation, physics, NPCs and map
+type Room struct {
+	ID        string
+	Name      string
+	Owner     string // User who created the room, empty for server rooms
+	CreatedAt time.Time
+	Manager   *game.Manager
+	NPCs      *game.NPCController
+	Physics   *physics.PhysicsIntegration
+
+	kv     jetstream.KeyValue
+	nc     *nats.Conn // Match events are published on, nil if they are not broadcast
+	cancel context.CancelFunc
+}
+
+// KeyFor returns the KV key a room's game state is stored under
+func KeyFor(id string) string {
+	return keyPrefix + id
+}
+
+// roomRecorder tags match results with the room that played them
+type roomRecorder struct {
+	roomID   string
+	recorder game.MatchRecorder
+}
+
+func (r roomRecorder) RecordMatch(result game.MatchResult) error {
+	result.Room = r.roomID
+	return r.recorder.RecordMatch(result)
+}
+
+// newRoom creates and starts all components of a room
+func newRoom(ctx context.Context, kv jetstream.KeyValue, nc *nats.Conn, stats Stats, replays Replays, id string, cfg Config, gameMap *game.GameMap, loader game.MapLoader) (*Room, error) {
+	roomCtx, cancel := context.WithCancel(ctx)
+
+	manager, err := game.NewManager(roomCtx, kv, KeyFor(id), gameMap, cfg.TickRate)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to initialize game manager: %v", err)
+	}
+
+	// Apply the game mode before anyone joins so teams are assigned from the start
+	if err := manager.SetMode(cfg.Mode); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to set game mode: %v", err)
+	}
+	manager.SetLifecycle(cfg.Lifecycle)
+	manager.SetMapLoader(loader)
+	if stats != nil {
+		manager.SetMatchRecorder(roomRecorder{roomID: id, recorder: stats})
+		manager.SetStatsRecorder(stats)
+	}
+	if replays != nil {
+		manager.SetReplayRecorder(replays.RecordRoom(roomCtx, id))
+	}
+	if nc != nil {
+		manager.SetEventPublisher(roomEvents{nc: nc, subject: EventSubject(id)})
+	}
+
+	// Physics and NPCs run as phases of the room's tick
+	physicsManager := physics.NewVuPhysicsManager(gameMap, manager)
+	physicsIntegration := physics.NewPhysicsIntegration(manager, gameMap, physicsManager)
+	physicsIntegration.Start()
+
+	npcController := game.NewNPCController(manager, gameMap, physicsManager)
+	npcController.Start()
+
+	// Both follow the room onto the next map of its rotation
+	manager.AddMapListener(physicsIntegration)
+	manager.AddMapListener(npcController)
+
+	room := &Room{
+		ID:        id,
+		Name:      cfg.Name,
+		Owner:     cfg.Owner,
+		CreatedAt: time.Now(),
+		Manager:   manager,
+		NPCs:      npcController,
+		Physics:   physicsIntegration,
+		kv:        kv,
+		nc:        nc,
+		cancel:    cancel,
+	}
+
+	room.spawnNPCs(cfg.NumNPCs)
+
+	return room, nil
+}
+
+// Map returns the map the room is playing
+func (r *Room) Map() *game.GameMap {
+	return r.Manager.GameMap()
+}
+
+// spawnNPCs adds NPC tanks with random movement patterns
+func (r *Room) spawnNPCs(count int) {
+	movementPatterns := []game.MovementPattern{
+		game.CircleMovement,
+		game.ZigzagMovement,
+		game.PatrolMovement,
+		game.RandomMovement,
+	}
+
+	for i := 0; i < count; i++ {
+		movementPattern := movementPatterns[rand.Intn(len(movementPatterns))]
+		r.NPCs.SpawnNPC("Bot", movementPattern)
+		log.Debug("Spawned NPC", "room", r.ID, "count", fmt.Sprintf("%d/%d", i+1, count), "pattern", movementPattern)
+	}
+}
+
+// Backfill adds NPC tanks of the given difficulty (0.0-1.0) to fill empty player slots.
+// The room never holds more than MaxNPCs; the number actually spawned is returned.
+func (r *Room) Backfill(count int, difficulty float64) int {
+	movementPatterns := []game.MovementPattern{
+		game.CircleMovement,
+		game.ZigzagMovement,
+		game.PatrolMovement,
+		game.RandomMovement,
+	}
+
+	if free := MaxNPCs - len(r.NPCs.GetActiveNPCs()); count > free {
+		count = free
+	}
+
+	for i := 0; i < count; i++ {
+		movementPattern := movementPatterns[rand.Intn(len(movementPatterns))]
+		r.NPCs.SpawnCustomNPC(game.GenerateNPCName(), movementPattern, difficulty)
+	}
+
+	if count > 0 {
+		log.Info("Backfilled room with NPCs", "room", r.ID, "count", count, "difficulty", difficulty)
+	}
+	return max(count, 0)
+}
+
+// Info returns a summary of the room for listings
+func (r *Room) Info() Info {
+	state := r.Manager.GetState()
+	npcs := len(r.NPCs.GetActiveNPCs())
+
+	return Info{
+		ID:         r.ID,
+		Name:       r.Name,
+		Mode:       state.Match.Mode,
+		Phase:      state.Match.Phase,
+		Map:        state.Match.Map,
+		Players:    len(state.Players) - npcs,
+		NPCs:       npcs,
+		Spectators: state.Spectators,
+		TickRate:   r.Manager.TickRate(),
+		Owner:      r.Owner,
+		CreatedAt:  r.CreatedAt,
+	}
+}
+
+// close stops the room's simulation and removes its state from KV
+func (r *Room) close() error {
+	r.NPCs.Stop()
+	r.Physics.Stop()
+	r.cancel()
+
+	if err := r.kv.Purge(context.Background(), KeyFor(r.ID)); err != nil {
+		return fmt.Errorf("failed to purge room state: %v", err)
+	}
+	return nil
+}
+
+// Registry owns all running rooms
+type Registry struct {
+	ctx      context.Context
+	kv       jetstream.KeyValue
+	nc       *nats.Conn
+	stats    Stats
+	replays  Replays
+	defaults Config
+	maxRooms int
+	maxOwned int // Rooms one player may own, 0 for no limit
+	mutex    sync.RWMutex
+	rooms    map[string]*Room
+	maps     map[string]*game.MapFile // Map files rooms can be created with, by name
+}
+
+// Stats persists match results and career stats for every room
+type Stats interface {
+	game.MatchRecorder
+	game.StatsRecorder
+}
+
+// Replays records the matches of every room for playback
+type Replays interface {
+	// RecordRoom returns the recorder of a room, which stops when the context is done
+	RecordRoom(ctx context.Context, roomID string) game.ReplayRecorder
+}
+
+// NewRegistry creates a room registry. Room state left in KV by a previous run is purged.
+// Finished matches and combat stats in every room are persisted through stats, if set.
+func NewRegistry(ctx context.Context, kv jetstream.KeyValue, stats Stats, defaults Config, maxRooms int) (*Registry, error) {
+	if err := game.ValidateTickRate(defaults.TickRate); err != nil {
+		return nil, err
+	}
+
+	registry := &Registry{
+		ctx:      ctx,
+		kv:       kv,
+		stats:    stats,
+		defaults: defaults,
+		maxRooms: maxRooms,
+		maxOwned: DefaultMaxRoomsPerOwner,
+		rooms:    make(map[string]*Room),
+		maps:     make(map[string]*game.MapFile),
+	}
+
+	if err := registry.purgeStale(); err != nil {
+		return nil, err
+	}
+
+	return registry, nil
+}
+
+// purgeStale removes room states left over from a previous run
+func (reg *Registry) purgeStale() error {
+	lister, err := reg.kv.ListKeysFiltered(reg.ctx, keyPrefix+">")
+	if err != nil {
+		if errors.Is(err, jetstream.ErrNoKeysFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to list room keys: %v", err)
+	}
+	defer lister.Stop()
+
+	for key := range lister.Keys() {
+		if err := reg.kv.Purge(reg.ctx, key); err != nil {
+			return fmt.Errorf("failed to purge stale room %s: %v", key, err)
+		}
+		log.Debug("Purged stale room state", "key", key)
+	}
+
+	return nil
+}
+
+// Create starts a new room. An empty ID generates a random one and zero config values use the defaults.
+func (reg *Registry) Create(id string, cfg Config) (*Room, error) {
+	if id == "" {
+		id = fmt.Sprintf("%06x", rand.Intn(0x1000000))
+	}
+	if !validRoomID.MatchString(id) {
+		return nil, ErrInvalidID
+	}
+
+	// Fill in defaults
+	if cfg.Name == "" {
+		cfg.Name = id
+	}
+	if cfg.TickRate == 0 {
+		cfg.TickRate = reg.defaults.TickRate
+	}
+	if err := game.ValidateTickRate(cfg.TickRate); err != nil {
+		return nil, err
+	}
+	if cfg.Mode.Type == "" {
+		cfg.Mode = reg.defaults.Mode
+	}
+	if cfg.Lifecycle.MinPlayers == 0 {
+		cfg.Lifecycle.MinPlayers = reg.defaults.Lifecycle.MinPlayers
+	}
+	if cfg.Lifecycle.WarmupTime == 0 {
+		cfg.Lifecycle.WarmupTime = reg.defaults.Lifecycle.WarmupTime
+	}
+	if cfg.Lifecycle.PostMatchTime == 0 {
+		cfg.Lifecycle.PostMatchTime = reg.defaults.Lifecycle.PostMatchTime
+	}
+	if cfg.Map == (game.MapConfig{}) {
+		cfg.Map = reg.defaults.Map
+	}
+	if len(cfg.Lifecycle.Maps) == 0 {
+		cfg.Lifecycle.Maps = reg.defaults.Lifecycle.Maps
+	}
+	if cfg.MapName == "" {
+		cfg.MapName = reg.defaults.MapName
+	}
+	cfg.Lifecycle.Maps = rotationFrom(cfg.MapName, cfg.Lifecycle.Maps)
+	cfg.MapName = cfg.Lifecycle.Maps[0]
+	for _, name := range cfg.Lifecycle.Maps {
+		if err := reg.checkMap(name); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := game.NewGameMode(cfg.Mode); err != nil {
+		return nil, err
+	}
+	if cfg.NumNPCs < 0 {
+		cfg.NumNPCs = 0
+	}
+	if cfg.NumNPCs > MaxNPCs {
+		log.Warn("Requested NPCs exceeds maximum limit", "requested", cfg.NumNPCs, "max", MaxNPCs, "using", MaxNPCs)
+		cfg.NumNPCs = MaxNPCs
+	}
+
+	if err := cfg.Map.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMap, err)
+	}
+
+	// Check the room can be added before spending time on its map
+	reg.mutex.RLock()
+	err := reg.checkAvailable(id, cfg.Owner)
+	reg.mutex.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	gameMap, err := reg.buildMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+
+	// Another room may have been created while the map was built
+	if err := reg.checkAvailable(id, cfg.Owner); err != nil {
+		return nil, err
+	}
+
+	// The rest of the rotation is built from the same config, with its own map name
+	loader := func(name string) (*game.GameMap, error) {
+		next := cfg
+		next.MapName = name
+		return reg.buildMap(next)
+	}
+
+	room, err := newRoom(reg.ctx, reg.kv, reg.nc, reg.stats, reg.replays, id, cfg, gameMap, loader)
+	if err != nil {
+		return nil, err
+	}
+	reg.rooms[id] = room
+
+	log.Info("Room created", "id", id, "name", cfg.Name, "mode", cfg.Mode.Type, "tickRate", cfg.TickRate, "npcs", cfg.NumNPCs, "map", cfg.MapName, "seed", cfg.Map.Seed, "owner", cfg.Owner)
+	return room, nil
+}
+
+// checkAvailable returns an error if the ID is taken, the room limit reached or
+// the owner already has as many rooms as allowed. Caller must hold the mutex.
+func (reg *Registry) checkAvailable(id, owner string) error {
+	if _, exists := reg.rooms[id]; exists {
+		return ErrRoomExists
+	}
+	if reg.maxRooms > 0 && len(reg.rooms) >= reg.maxRooms {
+		return ErrTooManyRooms
+	}
+	if owner != "" && reg.maxOwned > 0 {
+		owned := 0
+		for _, room := range reg.rooms {
+			if room.Owner == owner {
+				owned++
+			}
+		}
+		if owned >= reg.maxOwned {
+			return ErrOwnerLimit
+		}
+	}
+	return nil
+}
+
+// rotationFrom returns the map rotation of a room starting with its first map.
+// A first map outside the rotation is played before it; without either the
+// room plays the generated default map.
+func rotationFrom(first string, maps []string) []string {
+	if first == "" {
+		if len(maps) > 0 {
+			return maps
+		}
+		return []string{game.DefaultMapName}
+	}
+
+	for i, name := range maps {
+		if name == first {
+			return append(append([]string{}, maps[i:]...), maps[:i]...)
+		}
+	}
+	return append([]string{first}, maps...)
+}
+
+// checkMap returns an error if there is no map with the given name
+func (reg *Registry) checkMap(name string) error {
+	if name == game.DefaultMapName {
+		return nil
+	}
+
+	reg.mutex.RLock()
+	_, exists := reg.maps[name]
+	reg.mutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrUnknownMap, name)
+	}
+	return nil
+}
+
+// buildMap creates the map a room plays on: the named map file, or a map
+// generated from the room's map config
+func (reg *Registry) buildMap(cfg Config) (*game.GameMap, error) {
+	if cfg.MapName == "" || cfg.MapName == game.DefaultMapName {
+		return game.GenerateMap(cfg.Map), nil
+	}
+
+	reg.mutex.RLock()
+	file, exists := reg.maps[cfg.MapName]
+	reg.mutex.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMap, cfg.MapName)
+	}
+	return file.Build(), nil
+}
+
+// SetMaps replaces the map files rooms can be created with
+func (reg *Registry) SetMaps(maps map[string]*game.MapFile) {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+
+	reg.maps = maps
+}
+
+// SetReplays sets where the matches of rooms created from now on are recorded
+func (reg *Registry) SetReplays(replays Replays) {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+
+	reg.replays = replays
+}
+
+// SetEvents sets the NATS connection the match events of rooms created from now on
+// are published on
+func (reg *Registry) SetEvents(nc *nats.Conn) {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+
+	reg.nc = nc
+}
+
+// Maps returns the names of all maps rooms can be created with, the generated map first
+func (reg *Registry) Maps() []string {
+	reg.mutex.RLock()
+	defer reg.mutex.RUnlock()
+
+	return append([]string{game.DefaultMapName}, game.MapNames(reg.maps)...)
+}
+
+// SetMaxRoomsPerOwner sets how many rooms one player may have running, 0 for no limit
+func (reg *Registry) SetMaxRoomsPerOwner(max int) {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+
+	reg.maxOwned = max
+}
+
+// Defaults returns the config used for values not set when creating a room
+func (reg *Registry) Defaults() Config {
+	return reg.defaults
+}
+
+// Get returns a room by ID
+func (reg *Registry) Get(id string) (*Room, bool) {
+	reg.mutex.RLock()
+	defer reg.mutex.RUnlock()
+
+	room, exists := reg.rooms[id]
+	return room, exists
+}
+
+// List returns a summary of every room, oldest first
+func (reg *Registry) List() []Info {
+	reg.mutex.RLock()
+	rooms := make([]*Room, 0, len(reg.rooms))
+	for _, room := range reg.rooms {
+		rooms = append(rooms, room)
+	}
+	reg.mutex.RUnlock()
+
+	infos := make([]Info, 0, len(rooms))
+	for _, room := range rooms {
+		infos = append(infos, room.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].CreatedAt.Before(infos[j].CreatedAt)
+	})
+
+	return infos
+}
+
+// Remove stops a room and deletes its state. The default room is never removed.
+func (reg *Registry) Remove(id string) error {
+	if id == DefaultRoomID {
+		return ErrDefaultRoom
+	}
+
+	reg.mutex.Lock()
+	room, exists := reg.rooms[id]
+	if exists {
+		delete(reg.rooms, id)
+	}
+	reg.mutex.Unlock()
+
+	if !exists {
+		return ErrRoomNotFound
+	}
+
+	log.Info("Room removed", "id", id)
+	return room.close()
+}
+
+// Run closes rooms created by players once they are left empty, until the context is cancelled
+func (reg *Registry) Run(ctx context.Context) {
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			reg.reap(now)
+		}
+	}
+}
+
+// reap closes player rooms past their grace period that have no players
+func (reg *Registry) reap(now time.Time) {
+	reg.mutex.RLock()
+	var expired []*Room
+	for _, room := range reg.rooms {
+		if room.Owner != "" && now.Sub(room.CreatedAt) > emptyRoomGrace {
+			expired = append(expired, room)
+		}
+	}
+	reg.mutex.RUnlock()
+
+	for _, room := range expired {
+		if room.Info().Players > 0 {
+			continue
+		}
+
+		if err := reg.Remove(room.ID); err != nil {
+			if !errors.Is(err, ErrRoomNotFound) {
+				log.Error("Failed to remove empty player room", "room", room.ID, "error", err)
+			}
+			continue
+		}
+		log.Info("Closed empty player room", "room", room.ID, "owner", room.Owner)
+	}
+}
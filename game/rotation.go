@@ -0,0 +1,139 @@
+package game
+
+import "github.com/charmbracelet/log"
+
+// MapLoader builds a map of the rotation by name
+type MapLoader func(name string) (*GameMap, error)
+
+// MapListener is told when the room moves on to the next map of its rotation.
+// It is called from the tick without the manager's mutex held.
+type MapListener interface {
+	SetMap(gameMap *GameMap)
+}
+
+// loadedMap is the next map of the rotation, built in the background during the scoreboard
+type loadedMap struct {
+	index   int
+	gameMap *GameMap
+	err     error
+}
+
+// SetMapLoader sets how the maps of the rotation are built. Without a loader the
+// room stays on its first map.
+func (m *Manager) SetMapLoader(loader MapLoader) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.mapLoader = loader
+}
+
+// AddMapListener registers a component that has to follow map changes
+func (m *Manager) AddMapListener(listener MapListener) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.mapListeners = append(m.mapListeners, listener)
+}
+
+// GameMap returns the map the room is playing
+func (m *Manager) GameMap() *GameMap {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.gameMap
+}
+
+// rotates reports whether there is another map to move on to. Caller must hold the mutex.
+func (m *Manager) rotates() bool {
+	return m.mapLoader != nil && len(m.lifecycle.Maps) > 1
+}
+
+// loadNextMap starts building the next map of the rotation so it is ready when
+// the scoreboard ends. Caller must hold the mutex.
+func (m *Manager) loadNextMap() {
+	if !m.rotates() {
+		return
+	}
+
+	index := (m.mapIndex + 1) % len(m.lifecycle.Maps)
+	name := m.lifecycle.Maps[index]
+	loader := m.mapLoader
+	m.mapLoads++
+	load := m.mapLoads
+	m.nextMap = nil
+	m.loadingMap = true
+
+	go func() {
+		gameMap, err := loader(name)
+
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		// A newer load or a reconfigured rotation supersedes this one
+		if load == m.mapLoads && m.loadingMap {
+			m.nextMap = &loadedMap{index: index, gameMap: gameMap, err: err}
+		}
+	}()
+}
+
+// advanceMap moves the room onto the next map of the rotation once it has been
+// built. It returns false while the map is still being built. Caller must hold the mutex.
+func (m *Manager) advanceMap(now int64) bool {
+	if !m.loadingMap {
+		return true
+	}
+	next := m.nextMap
+	if next == nil {
+		return false
+	}
+	m.loadingMap = false
+	m.nextMap = nil
+
+	// The rotation may have been reconfigured while the map was built
+	if next.index >= len(m.lifecycle.Maps) {
+		return true
+	}
+	name := m.lifecycle.Maps[next.index]
+	if next.err != nil {
+		log.Error("Error building the next map, staying on the current one", "map", name, "error", next.err)
+		return true
+	}
+
+	m.mapIndex = next.index
+	m.gameMap = next.gameMap
+	m.state.Match.Map = name
+	m.changedMap = next.gameMap
+
+	// Nothing of the previous map carries over
+	m.state.Shells = []ShellState{}
+	m.state.Impacts = nil
+	m.restoreObstaclesLocked()
+	m.applyMapToMode(m.mode)
+	m.mode.Start(&m.state, now)
+
+	for id, player := range m.state.Players {
+		player.Position = m.randomSpawnPosition()
+		player.Velocity = 0
+		player.IsMoving = false
+		player.Timestamp = now
+		m.state.Players[id] = player
+	}
+
+	log.Info("Next map", "map", name)
+	return true
+}
+
+// notifyMapChange tells the listeners about a map change of the last lifecycle update
+func (m *Manager) notifyMapChange() {
+	m.mutex.Lock()
+	gameMap := m.changedMap
+	m.changedMap = nil
+	listeners := m.mapListeners
+	m.mutex.Unlock()
+
+	if gameMap == nil {
+		return
+	}
+	for _, listener := range listeners {
+		listener.SetMap(gameMap)
+	}
+}
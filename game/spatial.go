@@ -0,0 +1,334 @@
+package game
+
+import (
+	"math"
+)
+
+// DefaultGridCellSize is the cell edge length of the obstacle grid in world units.
+// The largest obstacles are about 4 units across, so most sit in a single cell.
+const DefaultGridCellSize = 25.0
+
+// Obstacle types in the grid
+const (
+	ObstacleTree = "tree"
+	ObstacleRock = "rock"
+)
+
+// GridObstacle is a tree or rock indexed by the spatial grid
+type GridObstacle struct {
+	Index     int      // Position in SpatialGrid.Obstacles, trees first and then rocks
+	ID        string   // TreeID or RockID of the obstacle
+	Type      string   // ObstacleTree or ObstacleRock
+	Position  Position // Centre of the collider
+	Radius    float64  // Collider radius
+	HitPoints int      // Shell hits before the obstacle breaks, 0 if indestructible
+}
+
+// gridCellRange is the block of cells an obstacle or query covers
+type gridCellRange struct {
+	minCol, minRow, maxCol, maxRow int
+}
+
+// SpatialGrid is a uniform grid over the ground plane indexing the static obstacles
+// of a map. It is built once and never modified, so it can be queried concurrently.
+// Queries work on the X/Z plane; callers needing exact 3D tests run them on the results.
+type SpatialGrid struct {
+	cellSize  float64
+	minX      float64
+	minZ      float64
+	cols      int
+	rows      int
+	cells     [][]int32       // Obstacle indices per cell, row-major
+	covers    []gridCellRange // Cells each obstacle was inserted into
+	obstacles []GridObstacle
+	maxRadius float64
+}
+
+// NewSpatialGrid indexes the trees and rocks of a map with the given cell size
+func NewSpatialGrid(gameMap *GameMap, cellSize float64) *SpatialGrid {
+	if cellSize <= 0 {
+		cellSize = DefaultGridCellSize
+	}
+
+	g := &SpatialGrid{cellSize: cellSize}
+	for i, tree := range gameMap.Trees.Trees {
+		g.obstacles = append(g.obstacles, GridObstacle{
+			ID:       TreeID(i),
+			Type:     ObstacleTree,
+			Position: tree.Position,
+			Radius:   tree.Radius,
+		})
+	}
+	for i, rock := range gameMap.Rocks.Rocks {
+		g.obstacles = append(g.obstacles, GridObstacle{
+			ID:        RockID(i),
+			Type:      ObstacleRock,
+			Position:  rock.Position,
+			Radius:    rock.Radius,
+			HitPoints: rock.HitPoints,
+		})
+	}
+
+	// Size the grid to the obstacles so any map fits
+	minX, minZ := math.Inf(1), math.Inf(1)
+	maxX, maxZ := math.Inf(-1), math.Inf(-1)
+	for i := range g.obstacles {
+		obstacle := &g.obstacles[i]
+		obstacle.Index = i
+		g.maxRadius = math.Max(g.maxRadius, obstacle.Radius)
+		minX = math.Min(minX, obstacle.Position.X-obstacle.Radius)
+		minZ = math.Min(minZ, obstacle.Position.Z-obstacle.Radius)
+		maxX = math.Max(maxX, obstacle.Position.X+obstacle.Radius)
+		maxZ = math.Max(maxZ, obstacle.Position.Z+obstacle.Radius)
+	}
+	if len(g.obstacles) == 0 {
+		minX, minZ, maxX, maxZ = 0, 0, 0, 0
+	}
+
+	g.minX = minX
+	g.minZ = minZ
+	g.cols = int((maxX-minX)/cellSize) + 1
+	g.rows = int((maxZ-minZ)/cellSize) + 1
+	g.cells = make([][]int32, g.cols*g.rows)
+	g.covers = make([]gridCellRange, len(g.obstacles))
+
+	for i, obstacle := range g.obstacles {
+		cover := g.cellRange(obstacle.Position.X-obstacle.Radius, obstacle.Position.Z-obstacle.Radius,
+			obstacle.Position.X+obstacle.Radius, obstacle.Position.Z+obstacle.Radius)
+		g.covers[i] = cover
+		for row := cover.minRow; row <= cover.maxRow; row++ {
+			for col := cover.minCol; col <= cover.maxCol; col++ {
+				cell := row*g.cols + col
+				g.cells[cell] = append(g.cells[cell], int32(i))
+			}
+		}
+	}
+
+	return g
+}
+
+// Obstacles returns every indexed obstacle, ordered by Index
+func (g *SpatialGrid) Obstacles() []GridObstacle {
+	return g.obstacles
+}
+
+// MaxRadius returns the radius of the largest obstacle
+func (g *SpatialGrid) MaxRadius() float64 {
+	return g.maxRadius
+}
+
+// cellRange returns the cells covering a rectangle, clamped to the grid
+func (g *SpatialGrid) cellRange(minX, minZ, maxX, maxZ float64) gridCellRange {
+	return gridCellRange{
+		minCol: g.clampCol(int(math.Floor((minX - g.minX) / g.cellSize))),
+		minRow: g.clampRow(int(math.Floor((minZ - g.minZ) / g.cellSize))),
+		maxCol: g.clampCol(int(math.Floor((maxX - g.minX) / g.cellSize))),
+		maxRow: g.clampRow(int(math.Floor((maxZ - g.minZ) / g.cellSize))),
+	}
+}
+
+func (g *SpatialGrid) clampCol(col int) int {
+	return max(0, min(g.cols-1, col))
+}
+
+func (g *SpatialGrid) clampRow(row int) int {
+	return max(0, min(g.rows-1, row))
+}
+
+// Near calls visit for every obstacle whose circle on the ground plane comes within
+// radius of center. Returning false from visit stops the query.
+func (g *SpatialGrid) Near(center Position, radius float64, visit func(obstacle *GridObstacle) bool) {
+	query := g.cellRange(center.X-radius, center.Z-radius, center.X+radius, center.Z+radius)
+
+	for row := query.minRow; row <= query.maxRow; row++ {
+		for col := query.minCol; col <= query.maxCol; col++ {
+			for _, index := range g.cells[row*g.cols+col] {
+				// An obstacle spanning several cells is only reported from the first
+				// cell it shares with the query
+				cover := g.covers[index]
+				if col != max(cover.minCol, query.minCol) || row != max(cover.minRow, query.minRow) {
+					continue
+				}
+
+				obstacle := &g.obstacles[index]
+				dx := obstacle.Position.X - center.X
+				dz := obstacle.Position.Z - center.Z
+				reach := radius + obstacle.Radius
+				if dx*dx+dz*dz > reach*reach {
+					continue
+				}
+				if !visit(obstacle) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Segment calls visit for every obstacle whose circle on the ground plane, grown by
+// padding, the segment from start to end passes through. Returning false from visit
+// stops the query.
+func (g *SpatialGrid) Segment(start, end Position, padding float64, visit func(obstacle *GridObstacle) bool) {
+	reach := padding + g.maxRadius
+	query := g.cellRange(math.Min(start.X, end.X)-reach, math.Min(start.Z, end.Z)-reach,
+		math.Max(start.X, end.X)+reach, math.Max(start.Z, end.Z)+reach)
+
+	// A cell can only hold a candidate if its centre is this close to the segment
+	cellReach := reach + g.cellSize*math.Sqrt2/2
+
+	for row := query.minRow; row <= query.maxRow; row++ {
+		for col := query.minCol; col <= query.maxCol; col++ {
+			centre := Position{
+				X: g.minX + (float64(col)+0.5)*g.cellSize,
+				Z: g.minZ + (float64(row)+0.5)*g.cellSize,
+			}
+			if segmentDistanceSq2D(start, end, centre) > cellReach*cellReach {
+				continue
+			}
+
+			for _, index := range g.cells[row*g.cols+col] {
+				// Report obstacles spanning several cells only from their first cell
+				// the query looks at
+				cover := g.covers[index]
+				if !g.firstVisitedCell(cover, query, col, row, start, end, cellReach) {
+					continue
+				}
+
+				obstacle := &g.obstacles[index]
+				limit := obstacle.Radius + padding
+				if segmentDistanceSq2D(start, end, obstacle.Position) > limit*limit {
+					continue
+				}
+				if !visit(obstacle) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// firstVisitedCell reports whether col/row is the first cell, in the scan order of
+// Segment, that both holds an obstacle with the given cover and is close enough to
+// the segment to be scanned
+func (g *SpatialGrid) firstVisitedCell(cover, query gridCellRange, col, row int, start, end Position, cellReach float64) bool {
+	for r := max(cover.minRow, query.minRow); r <= min(cover.maxRow, query.maxRow); r++ {
+		for c := max(cover.minCol, query.minCol); c <= min(cover.maxCol, query.maxCol); c++ {
+			if r == row && c == col {
+				return true
+			}
+			centre := Position{
+				X: g.minX + (float64(c)+0.5)*g.cellSize,
+				Z: g.minZ + (float64(r)+0.5)*g.cellSize,
+			}
+			if segmentDistanceSq2D(start, end, centre) <= cellReach*cellReach {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// Ray returns the nearest obstacle a ray from origin hits within maxDistance, treating
+// obstacles as spheres grown by padding, and how far along the ray it is hit
+func (g *SpatialGrid) Ray(origin, direction Position, maxDistance, padding float64) (*GridObstacle, float64, bool) {
+	length := math.Sqrt(direction.X*direction.X + direction.Y*direction.Y + direction.Z*direction.Z)
+	if length == 0 {
+		return nil, 0, false
+	}
+	end := Position{
+		X: origin.X + direction.X/length*maxDistance,
+		Y: origin.Y + direction.Y/length*maxDistance,
+		Z: origin.Z + direction.Z/length*maxDistance,
+	}
+
+	var nearest *GridObstacle
+	nearestDistance := math.Inf(1)
+	g.Segment(origin, end, padding, func(obstacle *GridObstacle) bool {
+		if distance, ok := SegmentSphereContact(origin, end, obstacle.Position, obstacle.Radius+padding); ok && distance < nearestDistance {
+			nearest = obstacle
+			nearestDistance = distance
+		}
+		return true
+	})
+
+	if nearest == nil {
+		return nil, 0, false
+	}
+	return nearest, nearestDistance, true
+}
+
+// segmentDistanceSq2D returns the squared distance on the ground plane from a point
+// to the segment between start and end
+func segmentDistanceSq2D(start, end, point Position) float64 {
+	dx := end.X - start.X
+	dz := end.Z - start.Z
+	px := point.X - start.X
+	pz := point.Z - start.Z
+
+	lengthSq := dx*dx + dz*dz
+	t := 0.0
+	if lengthSq > 0 {
+		t = math.Max(0, math.Min(1, (px*dx+pz*dz)/lengthSq))
+	}
+
+	ex := px - dx*t
+	ez := pz - dz*t
+	return ex*ex + ez*ez
+}
+
+// SegmentSphereContact returns how far along the segment from start to end it first
+// touches the sphere. A segment starting inside the sphere touches it at distance 0.
+func SegmentSphereContact(start, end, center Position, radius float64) (float64, bool) {
+	// Convert to a ray intersection problem
+	// Direction vector of the ray
+	dx := end.X - start.X
+	dy := end.Y - start.Y
+	dz := end.Z - start.Z
+
+	// Vector from ray origin to sphere center
+	ox := start.X - center.X
+	oy := start.Y - center.Y
+	oz := start.Z - center.Z
+	c := ox*ox + oy*oy + oz*oz - radius*radius
+
+	// Starting inside the sphere is an immediate contact
+	if c <= 0 {
+		return 0, true
+	}
+
+	// Length of the ray
+	rayLength := math.Sqrt(dx*dx + dy*dy + dz*dz)
+	if rayLength < 0.001 {
+		return 0, false
+	}
+
+	// Normalize the direction vector
+	dx /= rayLength
+	dy /= rayLength
+	dz /= rayLength
+
+	// Calculate coefficients for quadratic equation; a is 1 for a normalized ray
+	b := 2 * (ox*dx + oy*dy + oz*dz)
+
+	// If discriminant is negative, the ray doesn't intersect the sphere
+	discriminant := b*b - 4*c
+	if discriminant < 0 {
+		return 0, false
+	}
+
+	// The nearer intersection is where the segment enters the sphere
+	t := (-b - math.Sqrt(discriminant)) / 2
+	if t < 0 || t > rayLength {
+		return 0, false
+	}
+	return t, true
+}
+
+// Grid returns the spatial index of the map's obstacles, building it on first use.
+// The map must not gain or lose obstacles afterwards.
+func (gm *GameMap) Grid() *SpatialGrid {
+	gm.gridOnce.Do(func() {
+		gm.grid = NewSpatialGrid(gm, DefaultGridCellSize)
+	})
+	return gm.grid
+}
@@ -0,0 +1,301 @@
+package game
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// testGrid returns the grid of the standard map and a point on its cell edges
+func testGrid(t *testing.T) (*SpatialGrid, Position) {
+	t.Helper()
+	grid := GenerateMap(DefaultMapConfig()).Grid()
+	if len(grid.Obstacles()) == 0 {
+		t.Fatal("standard map has no obstacles")
+	}
+	edge := Position{X: grid.minX + 40*grid.cellSize, Y: 1, Z: grid.minZ + 40*grid.cellSize}
+	return grid, edge
+}
+
+// gridBounds returns the corners of the area the grid covers
+func gridBounds(grid *SpatialGrid) (Position, Position) {
+	return Position{X: grid.minX, Y: 1, Z: grid.minZ},
+		Position{X: grid.minX + float64(grid.cols)*grid.cellSize, Y: 1, Z: grid.minZ + float64(grid.rows)*grid.cellSize}
+}
+
+// collect runs a grid query and returns the sorted indices it visited, failing
+// on obstacles visited twice
+func collect(t *testing.T, query func(visit func(obstacle *GridObstacle) bool)) []int {
+	t.Helper()
+	seen := make(map[int]bool)
+	var indices []int
+	query(func(obstacle *GridObstacle) bool {
+		if seen[obstacle.Index] {
+			t.Errorf("obstacle %d visited twice", obstacle.Index)
+		}
+		seen[obstacle.Index] = true
+		indices = append(indices, obstacle.Index)
+		return true
+	})
+	sort.Ints(indices)
+	return indices
+}
+
+// scan returns the sorted indices of every obstacle matching a test
+func scan(grid *SpatialGrid, match func(obstacle *GridObstacle) bool) []int {
+	var indices []int
+	for i := range grid.Obstacles() {
+		if match(&grid.Obstacles()[i]) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+func equalIndices(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSpatialGridNear(t *testing.T) {
+	grid, edge := testGrid(t)
+	low, high := gridBounds(grid)
+
+	tests := []struct {
+		name   string
+		center Position
+		radius float64
+	}{
+		{"origin", Position{}, tankCollisionRadius},
+		{"zero radius", Position{X: 20, Z: 0}, 0},
+		{"cell corner", edge, tankCollisionRadius},
+		{"cell edge", Position{X: edge.X, Z: edge.Z + grid.cellSize/2}, grid.cellSize},
+		{"exactly one cell", edge, grid.cellSize / 2},
+		{"grid minimum corner", low, 50},
+		{"grid maximum corner", high, 50},
+		{"map edge", Position{X: mapHalfSize, Z: 0}, 100},
+		{"outside the grid", Position{X: -mapHalfSize * 2, Z: mapHalfSize * 2}, 10},
+		{"large radius", Position{X: 150, Z: -300}, 500},
+		{"whole map", Position{}, mapHalfSize * 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := collect(t, func(visit func(obstacle *GridObstacle) bool) {
+				grid.Near(tt.center, tt.radius, visit)
+			})
+			want := scan(grid, func(obstacle *GridObstacle) bool {
+				dx := obstacle.Position.X - tt.center.X
+				dz := obstacle.Position.Z - tt.center.Z
+				reach := tt.radius + obstacle.Radius
+				return dx*dx+dz*dz <= reach*reach
+			})
+			if !equalIndices(got, want) {
+				t.Errorf("Near found %d obstacles, linear scan %d", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestSpatialGridSegment(t *testing.T) {
+	grid, edge := testGrid(t)
+	low, high := gridBounds(grid)
+
+	tests := []struct {
+		name       string
+		start, end Position
+		padding    float64
+	}{
+		{"short", Position{X: -50, Z: -50}, Position{X: 50, Z: 80}, 0},
+		{"point", Position{X: 120, Z: 0}, Position{X: 120, Z: 0}, tankCollisionRadius},
+		{"along a cell edge", edge, Position{X: edge.X + 10*grid.cellSize, Z: edge.Z}, 0},
+		{"across a cell corner", Position{X: edge.X - 30, Z: edge.Z - 30}, Position{X: edge.X + 30, Z: edge.Z + 30}, 1},
+		{"diagonal of the grid", low, high, 0},
+		{"along the grid boundary", low, Position{X: high.X, Z: low.Z}, 5},
+		{"from outside the map", Position{X: -mapHalfSize, Z: 400}, Position{X: mapHalfSize, Z: 400}, 0},
+		{"large padding", Position{X: -200, Z: 300}, Position{X: 300, Z: -100}, 150},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := collect(t, func(visit func(obstacle *GridObstacle) bool) {
+				grid.Segment(tt.start, tt.end, tt.padding, visit)
+			})
+			want := scan(grid, func(obstacle *GridObstacle) bool {
+				limit := obstacle.Radius + tt.padding
+				return segmentDistanceSq2D(tt.start, tt.end, obstacle.Position) <= limit*limit
+			})
+			if !equalIndices(got, want) {
+				t.Errorf("Segment found %d obstacles, linear scan %d", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestSpatialGridRay(t *testing.T) {
+	grid, edge := testGrid(t)
+	low, _ := gridBounds(grid)
+
+	tests := []struct {
+		name        string
+		origin      Position
+		direction   Position
+		maxDistance float64
+		padding     float64
+	}{
+		{"from the centre", Position{Y: 1}, Position{X: 1}, 300, 0},
+		{"along a cell edge", edge, Position{Z: 1}, 20 * grid.cellSize, 0},
+		{"diagonal through cell corners", edge, Position{X: 1, Z: 1}, 500, 0.25},
+		{"climbing", Position{X: -100, Y: 1, Z: 100}, Position{X: 1, Y: 0.2, Z: -1}, 400, 0},
+		{"from the grid corner", low, Position{X: 1, Z: 1}, mapHalfSize * 3, 0},
+		{"from outside the map", Position{X: -mapHalfSize, Y: 1, Z: 30}, Position{X: 1}, mapHalfSize * 2, 0},
+		{"long range with padding", Position{X: 400, Y: 1, Z: -400}, Position{X: -1, Z: 0.5}, mapHalfSize * 2, 2},
+		{"zero direction", Position{}, Position{}, 100, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obstacle, distance, hit := grid.Ray(tt.origin, tt.direction, tt.maxDistance, tt.padding)
+
+			// Linear scan for the nearest contact along the same segment
+			wantDistance, wantHit := math.Inf(1), false
+			length := math.Sqrt(tt.direction.X*tt.direction.X + tt.direction.Y*tt.direction.Y + tt.direction.Z*tt.direction.Z)
+			if length > 0 {
+				end := Position{
+					X: tt.origin.X + tt.direction.X/length*tt.maxDistance,
+					Y: tt.origin.Y + tt.direction.Y/length*tt.maxDistance,
+					Z: tt.origin.Z + tt.direction.Z/length*tt.maxDistance,
+				}
+				for _, candidate := range grid.Obstacles() {
+					if d, ok := SegmentSphereContact(tt.origin, end, candidate.Position, candidate.Radius+tt.padding); ok && d < wantDistance {
+						wantDistance, wantHit = d, true
+					}
+				}
+			}
+
+			if hit != wantHit {
+				t.Fatalf("Ray hit %v, linear scan %v", hit, wantHit)
+			}
+			if hit && (obstacle == nil || distance != wantDistance) {
+				t.Errorf("Ray hit at %.3f, linear scan at %.3f", distance, wantDistance)
+			}
+		})
+	}
+}
+
+// benchmarkQueries returns reproducible query points and segments across the
+// part of the map that has obstacles
+func benchmarkQueries(count int) ([]Position, []Position) {
+	r := rand.New(rand.NewSource(1))
+	starts := make([]Position, count)
+	ends := make([]Position, count)
+	for i := range starts {
+		starts[i] = Position{X: r.Float64()*2000 - 1000, Y: 1, Z: r.Float64()*2000 - 1000}
+		// NPC sight lines and shell steps are at most a few hundred units long
+		ends[i] = Position{X: starts[i].X + r.Float64()*400 - 200, Y: 1, Z: starts[i].Z + r.Float64()*400 - 200}
+	}
+	return starts, ends
+}
+
+// BenchmarkNearScan is the linear scan over every obstacle that Near replaces
+func BenchmarkNearScan(b *testing.B) {
+	obstacles := GenerateMap(DefaultMapConfig()).Grid().Obstacles()
+	centers, _ := benchmarkQueries(1024)
+	b.ResetTimer()
+
+	found := 0
+	for i := 0; i < b.N; i++ {
+		center := centers[i%len(centers)]
+		for j := range obstacles {
+			dx := obstacles[j].Position.X - center.X
+			dz := obstacles[j].Position.Z - center.Z
+			reach := tankCollisionRadius + obstacles[j].Radius
+			if dx*dx+dz*dz <= reach*reach {
+				found++
+			}
+		}
+	}
+	_ = found
+}
+
+func BenchmarkNearGrid(b *testing.B) {
+	grid := GenerateMap(DefaultMapConfig()).Grid()
+	centers, _ := benchmarkQueries(1024)
+	b.ResetTimer()
+
+	found := 0
+	for i := 0; i < b.N; i++ {
+		grid.Near(centers[i%len(centers)], tankCollisionRadius, func(obstacle *GridObstacle) bool {
+			found++
+			return true
+		})
+	}
+	_ = found
+}
+
+// BenchmarkLineOfSightScan is the linear line-of-sight test over every obstacle
+func BenchmarkLineOfSightScan(b *testing.B) {
+	obstacles := GenerateMap(DefaultMapConfig()).Grid().Obstacles()
+	starts, ends := benchmarkQueries(1024)
+	b.ResetTimer()
+
+	blocked := 0
+	for i := 0; i < b.N; i++ {
+		start, end := starts[i%len(starts)], ends[i%len(ends)]
+		for j := range obstacles {
+			if _, hit := SegmentSphereContact(start, end, obstacles[j].Position, obstacles[j].Radius); hit {
+				blocked++
+				break
+			}
+		}
+	}
+	_ = blocked
+}
+
+func BenchmarkLineOfSightGrid(b *testing.B) {
+	grid := GenerateMap(DefaultMapConfig()).Grid()
+	starts, ends := benchmarkQueries(1024)
+	b.ResetTimer()
+
+	blocked := 0
+	for i := 0; i < b.N; i++ {
+		start, end := starts[i%len(starts)], ends[i%len(ends)]
+		grid.Segment(start, end, 0, func(obstacle *GridObstacle) bool {
+			if _, hit := SegmentSphereContact(start, end, obstacle.Position, obstacle.Radius); hit {
+				blocked++
+				return false
+			}
+			return true
+		})
+	}
+	_ = blocked
+}
+
+func BenchmarkRayGrid(b *testing.B) {
+	grid := GenerateMap(DefaultMapConfig()).Grid()
+	starts, ends := benchmarkQueries(1024)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		start, end := starts[i%len(starts)], ends[i%len(ends)]
+		direction := Position{X: end.X - start.X, Z: end.Z - start.Z}
+		grid.Ray(start, direction, 300, 0.25)
+	}
+}
+
+func BenchmarkNewSpatialGrid(b *testing.B) {
+	gameMap := GenerateMap(DefaultMapConfig())
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		NewSpatialGrid(gameMap, DefaultGridCellSize)
+	}
+}
@@ -0,0 +1,39 @@
+package game
+
+import "github.com/charmbracelet/log"
+
+// AddSpectator registers a connection watching the game without a tank.
+// Spectators are counted in the state but never become players.
+func (m *Manager) AddSpectator(userID string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.spectators[userID]++
+	m.state.Spectators = len(m.spectators)
+
+	log.Info("Spectator joined", "userID", userID, "spectators", m.state.Spectators)
+}
+
+// RemoveSpectator unregisters a spectator connection. A user watching from
+// several connections stays counted until the last one closes.
+func (m *Manager) RemoveSpectator(userID string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.spectators[userID] > 1 {
+		m.spectators[userID]--
+		return
+	}
+	delete(m.spectators, userID)
+	m.state.Spectators = len(m.spectators)
+
+	log.Info("Spectator left", "userID", userID, "spectators", m.state.Spectators)
+}
+
+// IsSpectator reports whether a user has an open spectator connection
+func (m *Manager) IsSpectator(userID string) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.spectators[userID] > 0
+}
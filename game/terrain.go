@@ -0,0 +1,453 @@
+package game
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Terrain defaults
+const (
+	DefaultTerrainCellSize = 8.0 // Sample spacing of the heightmap in world units
+
+	// Heights are stored as multiples of this so the server and client sample
+	// exactly the same surface
+	terrainHeightStep = 1.0 / 64
+
+	mountainBaseOffset    = 1.0  // Mountains are sunk this far so their edges meet the ground
+	terrainSightTolerance = 0.5  // Terrain must rise this far above a sight line to block it
+	maxSpawnSlope         = 0.25 // Steepest ground tanks spawn on, as rise over run
+	spawnAttempts         = 20   // Candidate spawn points tried before settling for a steep one
+)
+
+// Mountain is a single noise-shaped peak of the terrain
+type Mountain struct {
+	X      float64 `json:"x"`      // Centre of the peak
+	Z      float64 `json:"z"`      // Centre of the peak
+	Width  float64 `json:"width"`  // Footprint along X
+	Depth  float64 `json:"depth"`  // Footprint along Z
+	Height float64 `json:"height"` // Height scale of the noise
+	Seed   int64   `json:"seed"`   // Seed of the noise pattern
+}
+
+// mountainRange is a group of peaks scattered around a centre
+type mountainRange struct {
+	X, Z, Width, Depth float64
+	Peaks              int
+	Seed               int64
+}
+
+// The standard mountain layout. The terrain seed is added to every seed, which
+// moves, resizes and reshapes the peaks.
+var (
+	defaultMountainRanges = []mountainRange{
+		{X: 0, Z: 600, Width: 800, Depth: 300, Peaks: 5, Seed: 12345},     // Northern range
+		{X: 600, Z: 0, Width: 300, Depth: 800, Peaks: 4, Seed: 54321},     // Eastern range
+		{X: -500, Z: -500, Width: 400, Depth: 400, Peaks: 3, Seed: 98765}, // Southwestern mountains
+	}
+	defaultMountains = []Mountain{
+		{X: 350, Z: 350, Width: 200, Depth: 200, Height: 180, Seed: 24680},
+		{X: -350, Z: 350, Width: 180, Depth: 180, Height: 150, Seed: 13579},
+		{X: 80, Z: -80, Width: 120, Depth: 120, Height: 90, Seed: 11223}, // Small mountain near the centre
+	}
+)
+
+// GenerateMountains returns the peaks of the standard layout for a terrain seed,
+// with positions and footprints scaled to a map of the given half size
+func GenerateMountains(seed int64, halfSize float64) []Mountain {
+	var mountains []Mountain
+
+	for _, r := range defaultMountainRanges {
+		rangeSeed := float64(r.Seed + seed)
+		for i := 0; i < r.Peaks; i++ {
+			peak := float64(i)
+
+			// Deterministic offsets, sizes and heights from the seed
+			offsetX := math.Sin(rangeSeed+peak*100) * 0.5 * r.Width * 0.5
+			offsetZ := math.Cos(rangeSeed+peak*100) * 0.5 * r.Depth * 0.5
+			sizeVariation := 0.6 + math.Sin(rangeSeed+peak*200)*0.4
+			heightVariation := 0.7 + math.Sin(rangeSeed+peak*300)*0.3
+
+			mountains = append(mountains, Mountain{
+				X:      scaleToMap(r.X+offsetX, halfSize),
+				Z:      scaleToMap(r.Z+offsetZ, halfSize),
+				Width:  scaleToMap(r.Width*sizeVariation*0.6, halfSize),
+				Depth:  scaleToMap(r.Depth*sizeVariation*0.6, halfSize),
+				Height: (100 + peak*50) * heightVariation,
+				Seed:   r.Seed + seed + int64(i)*1000,
+			})
+		}
+	}
+
+	for _, mountain := range defaultMountains {
+		mountain.X = scaleToMap(mountain.X, halfSize)
+		mountain.Z = scaleToMap(mountain.Z, halfSize)
+		mountain.Width = scaleToMap(mountain.Width, halfSize)
+		mountain.Depth = scaleToMap(mountain.Depth, halfSize)
+		mountain.Seed += seed
+		mountains = append(mountains, mountain)
+	}
+
+	return mountains
+}
+
+// heightAt returns the surface height of the mountain at a world position, and
+// false outside its footprint
+func (m Mountain) heightAt(x, z float64) (float64, bool) {
+	lx := x - m.X
+	lz := z - m.Z
+	if math.Abs(lx) > m.Width/2 || math.Abs(lz) > m.Depth/2 {
+		return 0, false
+	}
+
+	// Normalized coordinates in [0, 1] across the footprint
+	nx := lx/m.Width + 0.5
+	nz := lz/m.Depth + 0.5
+	seedOffsetX := math.Sin(float64(m.Seed)*0.1) * 100
+	seedOffsetZ := math.Cos(float64(m.Seed)*0.1) * 100
+
+	// Large, medium and small scale features plus ridges
+	y := perlinNoise(nx*3+seedOffsetX, 0, nz*3+seedOffsetZ)*0.5 + 0.5
+	y += (perlinNoise(nx*6+seedOffsetX, 0, nz*6+seedOffsetZ)*0.5 + 0.5) * 0.25
+	y += (perlinNoise(nx*12+seedOffsetX, 0, nz*12+seedOffsetZ)*0.5 + 0.5) * 0.125
+	y += math.Abs(perlinNoise(nx*4+seedOffsetX, 0, nz*4+seedOffsetZ)) * 0.2
+
+	// Circular falloff toward the edges of the footprint
+	dx := math.Abs(lx) / (m.Width * 0.5)
+	dz := math.Abs(lz) / (m.Depth * 0.5)
+	falloff := math.Max(0, 1-(dx*dx+dz*dz))
+
+	return y*m.Height*falloff - mountainBaseOffset, true
+}
+
+// Heightmap is the terrain surface sampled on a regular grid. Between samples each
+// cell is split into two triangles along the diagonal from (col+1, row) to
+// (col, row+1), the same way the client builds its mesh. Outside the grid the
+// ground is flat at height 0.
+type Heightmap struct {
+	Seed     int64     // Seed the terrain was generated from
+	MinX     float64   // X of the first column
+	MinZ     float64   // Z of the first row
+	CellSize float64   // Distance between samples
+	Cols     int       // Samples per row
+	Rows     int       // Number of rows
+	Heights  []float64 // Samples, row-major
+
+	Mountains []Mountain // Peaks the samples were taken from, not sent to clients
+}
+
+// NewHeightmap samples mountains with the given cell size. The seed is the one
+// the mountains were generated from.
+func NewHeightmap(seed int64, mountains []Mountain, cellSize float64) *Heightmap {
+	if cellSize <= 0 {
+		cellSize = DefaultTerrainCellSize
+	}
+
+	// Cover the footprints of all mountains, snapped to the cell size
+	minX, minZ := math.Inf(1), math.Inf(1)
+	maxX, maxZ := math.Inf(-1), math.Inf(-1)
+	for _, m := range mountains {
+		minX = math.Min(minX, m.X-m.Width/2)
+		minZ = math.Min(minZ, m.Z-m.Depth/2)
+		maxX = math.Max(maxX, m.X+m.Width/2)
+		maxZ = math.Max(maxZ, m.Z+m.Depth/2)
+	}
+	if len(mountains) == 0 {
+		minX, minZ, maxX, maxZ = 0, 0, 0, 0
+	}
+	minX = math.Floor(minX/cellSize) * cellSize
+	minZ = math.Floor(minZ/cellSize) * cellSize
+
+	h := &Heightmap{
+		Seed:      seed,
+		MinX:      minX,
+		MinZ:      minZ,
+		CellSize:  cellSize,
+		Cols:      int(math.Ceil((maxX-minX)/cellSize)) + 1,
+		Rows:      int(math.Ceil((maxZ-minZ)/cellSize)) + 1,
+		Mountains: mountains,
+	}
+	h.Heights = make([]float64, h.Cols*h.Rows)
+
+	for row := 0; row < h.Rows; row++ {
+		z := minZ + float64(row)*cellSize
+		for col := 0; col < h.Cols; col++ {
+			x := minX + float64(col)*cellSize
+
+			// Overlapping peaks merge into the highest surface
+			height := 0.0
+			for _, m := range mountains {
+				if y, ok := m.heightAt(x, z); ok {
+					height = math.Max(height, y)
+				}
+			}
+			h.Heights[row*h.Cols+col] = quantizeHeight(height)
+		}
+	}
+
+	return h
+}
+
+// quantizeHeight rounds a height to the storage step, within what the wire format holds
+func quantizeHeight(height float64) float64 {
+	return math.Min(math.Round(height/terrainHeightStep), math.MaxUint16) * terrainHeightStep
+}
+
+// sample returns the height at a grid point, or 0 outside the grid
+func (h *Heightmap) sample(col, row int) float64 {
+	if col < 0 || row < 0 || col >= h.Cols || row >= h.Rows {
+		return 0
+	}
+	return h.Heights[row*h.Cols+col]
+}
+
+// cell returns the grid cell containing a position, the position within it in
+// [0, 1), and the heights of its corners
+func (h *Heightmap) cell(x, z float64) (fx, fz, h00, h10, h01, h11 float64) {
+	gx := (x - h.MinX) / h.CellSize
+	gz := (z - h.MinZ) / h.CellSize
+	col := int(math.Floor(gx))
+	row := int(math.Floor(gz))
+
+	return gx - float64(col), gz - float64(row),
+		h.sample(col, row), h.sample(col+1, row), h.sample(col, row+1), h.sample(col+1, row+1)
+}
+
+// HeightAt returns the ground height at a position. A nil heightmap is flat ground.
+func (h *Heightmap) HeightAt(x, z float64) float64 {
+	if h == nil || len(h.Heights) == 0 {
+		return 0
+	}
+
+	fx, fz, h00, h10, h01, h11 := h.cell(x, z)
+	if fx+fz <= 1 {
+		return h00 + fx*(h10-h00) + fz*(h01-h00)
+	}
+	return h11 + (1-fx)*(h01-h11) + (1-fz)*(h10-h11)
+}
+
+// NormalAt returns the unit surface normal of the ground at a position
+func (h *Heightmap) NormalAt(x, z float64) Position {
+	if h == nil || len(h.Heights) == 0 {
+		return Position{Y: 1}
+	}
+
+	// Slopes of the triangle containing the position
+	fx, fz, h00, h10, h01, h11 := h.cell(x, z)
+	var slopeX, slopeZ float64
+	if fx+fz <= 1 {
+		slopeX = (h10 - h00) / h.CellSize
+		slopeZ = (h01 - h00) / h.CellSize
+	} else {
+		slopeX = (h11 - h01) / h.CellSize
+		slopeZ = (h11 - h10) / h.CellSize
+	}
+
+	length := math.Sqrt(slopeX*slopeX + 1 + slopeZ*slopeZ)
+	return Position{X: -slopeX / length, Y: 1 / length, Z: -slopeZ / length}
+}
+
+// BlocksSight reports whether the ground rises above the straight line between
+// two positions, sampling it every half cell
+func (h *Heightmap) BlocksSight(from, to Position) bool {
+	if h == nil || len(h.Heights) == 0 {
+		return false
+	}
+
+	dx := to.X - from.X
+	dz := to.Z - from.Z
+	steps := int(math.Sqrt(dx*dx+dz*dz) / (h.CellSize / 2))
+
+	// The end points themselves sit on or above the ground
+	for i := 1; i < steps; i++ {
+		t := float64(i) / float64(steps)
+		x := from.X + dx*t
+		z := from.Z + dz*t
+		if h.HeightAt(x, z) > from.Y+(to.Y-from.Y)*t+terrainSightTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// SpawnPosition places a tank on the ground at a candidate from pick, retrying
+// while the ground there is too steep. If no gentle ground is found the last
+// candidate is used.
+func (h *Heightmap) SpawnPosition(pick func() (x, z float64)) Position {
+	var x, z float64
+	for attempt := 0; attempt < spawnAttempts; attempt++ {
+		x, z = pick()
+		normal := h.NormalAt(x, z)
+		if math.Sqrt(normal.X*normal.X+normal.Z*normal.Z) <= maxSpawnSlope*normal.Y {
+			break
+		}
+	}
+	return Position{X: x, Y: h.HeightAt(x, z), Z: z}
+}
+
+// heightmapJSON is the wire format of a heightmap. Heights are little-endian
+// uint16 multiples of heightStep, base64 encoded to keep the page small.
+type heightmapJSON struct {
+	Seed       int64   `json:"seed"`
+	MinX       float64 `json:"minX"`
+	MinZ       float64 `json:"minZ"`
+	CellSize   float64 `json:"cellSize"`
+	Cols       int     `json:"cols"`
+	Rows       int     `json:"rows"`
+	HeightStep float64 `json:"heightStep"`
+	Heights    string  `json:"heights"`
+}
+
+// MarshalJSON encodes the heightmap in its compact wire format
+func (h *Heightmap) MarshalJSON() ([]byte, error) {
+	data := make([]byte, 2*len(h.Heights))
+	for i, height := range h.Heights {
+		binary.LittleEndian.PutUint16(data[2*i:], uint16(math.Round(height/terrainHeightStep)))
+	}
+
+	return json.Marshal(heightmapJSON{
+		Seed:       h.Seed,
+		MinX:       h.MinX,
+		MinZ:       h.MinZ,
+		CellSize:   h.CellSize,
+		Cols:       h.Cols,
+		Rows:       h.Rows,
+		HeightStep: terrainHeightStep,
+		Heights:    base64.StdEncoding.EncodeToString(data),
+	})
+}
+
+// UnmarshalJSON decodes a heightmap from its compact wire format
+func (h *Heightmap) UnmarshalJSON(b []byte) error {
+	var wire heightmapJSON
+	if err := json.Unmarshal(b, &wire); err != nil {
+		return err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(wire.Heights)
+	if err != nil {
+		return fmt.Errorf("invalid heightmap data: %v", err)
+	}
+	if wire.Cols < 0 || wire.Rows < 0 || len(data) != 2*wire.Cols*wire.Rows {
+		return fmt.Errorf("heightmap has %d bytes of data for %dx%d samples", len(data), wire.Cols, wire.Rows)
+	}
+	if wire.HeightStep <= 0 {
+		wire.HeightStep = terrainHeightStep
+	}
+
+	*h = Heightmap{
+		Seed:     wire.Seed,
+		MinX:     wire.MinX,
+		MinZ:     wire.MinZ,
+		CellSize: wire.CellSize,
+		Cols:     wire.Cols,
+		Rows:     wire.Rows,
+		Heights:  make([]float64, wire.Cols*wire.Rows),
+	}
+	for i := range h.Heights {
+		h.Heights[i] = float64(binary.LittleEndian.Uint16(data[2*i:])) * wire.HeightStep
+	}
+	return nil
+}
+
+// terrain returns the map's heightmap, or nil for flat ground
+func (gm *GameMap) terrain() *Heightmap {
+	if gm == nil {
+		return nil
+	}
+	return gm.Terrain
+}
+
+// GroundHeight returns the terrain height at a position on the map
+func (gm *GameMap) GroundHeight(x, z float64) float64 {
+	return gm.terrain().HeightAt(x, z)
+}
+
+// randomSpawnPosition picks one of the map's spawn points, or a point anywhere on
+// the map on ground gentle enough to spawn a tank if it has none
+func (m *Manager) randomSpawnPosition() Position {
+	if m.gameMap != nil && len(m.gameMap.SpawnPoints) > 0 {
+		spawn := m.gameMap.SpawnPoints[rand.Intn(len(m.gameMap.SpawnPoints))]
+		spawn.Y = m.gameMap.GroundHeight(spawn.X, spawn.Z)
+		return spawn
+	}
+
+	halfSize := m.gameMap.HalfSize()
+	return m.gameMap.terrain().SpawnPosition(func() (float64, float64) {
+		return -halfSize + rand.Float64()*halfSize*2, -halfSize + rand.Float64()*halfSize*2
+	})
+}
+
+// perlinPermutation is Ken Perlin's reference permutation, the same table as
+// three.js ImprovedNoise, repeated once to avoid wrapping indices
+var perlinPermutation = func() [512]int {
+	p := [256]int{151, 160, 137, 91, 90, 15, 131, 13, 201, 95, 96, 53, 194, 233, 7, 225, 140, 36, 103, 30, 69, 142,
+		8, 99, 37, 240, 21, 10, 23, 190, 6, 148, 247, 120, 234, 75, 0, 26, 197, 62, 94, 252, 219, 203, 117, 35, 11, 32,
+		57, 177, 33, 88, 237, 149, 56, 87, 174, 20, 125, 136, 171, 168, 68, 175, 74, 165, 71, 134, 139, 48, 27, 166,
+		77, 146, 158, 231, 83, 111, 229, 122, 60, 211, 133, 230, 220, 105, 92, 41, 55, 46, 245, 40, 244, 102, 143, 54,
+		65, 25, 63, 161, 1, 216, 80, 73, 209, 76, 132, 187, 208, 89, 18, 169, 200, 196, 135, 130, 116, 188, 159, 86,
+		164, 100, 109, 198, 173, 186, 3, 64, 52, 217, 226, 250, 124, 123, 5, 202, 38, 147, 118, 126, 255, 82, 85, 212,
+		207, 206, 59, 227, 47, 16, 58, 17, 182, 189, 28, 42, 223, 183, 170, 213, 119, 248, 152, 2, 44, 154, 163, 70,
+		221, 153, 101, 155, 167, 43, 172, 9, 129, 22, 39, 253, 19, 98, 108, 110, 79, 113, 224, 232, 178, 185, 112, 104,
+		218, 246, 97, 228, 251, 34, 242, 193, 238, 210, 144, 12, 191, 179, 162, 241, 81, 51, 145, 235, 249, 14, 239,
+		107, 49, 192, 214, 31, 181, 199, 106, 157, 184, 84, 204, 176, 115, 121, 50, 45, 127, 4, 150, 254, 138, 236,
+		205, 93, 222, 114, 67, 29, 24, 72, 243, 141, 128, 195, 78, 66, 215, 61, 156, 180}
+
+	var table [512]int
+	for i := range table {
+		table[i] = p[i&255]
+	}
+	return table
+}()
+
+// perlinNoise is Ken Perlin's improved 3D noise in [-1, 1], matching three.js ImprovedNoise
+func perlinNoise(x, y, z float64) float64 {
+	p := &perlinPermutation
+
+	floorX, floorY, floorZ := math.Floor(x), math.Floor(y), math.Floor(z)
+	X, Y, Z := int(floorX)&255, int(floorY)&255, int(floorZ)&255
+	x -= floorX
+	y -= floorY
+	z -= floorZ
+
+	fade := func(t float64) float64 { return t * t * t * (t*(t*6-15) + 10) }
+	lerp := func(t, a, b float64) float64 { return a + t*(b-a) }
+	grad := func(hash int, x, y, z float64) float64 {
+		h := hash & 15
+		u, v := y, z
+		if h < 8 {
+			u = x
+		}
+		if h < 4 {
+			v = y
+		} else if h == 12 || h == 14 {
+			v = x
+		}
+		if h&1 != 0 {
+			u = -u
+		}
+		if h&2 != 0 {
+			v = -v
+		}
+		return u + v
+	}
+
+	u, v, w := fade(x), fade(y), fade(z)
+	A := p[X] + Y
+	AA := p[A] + Z
+	AB := p[A+1] + Z
+	B := p[X+1] + Y
+	BA := p[B] + Z
+	BB := p[B+1] + Z
+
+	return lerp(w,
+		lerp(v,
+			lerp(u, grad(p[AA], x, y, z), grad(p[BA], x-1, y, z)),
+			lerp(u, grad(p[AB], x, y-1, z), grad(p[BB], x-1, y-1, z))),
+		lerp(v,
+			lerp(u, grad(p[AA+1], x, y, z-1), grad(p[BA+1], x-1, y, z-1)),
+			lerp(u, grad(p[AB+1], x, y-1, z-1), grad(p[BB+1], x-1, y-1, z-1))))
+}
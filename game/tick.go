@@ -0,0 +1,145 @@
+package game
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// Supported simulation tick rates in Hz
+const (
+	TickRate20      = 20
+	TickRate30      = 30
+	TickRate60      = 60
+	DefaultTickRate = TickRate20
+)
+
+// TickPhase identifies a stage of the authoritative tick. Phases run in ascending order.
+type TickPhase int
+
+const (
+	PhaseMovement TickPhase = iota // Integrate client inputs into tank movement
+	PhaseNPC                       // NPC AI decisions and firing
+	PhasePhysics                   // Shell simulation and hit detection
+	PhaseCleanup                   // Expire shells, respawn and remove inactive players
+)
+
+// TickFunc is called once per tick for a registered phase
+// tick is the number of the tick being simulated and dt the elapsed time in seconds
+type TickFunc func(tick uint64, dt float64)
+
+// tickPhaseOrder lists the phases in the order they are executed each tick
+var tickPhaseOrder = []TickPhase{PhaseMovement, PhaseNPC, PhasePhysics, PhaseCleanup}
+
+// ValidateTickRate checks that a tick rate is one of the supported values
+func ValidateTickRate(rate int) error {
+	switch rate {
+	case TickRate20, TickRate30, TickRate60:
+		return nil
+	default:
+		return fmt.Errorf("unsupported tick rate %d, must be %d, %d or %d", rate, TickRate20, TickRate30, TickRate60)
+	}
+}
+
+// RegisterTickPhase adds a function to run during the given phase of every tick.
+// Functions within the same phase run in registration order.
+func (m *Manager) RegisterTickPhase(phase TickPhase, fn TickFunc) {
+	m.phaseMutex.Lock()
+	defer m.phaseMutex.Unlock()
+
+	m.phases[phase] = append(m.phases[phase], fn)
+}
+
+// TickRate returns the simulation tick rate in Hz
+func (m *Manager) TickRate() int {
+	return m.tickRate
+}
+
+// runTickLoop drives the authoritative simulation at a fixed rate.
+// Every tick runs all phases in order and then publishes exactly one snapshot.
+func (m *Manager) runTickLoop() {
+	interval := time.Second / time.Duration(m.tickRate)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Info("Game tick loop started", "tickRate", m.tickRate, "interval", interval)
+
+	lastTick := time.Now()
+	for {
+		select {
+		case <-m.ctx.Done():
+			log.Info("Game tick loop stopped")
+			return
+		case now := <-ticker.C:
+			dt := now.Sub(lastTick).Seconds()
+			lastTick = now
+
+			m.runTick(dt)
+
+			// Warn if a tick takes longer than its budget
+			if elapsed := time.Since(now); elapsed > interval {
+				log.Warn("Game tick overran its budget", "elapsed", elapsed, "budget", interval)
+			}
+		}
+	}
+}
+
+// runTick executes one tick: built-in phases, registered phases and a single publish
+func (m *Manager) runTick(dt float64) {
+	m.mutex.RLock()
+	tick := m.state.Tick + 1
+	m.mutex.RUnlock()
+
+	m.phaseMutex.RLock()
+	phases := make(map[TickPhase][]TickFunc, len(m.phases))
+	for phase, fns := range m.phases {
+		phases[phase] = fns
+	}
+	m.phaseMutex.RUnlock()
+
+	for _, phase := range tickPhaseOrder {
+		// Built-in manager phases run before externally registered ones
+		switch phase {
+		case PhaseMovement:
+			m.mutex.Lock()
+			m.simulateMovement(dt)
+			m.mutex.Unlock()
+		case PhaseCleanup:
+			m.cleanupGameState()
+			m.updateMatch()
+			m.notifyMapChange()
+			m.reconcileHitClaims()
+		}
+
+		for _, fn := range phases[phase] {
+			fn(tick, dt)
+		}
+	}
+
+	// Stamp the snapshot with the tick number and server time
+	m.mutex.Lock()
+	m.state.Tick = tick
+	m.state.ServerTime = m.getTime()
+	m.mutex.Unlock()
+
+	if err := m.saveState(); err != nil {
+		log.Error("Error publishing game state for tick", "tick", tick, "error", err)
+	}
+}
+
+// MaxRewindMs bounds lag compensation so high-latency players can't hit targets
+// that have long since moved behind cover
+const MaxRewindMs = 250
+
+// rewindTicks returns how many ticks the shooter's view lagged behind the server when
+// firing, bounded by MaxRewindMs. Caller must hold the mutex.
+func (m *Manager) rewindTicks(viewTick uint64) uint64 {
+	// Shooters that don't report a view tick, like NPCs, see the current state
+	if viewTick == 0 || viewTick >= m.state.Tick {
+		return 0
+	}
+
+	maxTicks := uint64(MaxRewindMs * m.tickRate / 1000)
+	return min(m.state.Tick-viewTick, maxTicks)
+}
@@ -1,7 +1,9 @@
 package game
 
 import (
+	"fmt"
 	"math"
+	"sync"
 )
 
 // TreeType represents the type of tree
@@ -21,6 +23,11 @@ type Tree struct {
 	Radius   float64  `json:"radius"`
 }
 
+// TreeID identifies the tree at an index of the tree map
+func TreeID(index int) string {
+	return fmt.Sprintf("tree_%d", index)
+}
+
 // TreeMap holds all trees in the game
 type TreeMap struct {
 	Trees []Tree `json:"trees"`
@@ -28,46 +35,21 @@ type TreeMap struct {
 
 // GameMap represents the entire game map including trees and other static objects
 type GameMap struct {
-	Trees TreeMap `json:"trees"`
-	Rocks RockMap `json:"rocks"`
-}
-
-// Global instance of the game map
-var gameMap *GameMap
-
-// Initialize the game map
-func InitGameMap() *GameMap {
-	if gameMap == nil {
-		// Initialize empty game map
-		gameMap = &GameMap{
-			Trees: TreeMap{
-				Trees: []Tree{},
-			},
-			Rocks: RockMap{
-				Rocks: []Rock{},
-			},
-		}
-
-		// Generate trees
-		generateTrees()
-
-		// Generate rocks
-		rockMap := InitRockMap()
-		gameMap.Rocks = *rockMap
-	}
-	return gameMap
-}
-
-// GetGameMap returns the game map instance
-func GetGameMap() *GameMap {
-	if gameMap == nil {
-		return InitGameMap()
-	}
-	return gameMap
+	Name        string        `json:"name,omitempty"` // Name of the map file, empty for generated maps
+	Config      MapConfig     `json:"config"`         // Parameters the map was generated from
+	Trees       TreeMap       `json:"trees"`
+	Rocks       RockMap       `json:"rocks"`
+	Terrain     *Heightmap    `json:"terrain"`
+	SpawnPoints []Position    `json:"spawnPoints,omitempty"` // Fixed spawns, random spawns if empty
+	Zones       []ControlZone `json:"zones,omitempty"`       // King of the Hill zones, see ControlZones if empty
+	Bases       *FlagBases    `json:"flagBases,omitempty"`   // Capture-the-flag bases, on the X axis if nil
+
+	grid     *SpatialGrid // Obstacle index, built by Grid
+	gridOnce sync.Once
 }
 
 // createPineTree creates a pine tree at the specified position
-func createPineTree(scale float64, x, z float64) Tree {
+func (g *mapGenerator) createPineTree(scale float64, x, z float64) Tree {
 	// Create a collider for the tree
 	collisionRadius := 1.0 * scale
 	tree := Tree{
@@ -76,12 +58,14 @@ func createPineTree(scale float64, x, z float64) Tree {
 		Scale:    scale,
 		Radius:   collisionRadius,
 	}
-	gameMap.Trees.Trees = append(gameMap.Trees.Trees, tree)
+	if g.inBounds(x, z) {
+		g.trees.Trees = append(g.trees.Trees, tree)
+	}
 	return tree
 }
 
 // createRoundTree creates a round tree at the specified position
-func createRoundTree(scale float64, x, z float64) Tree {
+func (g *mapGenerator) createRoundTree(scale float64, x, z float64) Tree {
 	// Create a collider for the tree
 	collisionRadius := 1.2 * scale
 	tree := Tree{
@@ -90,12 +74,14 @@ func createRoundTree(scale float64, x, z float64) Tree {
 		Scale:    scale,
 		Radius:   collisionRadius,
 	}
-	gameMap.Trees.Trees = append(gameMap.Trees.Trees, tree)
+	if g.inBounds(x, z) {
+		g.trees.Trees = append(g.trees.Trees, tree)
+	}
 	return tree
 }
 
 // createCircleOfTrees creates a circle of trees with the specified radius and count
-func createCircleOfTrees(radius float64, count int, treeType TreeType) {
+func (g *mapGenerator) createCircleOfTrees(radius float64, count int, treeType TreeType) {
 	for i := 0; i < count; i++ {
 		angle := float64(i) / float64(count) * math.Pi * 2
 		x := math.Cos(angle) * radius
@@ -104,15 +90,15 @@ func createCircleOfTrees(radius float64, count int, treeType TreeType) {
 		scale := 1.0 + (math.Sin(angle*3)+1)*0.3 // Deterministic scale variation
 
 		if treeType == PineTree {
-			createPineTree(scale, x, z)
+			g.createPineTree(scale, x, z)
 		} else {
-			createRoundTree(scale, x, z)
+			g.createRoundTree(scale, x, z)
 		}
 	}
 }
 
 // createSacredGrove creates a sacred grove of trees
-func createSacredGrove(centerX, centerZ, radius float64, count int) {
+func (g *mapGenerator) createSacredGrove(centerX, centerZ, radius float64, count int) {
 	for i := 0; i < count; i++ {
 		angle := float64(i) / float64(count) * math.Pi * 2
 		x := centerX + math.Cos(angle)*radius
@@ -121,9 +107,9 @@ func createSacredGrove(centerX, centerZ, radius float64, count int) {
 		scale := 1.5 // All trees same size
 
 		if i%2 == 0 {
-			createPineTree(scale, x, z)
+			g.createPineTree(scale, x, z)
 		} else {
-			createRoundTree(scale, x, z)
+			g.createRoundTree(scale, x, z)
 		}
 	}
 }
@@ -191,12 +177,13 @@ func noise2D(x, y float64, seed int) float64 {
 	return (result + 1) * 0.5
 }
 
-// fbm implements Fractal Brownian Motion (same as in trees.ts)
-func fbm(x, y float64, octaves int, lacunarity, persistence float64, seed int) float64 {
+// fbm implements Fractal Brownian Motion (same as in trees.ts), seeded by the map seed
+func (g *mapGenerator) fbm(x, y float64, octaves int, lacunarity, persistence float64, seed int) float64 {
 	var total float64 = 0
-	frequency := 0.005 // Base frequency - controls pattern scale
+	frequency := baseNoiseFrequency / g.cfg.BiomeScale // Base frequency - controls pattern scale
 	amplitude := 1.0
 	var maxValue float64 = 0
+	seed = g.seeded(seed)
 
 	for i := 0; i < octaves; i++ {
 		// Add noise at current frequency and amplitude
@@ -213,15 +200,15 @@ func fbm(x, y float64, octaves int, lacunarity, persistence float64, seed int) f
 }
 
 // treeNoiseValue calculates tree density at a given position
-func treeNoiseValue(x, y float64, biomeScale float64, foliageType TreeType) (value float64, treeType TreeType) {
+func (g *mapGenerator) treeNoiseValue(x, y float64, biomeScale float64, foliageType TreeType) (value float64, treeType TreeType) {
 	// Large-scale biome variation
-	biomeNoise := fbm(x, y, 3, 2.0, 0.5, 42)
+	biomeNoise := g.fbm(x, y, 3, 2.0, 0.5, 42)
 
 	// Medium-scale terrain variation
-	terrainNoise := fbm(x, y, 4, 2.0, 0.5, 123)
+	terrainNoise := g.fbm(x, y, 4, 2.0, 0.5, 123)
 
 	// Small-scale details
-	detailNoise := fbm(x, y, 6, 2.2, 0.6, 987)
+	detailNoise := g.fbm(x, y, 6, 2.2, 0.6, 987)
 
 	// Combine noise layers with different weights
 	combinedNoise := biomeNoise*0.4 + terrainNoise*0.4 + detailNoise*0.2
@@ -236,7 +223,7 @@ func treeNoiseValue(x, y float64, biomeScale float64, foliageType TreeType) (val
 		treeType = RoundTree
 	} else {
 		// For mixed forests, use separate noise function to determine type
-		typeNoise := fbm(x, y, 2, 2.5, 0.5, 789)
+		typeNoise := g.fbm(x, y, 2, 2.5, 0.5, 789)
 		if typeNoise > 0.5 {
 			treeType = PineTree
 		} else {
@@ -248,115 +235,121 @@ func treeNoiseValue(x, y float64, biomeScale float64, foliageType TreeType) (val
 }
 
 // createTreeFromNoise creates a tree based on a noise threshold
-func createTreeFromNoise(x, z, densityThreshold, scaleBase, biomeScale float64, foliageType TreeType) {
+func (g *mapGenerator) createTreeFromNoise(x, z, densityThreshold, scaleBase, biomeScale float64, foliageType TreeType) {
 	// Get noise value at this position
-	noiseValue, treeType := treeNoiseValue(x, z, biomeScale, foliageType)
+	noiseValue, treeType := g.treeNoiseValue(x, z, biomeScale, foliageType)
 
 	// Only place trees where noise value exceeds threshold
 	if noiseValue > densityThreshold {
 		// Scale varies deterministically based on position
-		scale := scaleBase + fbm(x, z, 3, 2.0, 0.5, 555)*0.5
+		scale := scaleBase + g.fbm(x, z, 3, 2.0, 0.5, 555)*0.5
 
 		// Create the appropriate tree type
 		if treeType == PineTree {
-			createPineTree(scale, x, z)
+			g.createPineTree(scale, x, z)
 		} else {
-			createRoundTree(scale, x, z)
+			g.createRoundTree(scale, x, z)
 		}
 	}
 }
 
 // generateTrees generates all the trees in the game map
-func generateTrees() {
+func (g *mapGenerator) generateTrees() {
+	landmarks := g.cfg.Landmarks
+
 	// 1. Trees surrounding the starting area (using circles for consistent gameplay)
-	createCircleOfTrees(30, 10, PineTree)  // Inner ring of pine trees
-	createCircleOfTrees(45, 12, RoundTree) // Middle ring of round trees
-	createCircleOfTrees(60, 16, PineTree)  // Outer ring of pine trees
+	if !landmarks.NoStartingArea {
+		g.createCircleOfTrees(30, 10, PineTree)  // Inner ring of pine trees
+		g.createCircleOfTrees(45, 12, RoundTree) // Middle ring of round trees
+		g.createCircleOfTrees(60, 16, PineTree)  // Outer ring of pine trees
+	}
 
 	// 2. Sacred groves at key locations (preserved for gameplay landmarks)
-	createSacredGrove(200, 200, 40, 12)
-	createSacredGrove(-200, -200, 40, 12)
-	createSacredGrove(200, -200, 40, 12)
-	createSacredGrove(-200, 200, 40, 12)
+	if !landmarks.NoGroves {
+		g.createSacredGrove(g.at(200), g.at(200), 40, 12)
+		g.createSacredGrove(g.at(-200), g.at(-200), 40, 12)
+		g.createSacredGrove(g.at(200), g.at(-200), 40, 12)
+		g.createSacredGrove(g.at(-200), g.at(200), 40, 12)
+	}
 
 	// 3. Forests using fractal noise patterns
 
 	// North Forest - Pine dominant biome
-	for x := -400.0; x <= 400.0; x += 20.0 {
-		for z := 400.0; z <= 800.0; z += 20.0 {
-			createTreeFromNoise(x, z, 0.55, 1.2, 1.2, PineTree)
+	for x := g.at(-400); x <= g.at(400); x += g.treeStep(20) {
+		for z := g.at(400); z <= g.at(800); z += g.treeStep(20) {
+			g.createTreeFromNoise(x, z, 0.55, 1.2, 1.2, PineTree)
 		}
 	}
 
 	// South Forest - Round dominant biome
-	for x := -400.0; x <= 400.0; x += 20.0 {
-		for z := -800.0; z <= -400.0; z += 20.0 {
-			createTreeFromNoise(x, z, 0.6, 1.0, 1.1, RoundTree)
+	for x := g.at(-400); x <= g.at(400); x += g.treeStep(20) {
+		for z := g.at(-800); z <= g.at(-400); z += g.treeStep(20) {
+			g.createTreeFromNoise(x, z, 0.6, 1.0, 1.1, RoundTree)
 		}
 	}
 
 	// East Forest - Mixed biome (less dense)
-	for x := 400.0; x <= 800.0; x += 25.0 {
-		for z := -400.0; z <= 400.0; z += 25.0 {
-			createTreeFromNoise(x, z, 0.65, 1.1, 0.9, MixedTree)
+	for x := g.at(400); x <= g.at(800); x += g.treeStep(25) {
+		for z := g.at(-400); z <= g.at(400); z += g.treeStep(25) {
+			g.createTreeFromNoise(x, z, 0.65, 1.1, 0.9, MixedTree)
 		}
 	}
 
 	// West Forest - Mixed biome (less dense)
-	for x := -800.0; x <= -400.0; x += 25.0 {
-		for z := -400.0; z <= 400.0; z += 25.0 {
-			createTreeFromNoise(x, z, 0.65, 1.1, 0.9, MixedTree)
+	for x := g.at(-800); x <= g.at(-400); x += g.treeStep(25) {
+		for z := g.at(-400); z <= g.at(400); z += g.treeStep(25) {
+			g.createTreeFromNoise(x, z, 0.65, 1.1, 0.9, MixedTree)
 		}
 	}
 
 	// 4. Tree lines - roads through the forests (preserved for navigation)
-	// North-South Road
-	for z := -1000.0; z <= 1000.0; z += 30.0 {
-		createPineTree(1.5, -15, z)
-		createPineTree(1.5, 15, z)
-	}
+	if !landmarks.NoRoads {
+		roadEnd := math.Min(roadHalfLength, g.halfSize)
 
-	// East-West Road
-	for x := -1000.0; x <= 1000.0; x += 30.0 {
-		createRoundTree(1.3, x, -15)
-		createRoundTree(1.3, x, 15)
+		// North-South Road
+		for z := -roadEnd; z <= roadEnd; z += 30.0 {
+			g.createPineTree(1.5, -15, z)
+			g.createPineTree(1.5, 15, z)
+		}
+
+		// East-West Road
+		for x := -roadEnd; x <= roadEnd; x += 30.0 {
+			g.createRoundTree(1.3, x, -15)
+			g.createRoundTree(1.3, x, 15)
+		}
 	}
 
 	// 5. Distinctive landmarks (preserved for navigation)
+	if !landmarks.NoTreeLandmarks {
+		// Large pine tree at origin
+		g.createPineTree(4.0, 0, 100)
+
+		// Circle of 8 large round trees
+		for i := 0; i < 8; i++ {
+			angle := float64(i) / 8.0 * math.Pi * 2
+			g.createRoundTree(2.5, math.Cos(angle)*120, math.Sin(angle)*120)
+		}
 
-	// Large pine tree at origin
-	createPineTree(4.0, 0, 100)
-
-	// Circle of 8 large round trees
-	for i := 0; i < 8; i++ {
-		angle := float64(i) / 8.0 * math.Pi * 2
-		createRoundTree(2.5, math.Cos(angle)*120, math.Sin(angle)*120)
-	}
-
-	// Spiral of pine trees
-	for i := 0; i < 40; i++ {
-		angle := float64(i) * 0.5
-		radius := 100.0 + float64(i)*5.0
-		createPineTree(1.0+float64(i)*0.05, math.Cos(angle)*radius, math.Sin(angle)*radius)
+		// Spiral of pine trees
+		for i := 0; i < 40; i++ {
+			angle := float64(i) * 0.5
+			radius := 100.0 + float64(i)*5.0
+			g.createPineTree(1.0+float64(i)*0.05, math.Cos(angle)*radius, math.Sin(angle)*radius)
+		}
 	}
 
 	// Add some extra forest patches in various areas to create more complex patterns
 	// Northwest region
-	for x := -600.0; x <= -300.0; x += 30.0 {
-		for z := 300.0; z <= 600.0; z += 30.0 {
-			createTreeFromNoise(x, z, 0.75, 1.3, 0.8, MixedTree)
+	for x := g.at(-600); x <= g.at(-300); x += g.treeStep(30) {
+		for z := g.at(300); z <= g.at(600); z += g.treeStep(30) {
+			g.createTreeFromNoise(x, z, 0.75, 1.3, 0.8, MixedTree)
 		}
 	}
 
 	// Southeast region
-	for x := 300.0; x <= 600.0; x += 30.0 {
-		for z := -600.0; z <= -300.0; z += 30.0 {
-			createTreeFromNoise(x, z, 0.75, 1.3, 0.8, MixedTree)
+	for x := g.at(300); x <= g.at(600); x += g.treeStep(30) {
+		for z := g.at(-600); z <= g.at(-300); z += g.treeStep(30) {
+			g.createTreeFromNoise(x, z, 0.75, 1.3, 0.8, MixedTree)
 		}
 	}
 }
-
-// GetAllTrees returns all trees in the game map
-func GetAllTrees() []Tree {
-	return gameMap.Trees.Trees
-}
@@ -29,23 +29,36 @@ type PlayerState struct {
 	TrackRotation   float64      `json:"trackRotation"`           // Track animation speed for client visualization
 	LastKilledBy    string       `json:"lastKilledBy,omitempty"`  // ID of player who last killed this player
 	LastDeathTime   int64        `json:"lastDeathTime,omitempty"` // Timestamp when player was last killed
-	Notification    string       `json:"notification,omitempty"`  // Kill notification message for client
+	LastInputSeq    uint32       `json:"lastInputSeq,omitempty"`  // Sequence of the last input applied by the server
+	Team            string       `json:"team,omitempty"`          // Team ID in team modes
+	Score           int          `json:"score,omitempty"`         // Objective points in modes where players score individually
 }
 
 // ShellState represents the state of a shell
 type ShellState struct {
-	ID        string   `json:"id"`
-	PlayerID  string   `json:"playerId"`
-	Position  Position `json:"position"`
-	Direction Position `json:"direction"`
-	Speed     float64  `json:"speed"`
-	Timestamp int64    `json:"timestamp"`
+	ID          string   `json:"id"`
+	PlayerID    string   `json:"playerId"`
+	Position    Position `json:"position"`
+	Direction   Position `json:"direction"`
+	Speed       float64  `json:"speed"`
+	Timestamp   int64    `json:"timestamp"`
+	RewindTicks uint64   `json:"-"` // How far behind the server the shooter saw targets, for lag compensation
 }
 
 // GameState represents the state of the entire game
 type GameState struct {
-	Players map[string]PlayerState `json:"players"`
-	Shells  []ShellState           `json:"shells"`
+	Players    map[string]PlayerState `json:"players"`
+	Shells     []ShellState           `json:"shells"`
+	Tick       uint64                 `json:"tick"`                 // Simulation tick this snapshot was published at
+	ServerTime int64                  `json:"serverTime"`           // Server time in milliseconds when the tick completed
+	Match      MatchState             `json:"match"`                // Current match mode, limits and result
+	Teams      map[string]TeamState   `json:"teams,omitempty"`      // Team scores in team modes
+	Flags      map[string]FlagState   `json:"flags,omitempty"`      // Team flags in capture-the-flag, keyed by owning team
+	Zones      []ZoneState            `json:"zones,omitempty"`      // Control zones in King of the Hill
+	Spectators int                    `json:"spectators,omitempty"` // Users watching without a tank
+
+	Impacts            []ImpactState   `json:"impacts,omitempty"`            // Recent shell impacts on trees and rocks
+	DestroyedObstacles []ObstacleState `json:"destroyedObstacles,omitempty"` // Rocks broken by shells this match
 }
 
 // EventType represents the type of game event
@@ -58,6 +71,8 @@ const (
 	EventTankHit      EventType = "TANK_HIT"
 	EventTankDeath    EventType = "TANK_DEATH"
 	EventTankRespawn  EventType = "TANK_RESPAWN"
+	EventPlayerInput  EventType = "PLAYER_INPUT"
+	EventStateAck     EventType = "STATE_ACK"
 )
 
 // GameEvent represents a consolidated game event
@@ -83,11 +98,23 @@ type RespawnData struct {
 	Position Position `json:"position"`
 }
 
+// PlayerInput represents the control inputs a client sends for its tank.
+// The server simulates movement from these; clients never set position directly.
+type PlayerInput struct {
+	Throttle        float64 `json:"throttle"`        // -1 (full reverse) to 1 (full forward)
+	Steer           float64 `json:"steer"`           // -1 (turn right) to 1 (turn left)
+	TurretRotation  float64 `json:"turretRotation"`  // Desired turret rotation in radians
+	BarrelElevation float64 `json:"barrelElevation"` // Desired barrel elevation in radians
+	Sequence        uint32  `json:"seq"`             // Client-side input sequence for reconciliation
+	Timestamp       int64   `json:"timestamp"`       // Client time when the input was sampled
+}
+
 // ShellData represents shell firing data
 type ShellData struct {
 	Position  Position `json:"position"`
 	Direction Position `json:"direction"`
 	Speed     float64  `json:"speed"`
+	ViewTick  uint64   `json:"viewTick,omitempty"` // Tick of the latest state the shooter had when firing
 }
 
 // PlayerStatus represents the current status of a player in the game lifecycle
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"math/rand"
 	"os"
 	"strconv"
@@ -12,10 +11,13 @@ import (
 	"github.com/charmbracelet/log"
 	"github.com/delaneyj/toolbelt/embeddednats"
 	"tank-game/game"
-	"tank-game/game/physics"
+	"tank-game/game/room"
+	"tank-game/matchmaking"
 	"tank-game/middleware"
 	_ "tank-game/migrations"
+	"tank-game/replay"
 	"tank-game/routes"
+	"tank-game/stats"
 	"tank-game/utils"
 	"github.com/nats-io/nats-server/v2/server"
 	"github.com/nats-io/nats.go"
@@ -99,39 +101,21 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to get KV bucket", "error", err)
 	}
-	err = kv.Purge(context.Background(), "current")
-	if err != nil {
-		log.Fatal("Failed to purge KV bucket", "error", err)
-	}
 	log.Info("KV store initialized")
 
-	// Initialize game manager
-	gameManager, err := game.NewManager(ctx, kv)
-	if err != nil {
-		log.Fatal("Failed to initialize game manager", "error", err)
+	// Set the simulation tick rate
+	// Read from environment variable or default to 20Hz
+	tickRate := game.DefaultTickRate
+	if tickRateStr := os.Getenv("TICK_RATE"); tickRateStr != "" {
+		if val, err := strconv.Atoi(tickRateStr); err == nil && game.ValidateTickRate(val) == nil {
+			tickRate = val
+		} else {
+			log.Warn("Invalid TICK_RATE, using default",
+				"requested", tickRateStr, "allowed", "20, 30 or 60", "using", tickRate)
+		}
 	}
-	log.Info("Game manager initialized")
-
-	// Initialize physics system
-	log.Info("Initializing physics collision detection system")
-
-	// Create all the required components in the correct order
-	gameMap := game.GetGameMap() // Use GetGameMap instead of InitGameMap to avoid redeclaration
 
-	// Use the new Vu physics-based manager instead of the old one
-	physics.PhysicsManagerInstance = physics.NewVuPhysicsManager(gameMap, gameManager)
-	physicsIntegration := physics.NewPhysicsIntegration(gameManager)
-	physicsIntegration.Start()
-
-	// Initialize NPC controller
-	log.Info("Initializing NPC controller")
-
-	// Reuse the gameMap variable from above
-	// Pass the physics manager to provide NPC tanks with targeting capabilities
-	npcController := game.NewNPCController(gameManager, gameMap, physics.PhysicsManagerInstance)
-	npcController.Start()
-
-	// Set the number of NPC tanks to spawn
+	// Set the number of NPC tanks to spawn per room
 	// Read from environment variable or default to 10
 	numNPCsStr := os.Getenv("NUM_NPCS")
 	numNPCs := 10 // Default to 10 NPCs for more exciting gameplay
@@ -139,46 +123,158 @@ func main() {
 		if val, err := strconv.Atoi(numNPCsStr); err == nil && val > 0 {
 			numNPCs = val
 			// Cap the number of NPCs to prevent performance issues
-			const MAX_NPCS = 10
-			if numNPCs > MAX_NPCS {
+			if numNPCs > room.MaxNPCs {
 				log.Warn("Requested NPCs exceeds maximum limit",
-					"requested", numNPCs, "max", MAX_NPCS, "using", MAX_NPCS)
-				numNPCs = MAX_NPCS
+					"requested", numNPCs, "max", room.MaxNPCs, "using", room.MaxNPCs)
+				numNPCs = room.MaxNPCs
 			}
 		}
 	}
 
-	// Spawn NPCs in a loop
-	for i := 0; i < numNPCs; i++ {
-		// Choose a random movement pattern for each NPC
-		movementPatterns := []game.MovementPattern{
-			game.CircleMovement,
-			game.ZigzagMovement,
-			game.PatrolMovement,
-			game.RandomMovement,
+	// Set the maximum number of concurrent rooms
+	// Read from environment variable or default to 8
+	maxRooms := 8
+	if maxRoomsStr := os.Getenv("MAX_ROOMS"); maxRoomsStr != "" {
+		if val, err := strconv.Atoi(maxRoomsStr); err == nil && val > 0 {
+			maxRooms = val
 		}
-		movementPattern := movementPatterns[rand.Intn(len(movementPatterns))]
+	}
+
+	// Set how many rooms one player may create
+	// Read from environment variable or default to 2; 0 removes the limit
+	maxRoomsPerPlayer := room.DefaultMaxRoomsPerOwner
+	if val, err := strconv.Atoi(os.Getenv("MAX_ROOMS_PER_PLAYER")); err == nil && val >= 0 {
+		maxRoomsPerPlayer = val
+	}
+
+	// Set the default game mode for rooms
+	// Read from environment variables or default to free-for-all
+	modeConfig := game.ModeConfig{
+		Type:         game.GameModeType(os.Getenv("GAME_MODE")),
+		FriendlyFire: os.Getenv("FRIENDLY_FIRE") == "true",
+	}
+	if _, err := game.NewGameMode(modeConfig); err != nil {
+		log.Warn("Invalid GAME_MODE, using free-for-all", "requested", modeConfig.Type, "allowed", "ffa, tdm, ctf or koth")
+		modeConfig.Type = game.ModeFreeForAll
+	}
+
+	// Set the default match lifecycle for rooms
+	// Read from environment variables; unset values use the lifecycle defaults
+	lifecycleConfig := game.LifecycleConfig{}
+	if val, err := strconv.Atoi(os.Getenv("MIN_PLAYERS")); err == nil && val > 0 {
+		lifecycleConfig.MinPlayers = val
+	}
+	if val, err := strconv.Atoi(os.Getenv("WARMUP_TIME")); err == nil && val > 0 {
+		lifecycleConfig.WarmupTime = val
+	}
+	if val, err := strconv.Atoi(os.Getenv("POSTMATCH_TIME")); err == nil && val > 0 {
+		lifecycleConfig.PostMatchTime = val
+	}
+	if maps := os.Getenv("MAP_ROTATION"); maps != "" {
+		lifecycleConfig.Maps = strings.Split(maps, ",")
+	}
+
+	// Set the default map generation config for rooms
+	// Read the seed from the environment or use the standard map
+	mapConfig := game.DefaultMapConfig()
+	if seedStr := os.Getenv("MAP_SEED"); seedStr != "" {
+		if val, err := strconv.ParseInt(seedStr, 10, 64); err == nil {
+			mapConfig.Seed = val
+		} else {
+			log.Warn("Invalid MAP_SEED, using the standard map", "requested", seedStr)
+		}
+	}
 
-		// Spawn the NPC with a random pattern
-		npcController.SpawnNPC("Bot", movementPattern)
-		log.Debug("Spawned NPC", "count", fmt.Sprintf("%d/%d", i+1, numNPCs), "pattern", movementPattern)
+	// Load hand-authored maps rooms can be played on by name
+	// Read the directory from the environment or default to maps
+	mapsDir := os.Getenv("MAPS_DIR")
+	if mapsDir == "" {
+		mapsDir = "maps"
+	}
+	mapFiles, err := game.LoadMapDir(mapsDir)
+	if err != nil {
+		log.Fatal("Failed to load maps", "dir", mapsDir, "error", err)
+	}
+	log.Info("Loaded maps", "dir", mapsDir, "maps", game.MapNames(mapFiles))
+
+	// Career stats and match history are stored in PocketBase
+	careerStats := stats.NewStore(app)
+
+	// Initialize the room registry; each room owns its game manager, physics, NPCs and map
+	rooms, err := room.NewRegistry(ctx, kv, careerStats, room.Config{
+		TickRate:  tickRate,
+		NumNPCs:   numNPCs,
+		Mode:      modeConfig,
+		Lifecycle: lifecycleConfig,
+		Map:       mapConfig,
+		MapName:   os.Getenv("DEFAULT_MAP"),
+	}, maxRooms)
+	if err != nil {
+		log.Fatal("Failed to initialize room registry", "error", err)
+	}
+	rooms.SetMaps(mapFiles)
+	rooms.SetMaxRoomsPerOwner(maxRoomsPerPlayer)
+
+	// Broadcast kills, shots, joins and phase changes of every room on its own subject
+	rooms.SetEvents(nc)
+
+	// Record every match into JetStream for replays
+	// Read the retention and per-replay size limit from the environment; unset values use the defaults
+	replayConfig := replay.Config{}
+	if val, err := strconv.Atoi(os.Getenv("REPLAY_RETENTION_HOURS")); err == nil && val > 0 {
+		replayConfig.Retention = time.Duration(val) * time.Hour
+	}
+	if val, err := strconv.Atoi(os.Getenv("REPLAY_MAX_MB")); err == nil && val > 0 {
+		replayConfig.MaxBytes = int64(val) << 20
+	}
+	replays := replay.NewService(js, app, replayConfig)
+	rooms.SetReplays(replays)
+
+	// Set the lobby size of matchmade rooms; empty slots are filled with NPCs
+	// Read from environment variable or default to 8
+	lobbySize := matchmaking.DefaultLobbySize
+	if val, err := strconv.Atoi(os.Getenv("LOBBY_SIZE")); err == nil && val > 1 {
+		lobbySize = val
+	}
+
+	// Matchmaking groups queued players of similar rating into new rooms
+	matchmaker := matchmaking.New(rooms, careerStats, matchmaking.Config{
+		LobbySize: lobbySize,
+		Mode:      modeConfig,
+	})
+
+	// Create the default room players join from the index page
+	if _, err := rooms.Create(room.DefaultRoomID, room.Config{Name: "Main Arena"}); err != nil {
+		log.Fatal("Failed to create default room", "error", err)
 	}
-	log.Info("NPC tanks spawned", "count", numNPCs, "note", "can be changed with NUM_NPCS env var")
 
 	log.Info("System status", 
 		"nats", "Running",
 		"jetstream", "Ready",
 		"kvstore", "Connected",
-		"gamemanager", "Initialized",
-		"physics", "Running",
-		"npccontroller", "Running",
-		"activeNPCs", numNPCs)
+		"rooms", 1,
+		"maxRooms", maxRooms,
+		"tickRate", tickRate,
+		"mode", modeConfig.Type,
+		"npcsPerRoom", numNPCs)
 
 	middleware.AddCookieSessionMiddleware(*app)
 
 	app.OnServe().BindFunc(func(se *core.ServeEvent) error {
+		// Write buffered career stats once the database is ready
+		go careerStats.Run(ctx)
+
+		// Group queued players into rooms
+		go matchmaker.Run(ctx)
+
+		// Close rooms players created once they are left empty
+		go rooms.Run(ctx)
+
+		// Delete expired replays
+		go replays.Run(ctx)
+
 		// Setup our custom routes first with game manager
-		err := routes.SetupRoutes(ctx, se.Router, gameManager)
+		err := routes.SetupRoutes(ctx, se.Router, rooms, matchmaker, replays)
 		if err != nil {
 			return err
 		}
@@ -189,6 +285,12 @@ func main() {
 		return se.Next()
 	})
 
+	// Don't lose buffered career stats on shutdown
+	app.OnTerminate().BindFunc(func(e *core.TerminateEvent) error {
+		careerStats.Flush()
+		return e.Next()
+	})
+
 	if err := app.Start(); err != nil {
 		log.Fatal("Application failed to start", "error", err)
 	}
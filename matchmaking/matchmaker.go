@@ -0,0 +1,344 @@
+package matchmaking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"tank-game/game"
+	"tank-game/game/room"
+	"tank-game/stats"
+)
+
+// Matchmaking timings
+const (
+	matchInterval  = time.Second      // How often queued players are grouped
+	ticketTTL      = time.Minute      // How long a matched ticket stays readable by its player
+	emptyRoomGrace = 2 * time.Minute  // How long a matchmade room may stay without players
+	defaultMaxWait = 30 * time.Second // How long a player waits before NPCs fill the lobby
+)
+
+// Rating windows: players within baseWindow of each other are grouped right away
+// and the window widens the longer the oldest player in a lobby has been waiting
+const (
+	baseWindow   = 100.0
+	windowGrowth = 20.0 // Rating points per second waited
+)
+
+// DefaultLobbySize is the number of tanks, players and NPCs, in a matchmade room
+const DefaultLobbySize = 8
+
+// ErrNotQueued is returned for players without a matchmaking ticket
+var ErrNotQueued = errors.New("not in the matchmaking queue")
+
+// Ratings looks up player skill ratings
+type Ratings interface {
+	Rating(userID string) (stats.Rating, error)
+}
+
+// Config holds the matchmaking settings
+type Config struct {
+	LobbySize int             // Tanks per matchmade room
+	MaxWait   time.Duration   // Wait before a lobby is filled up with NPCs
+	Mode      game.ModeConfig // Game mode of matchmade rooms
+}
+
+// TicketStatus is where a ticket is in matchmaking
+type TicketStatus string
+
+const (
+	TicketQueued  TicketStatus = "queued"
+	TicketMatched TicketStatus = "matched"
+)
+
+// Ticket is a player's place in the matchmaking queue
+type Ticket struct {
+	UserID    string       `json:"-"`
+	Rating    float64      `json:"rating"`
+	Status    TicketStatus `json:"status"`
+	QueuedAt  time.Time    `json:"queuedAt"`
+	RoomID    string       `json:"room,omitempty"` // Room to join once matched
+	MatchedAt time.Time    `json:"matchedAt,omitempty"`
+
+	starting bool // Grouped into a lobby whose room is being created
+}
+
+// Matchmaker groups queued players of similar rating into new rooms
+type Matchmaker struct {
+	rooms   *room.Registry
+	ratings Ratings
+	config  Config
+	mutex   sync.Mutex
+	tickets map[string]*Ticket   // Tickets by user ID
+	created map[string]time.Time // Rooms created for lobbies and when
+}
+
+// New creates a matchmaker creating rooms in the registry. Zero config values use the defaults.
+func New(rooms *room.Registry, ratings Ratings, config Config) *Matchmaker {
+	if config.LobbySize < 2 {
+		config.LobbySize = DefaultLobbySize
+	}
+	if config.MaxWait <= 0 {
+		config.MaxWait = defaultMaxWait
+	}
+
+	return &Matchmaker{
+		rooms:   rooms,
+		ratings: ratings,
+		config:  config,
+		tickets: make(map[string]*Ticket),
+		created: make(map[string]time.Time),
+	}
+}
+
+// Run groups queued players until the context is cancelled
+func (mm *Matchmaker) Run(ctx context.Context) {
+	ticker := time.NewTicker(matchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			mm.match(now)
+			mm.reap(now)
+		}
+	}
+}
+
+// Join queues a player. Joining again while queued or matched returns the existing ticket.
+func (mm *Matchmaker) Join(userID string) (Ticket, error) {
+	mm.mutex.Lock()
+	if ticket, exists := mm.tickets[userID]; exists {
+		mm.mutex.Unlock()
+		return *ticket, nil
+	}
+	mm.mutex.Unlock()
+
+	rating, err := mm.ratings.Rating(userID)
+	if err != nil {
+		return Ticket{}, fmt.Errorf("failed to get rating: %v", err)
+	}
+
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	ticket, exists := mm.tickets[userID]
+	if !exists {
+		ticket = &Ticket{
+			UserID:   userID,
+			Rating:   rating.Rating,
+			Status:   TicketQueued,
+			QueuedAt: time.Now(),
+		}
+		mm.tickets[userID] = ticket
+		log.Info("Player queued for matchmaking", "playerID", userID, "rating", rating.Rating, "queued", mm.queuedLocked())
+	}
+	return *ticket, nil
+}
+
+// Leave removes a player's ticket
+func (mm *Matchmaker) Leave(userID string) error {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	if _, exists := mm.tickets[userID]; !exists {
+		return ErrNotQueued
+	}
+	delete(mm.tickets, userID)
+	return nil
+}
+
+// Status returns a player's ticket
+func (mm *Matchmaker) Status(userID string) (Ticket, error) {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	ticket, exists := mm.tickets[userID]
+	if !exists {
+		return Ticket{}, ErrNotQueued
+	}
+	return *ticket, nil
+}
+
+// queuedLocked counts tickets still waiting for a lobby. Caller must hold the mutex.
+func (mm *Matchmaker) queuedLocked() int {
+	queued := 0
+	for _, ticket := range mm.tickets {
+		if ticket.Status == TicketQueued {
+			queued++
+		}
+	}
+	return queued
+}
+
+// match forms lobbies from the queue, oldest tickets first. A lobby is started once it is
+// full, or with NPCs filling the empty slots once its oldest player has waited MaxWait.
+// Rooms are created without the mutex held so joins and status polls never wait on them.
+func (mm *Matchmaker) match(now time.Time) {
+	mm.mutex.Lock()
+	lobbies := mm.formLobbiesLocked(now)
+	mm.mutex.Unlock()
+
+	for i, lobby := range lobbies {
+		if err := mm.startLobby(lobby, now); err != nil {
+			// Put everyone not yet matched back in the queue and retry on the next pass
+			log.Error("Failed to start matchmade room", "players", len(lobby), "error", err)
+			mm.requeue(lobbies[i:])
+			return
+		}
+	}
+}
+
+// formLobbiesLocked groups the queue into lobbies ready to start and reserves their
+// tickets so the next pass does not group them again. Caller must hold the mutex.
+func (mm *Matchmaker) formLobbiesLocked(now time.Time) [][]*Ticket {
+	var queue []*Ticket
+	for _, ticket := range mm.tickets {
+		if ticket.Status == TicketQueued && !ticket.starting {
+			queue = append(queue, ticket)
+		}
+	}
+	sort.Slice(queue, func(i, j int) bool {
+		return queue[i].QueuedAt.Before(queue[j].QueuedAt)
+	})
+
+	var lobbies [][]*Ticket
+	grouped := make(map[string]bool)
+	for _, anchor := range queue {
+		if grouped[anchor.UserID] {
+			continue
+		}
+
+		// Closest ratings within the window, the anchor first
+		waited := now.Sub(anchor.QueuedAt)
+		window := baseWindow + windowGrowth*waited.Seconds()
+		var candidates []*Ticket
+		for _, ticket := range queue {
+			if !grouped[ticket.UserID] && math.Abs(ticket.Rating-anchor.Rating) <= window {
+				candidates = append(candidates, ticket)
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return math.Abs(candidates[i].Rating-anchor.Rating) < math.Abs(candidates[j].Rating-anchor.Rating)
+		})
+		if len(candidates) > mm.config.LobbySize {
+			candidates = candidates[:mm.config.LobbySize]
+		}
+
+		if len(candidates) < mm.config.LobbySize && waited < mm.config.MaxWait {
+			continue
+		}
+
+		for _, ticket := range candidates {
+			grouped[ticket.UserID] = true
+			ticket.starting = true
+		}
+		lobbies = append(lobbies, candidates)
+	}
+	return lobbies
+}
+
+// startLobby creates a room for a lobby and backfills the empty slots with NPCs
+// as strong as the lobby's average rating. The lobby's tickets must be reserved
+// by formLobbiesLocked; players who left while the room was created are skipped.
+func (mm *Matchmaker) startLobby(lobby []*Ticket, now time.Time) error {
+	var total float64
+	for _, ticket := range lobby {
+		total += ticket.Rating
+	}
+	average := total / float64(len(lobby))
+
+	// NPCs come from the backfill, not the room defaults
+	gameRoom, err := mm.rooms.Create("", room.Config{
+		Name: fmt.Sprintf("Ranked %.0f", average),
+		Mode: mm.config.Mode,
+	})
+	if err != nil {
+		return err
+	}
+
+	backfilled := gameRoom.Backfill(mm.config.LobbySize-len(lobby), Difficulty(average))
+
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	mm.created[gameRoom.ID] = now
+	for _, ticket := range lobby {
+		ticket.starting = false
+		if mm.tickets[ticket.UserID] != ticket {
+			continue
+		}
+		ticket.Status = TicketMatched
+		ticket.RoomID = gameRoom.ID
+		ticket.MatchedAt = now
+	}
+
+	log.Info("Matchmade room started", "room", gameRoom.ID, "players", len(lobby), "npcs", backfilled, "rating", average)
+	return nil
+}
+
+// requeue releases the tickets of lobbies that were not started
+func (mm *Matchmaker) requeue(lobbies [][]*Ticket) {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	for _, lobby := range lobbies {
+		for _, ticket := range lobby {
+			ticket.starting = false
+		}
+	}
+}
+
+// reap drops matched tickets nobody picked up and closes matchmade rooms left empty
+func (mm *Matchmaker) reap(now time.Time) {
+	mm.mutex.Lock()
+	for userID, ticket := range mm.tickets {
+		if ticket.Status == TicketMatched && now.Sub(ticket.MatchedAt) > ticketTTL {
+			delete(mm.tickets, userID)
+		}
+	}
+	var expired []string
+	for roomID, createdAt := range mm.created {
+		if now.Sub(createdAt) > emptyRoomGrace {
+			expired = append(expired, roomID)
+		}
+	}
+	mm.mutex.Unlock()
+
+	for _, roomID := range expired {
+		gameRoom, exists := mm.rooms.Get(roomID)
+		if !exists {
+			mm.forget(roomID)
+			continue
+		}
+		if gameRoom.Info().Players > 0 {
+			continue
+		}
+
+		if err := mm.rooms.Remove(roomID); err != nil {
+			log.Error("Failed to remove empty matchmade room", "room", roomID, "error", err)
+		}
+		mm.forget(roomID)
+		log.Info("Closed empty matchmade room", "room", roomID)
+	}
+}
+
+// forget stops tracking a matchmade room
+func (mm *Matchmaker) forget(roomID string) {
+	mm.mutex.Lock()
+	delete(mm.created, roomID)
+	mm.mutex.Unlock()
+}
+
+// Difficulty maps a rating to an NPC difficulty level: 1000 and below is the
+// easiest, 2000 and above the hardest and the default rating of 1500 is medium
+func Difficulty(rating float64) float64 {
+	return math.Max(0, math.Min(1, (rating-1000)/1000))
+}
@@ -0,0 +1,97 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Collections for match history and lifetime player statistics
+func init() {
+	m.Register(func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId("_pb_users_auth_")
+		if err != nil {
+			return err
+		}
+
+		// One record per finished match
+		matches := core.NewBaseCollection("matches")
+		matches.ListRule = types.Pointer("")
+		matches.ViewRule = types.Pointer("")
+		matches.Fields.Add(
+			&core.TextField{Name: "room", Required: true},
+			&core.NumberField{Name: "number", OnlyInt: true},
+			&core.TextField{Name: "mode", Required: true},
+			&core.TextField{Name: "map"},
+			&core.DateField{Name: "started_at"},
+			&core.DateField{Name: "ended_at"},
+			&core.TextField{Name: "winner"},
+			&core.JSONField{Name: "teams"},
+			&core.AutodateField{Name: "created", OnCreate: true},
+		)
+		matches.AddIndex("idx_matches_ended_at", false, "ended_at", "")
+		if err := app.Save(matches); err != nil {
+			return err
+		}
+
+		// One record per player per finished match; NPCs have no user
+		matchPlayers := core.NewBaseCollection("match_players")
+		matchPlayers.ListRule = types.Pointer("")
+		matchPlayers.ViewRule = types.Pointer("")
+		matchPlayers.Fields.Add(
+			&core.RelationField{Name: "match", CollectionId: matches.Id, CascadeDelete: true, MaxSelect: 1, Required: true},
+			&core.RelationField{Name: "user", CollectionId: users.Id, MaxSelect: 1},
+			&core.TextField{Name: "player_id", Required: true},
+			&core.TextField{Name: "name"},
+			&core.TextField{Name: "team"},
+			&core.NumberField{Name: "kills", OnlyInt: true},
+			&core.NumberField{Name: "deaths", OnlyInt: true},
+			&core.NumberField{Name: "score", OnlyInt: true},
+			&core.NumberField{Name: "shots_fired", OnlyInt: true},
+			&core.NumberField{Name: "hits", OnlyInt: true},
+			&core.NumberField{Name: "damage_dealt", OnlyInt: true},
+			&core.NumberField{Name: "damage_taken", OnlyInt: true},
+			&core.NumberField{Name: "playtime", OnlyInt: true},
+			&core.BoolField{Name: "won"},
+			&core.BoolField{Name: "left"},
+			&core.AutodateField{Name: "created", OnCreate: true},
+		)
+		matchPlayers.AddIndex("idx_match_players_user", false, "user", "")
+		matchPlayers.AddIndex("idx_match_players_match", false, "`match`", "")
+		if err := app.Save(matchPlayers); err != nil {
+			return err
+		}
+
+		// Lifetime totals, one record per user
+		playerStats := core.NewBaseCollection("player_stats")
+		playerStats.ListRule = types.Pointer("")
+		playerStats.ViewRule = types.Pointer("")
+		playerStats.Fields.Add(
+			&core.RelationField{Name: "user", CollectionId: users.Id, CascadeDelete: true, MaxSelect: 1, Required: true},
+			&core.NumberField{Name: "kills", OnlyInt: true},
+			&core.NumberField{Name: "deaths", OnlyInt: true},
+			&core.NumberField{Name: "shots_fired", OnlyInt: true},
+			&core.NumberField{Name: "hits", OnlyInt: true},
+			&core.NumberField{Name: "damage_dealt", OnlyInt: true},
+			&core.NumberField{Name: "damage_taken", OnlyInt: true},
+			&core.NumberField{Name: "playtime", OnlyInt: true},
+			&core.NumberField{Name: "matches", OnlyInt: true},
+			&core.NumberField{Name: "wins", OnlyInt: true},
+			&core.AutodateField{Name: "created", OnCreate: true},
+			&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
+		)
+		playerStats.AddIndex("idx_player_stats_user", true, "user", "")
+		return app.Save(playerStats)
+	}, func(app core.App) error {
+		for _, name := range []string{"player_stats", "match_players", "matches"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				return err
+			}
+			if err := app.Delete(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,78 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Seasons and the incrementally maintained leaderboard entries
+func init() {
+	m.Register(func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId("_pb_users_auth_")
+		if err != nil {
+			return err
+		}
+
+		// Seasons are managed from the dashboard; the one covering the current time is active
+		seasons := core.NewBaseCollection("seasons")
+		seasons.ListRule = types.Pointer("")
+		seasons.ViewRule = types.Pointer("")
+		seasons.Fields.Add(
+			&core.TextField{Name: "name", Required: true, Presentable: true},
+			&core.DateField{Name: "starts_at", Required: true},
+			&core.DateField{Name: "ends_at", Required: true},
+			&core.AutodateField{Name: "created", OnCreate: true},
+		)
+		seasons.AddIndex("idx_seasons_starts_at", false, "starts_at", "")
+		if err := app.Save(seasons); err != nil {
+			return err
+		}
+
+		// Start the first season right away
+		now := time.Now().UTC()
+		first := core.NewRecord(seasons)
+		first.Set("name", "Season 1")
+		first.Set("starts_at", now)
+		first.Set("ends_at", now.AddDate(0, 3, 0))
+		if err := app.Save(first); err != nil {
+			return err
+		}
+
+		// One entry per user per leaderboard period, updated as matches finish
+		entries := core.NewBaseCollection("leaderboard_entries")
+		entries.ListRule = types.Pointer("")
+		entries.ViewRule = types.Pointer("")
+		entries.Fields.Add(
+			&core.RelationField{Name: "user", CollectionId: users.Id, CascadeDelete: true, MaxSelect: 1, Required: true},
+			&core.TextField{Name: "callsign"},
+			&core.SelectField{Name: "period", Values: []string{"daily", "weekly", "season"}, MaxSelect: 1, Required: true},
+			&core.TextField{Name: "period_key", Required: true},
+			&core.NumberField{Name: "kills", OnlyInt: true},
+			&core.NumberField{Name: "deaths", OnlyInt: true},
+			&core.NumberField{Name: "shots_fired", OnlyInt: true},
+			&core.NumberField{Name: "hits", OnlyInt: true},
+			&core.NumberField{Name: "matches", OnlyInt: true},
+			&core.NumberField{Name: "wins", OnlyInt: true},
+			&core.NumberField{Name: "kd"},
+			&core.NumberField{Name: "accuracy"},
+			&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
+		)
+		entries.AddIndex("idx_leaderboard_entries_user_period", true, "user, period, period_key", "")
+		entries.AddIndex("idx_leaderboard_entries_period", false, "period, period_key", "")
+		return app.Save(entries)
+	}, func(app core.App) error {
+		for _, name := range []string{"leaderboard_entries", "seasons"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				return err
+			}
+			if err := app.Delete(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Glicko-2 skill ratings, one record per rated user. Only the server writes
+// them, so they live outside the users collection that users can update.
+func init() {
+	m.Register(func(app core.App) error {
+		users, err := app.FindCollectionByNameOrId("_pb_users_auth_")
+		if err != nil {
+			return err
+		}
+
+		playerRatings := core.NewBaseCollection("player_ratings")
+		playerRatings.ListRule = types.Pointer("")
+		playerRatings.ViewRule = types.Pointer("")
+		playerRatings.Fields.Add(
+			&core.RelationField{Name: "user", CollectionId: users.Id, CascadeDelete: true, MaxSelect: 1, Required: true},
+			&core.NumberField{Name: "rating"},
+			&core.NumberField{Name: "deviation"},
+			&core.NumberField{Name: "volatility"},
+			&core.AutodateField{Name: "created", OnCreate: true},
+			&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
+		)
+		playerRatings.AddIndex("idx_player_ratings_user", true, "user", "")
+		playerRatings.AddIndex("idx_player_ratings_rating", false, "rating", "")
+		return app.Save(playerRatings)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("player_ratings")
+		if err != nil {
+			return err
+		}
+		return app.Delete(collection)
+	})
+}
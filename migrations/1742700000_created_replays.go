@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Index of recorded matches; the frames themselves live in a JetStream stream per replay
+func init() {
+	m.Register(func(app core.App) error {
+		replays := core.NewBaseCollection("replays")
+		replays.ListRule = types.Pointer("")
+		replays.ViewRule = types.Pointer("")
+		replays.Fields.Add(
+			&core.TextField{Name: "room", Required: true},
+			&core.NumberField{Name: "number", OnlyInt: true},
+			&core.TextField{Name: "mode", Required: true},
+			&core.TextField{Name: "map"},
+			&core.TextField{Name: "stream", Required: true},
+			&core.TextField{Name: "status", Required: true},
+			&core.DateField{Name: "started_at"},
+			&core.DateField{Name: "ended_at"},
+			&core.TextField{Name: "winner"},
+			&core.JSONField{Name: "players"},
+			&core.NumberField{Name: "frames", OnlyInt: true},
+			&core.NumberField{Name: "bytes", OnlyInt: true},
+			&core.AutodateField{Name: "created", OnCreate: true},
+			&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true},
+		)
+		replays.AddIndex("idx_replays_started_at", false, "started_at", "")
+		replays.AddIndex("idx_replays_room", false, "room", "")
+		return app.Save(replays)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("replays")
+		if err != nil {
+			return err
+		}
+		return app.Delete(collection)
+	})
+}
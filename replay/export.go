@@ -0,0 +1,60 @@
+package replay
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Export file format
+const (
+	ExportFormat  = "shell-shock-replay"
+	ExportVersion = 1
+)
+
+// ExportHeader is the first line of an export file
+type ExportHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	Info
+}
+
+// Export writes a replay as a gzipped file of newline-delimited JSON: a header
+// line with the index entry, then one line per frame in recording order
+func (s *Service) Export(ctx context.Context, id string, w io.Writer) error {
+	info, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(w)
+	header, err := json.Marshal(ExportHeader{Format: ExportFormat, Version: ExportVersion, Info: info})
+	if err != nil {
+		return fmt.Errorf("failed to encode replay header: %v", err)
+	}
+	if err := writeLine(zw, header); err != nil {
+		return err
+	}
+
+	// Frames are stored as JSON, so they are copied as they are
+	if err := s.Read(ctx, id, func(data []byte) error {
+		return writeLine(zw, data)
+	}); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write replay: %v", err)
+	}
+	return nil
+}
+
+// writeLine writes one line of an export file
+func writeLine(w io.Writer, data []byte) error {
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write replay: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tank-game/game"
+)
+
+// Playback settings
+const (
+	MinSpeed        = 0.5
+	MaxSpeed        = 4.0
+	minSendInterval = 50 * time.Millisecond // Shortest time between states sent above normal speed
+	maxFrameGap     = time.Second           // Longest wait between two states, for gaps in the recording
+	controlBuffer   = 8                     // Controls queued per viewer
+)
+
+// ControlAction is a playback command
+type ControlAction string
+
+const (
+	ActionPlay  ControlAction = "play"
+	ActionPause ControlAction = "pause"
+	ActionSeek  ControlAction = "seek"  // Jump to Tick
+	ActionSpeed ControlAction = "speed" // Play at Speed times real time
+)
+
+// Control is a playback command sent by a viewer
+type Control struct {
+	Action ControlAction `json:"action"`
+	Tick   uint64        `json:"tick,omitempty"`
+	Speed  float64       `json:"speed,omitempty"`
+}
+
+// PlaybackStatus is sent to the viewer with every state
+type PlaybackStatus struct {
+	Session   string  `json:"session"` // Viewer session controls are sent to
+	Tick      uint64  `json:"tick"`
+	FirstTick uint64  `json:"firstTick"`
+	LastTick  uint64  `json:"lastTick"`
+	Playing   bool    `json:"playing"`
+	Speed     float64 `json:"speed"`
+}
+
+// Playback steps through the states of a loaded replay
+type Playback struct {
+	Info      Info
+	Map       *game.GameMap
+	frames    []Frame // State and delta frames in recording order
+	keyframes []int   // Indexes of the state frames
+	next      int     // Index of the next frame to apply
+	state     game.GameState
+}
+
+// Load reads a replay for playback
+func (s *Service) Load(ctx context.Context, id string) (*Playback, error) {
+	info, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	frames, err := s.Frames(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Playback{Info: info}
+	for _, frame := range frames {
+		switch frame.Type {
+		case FrameMap:
+			p.Map = frame.Map.Build()
+		case FrameState:
+			p.keyframes = append(p.keyframes, len(p.frames))
+			p.frames = append(p.frames, frame)
+		case FrameDelta:
+			// Deltas before the first snapshot have no base
+			if len(p.keyframes) > 0 {
+				p.frames = append(p.frames, frame)
+			}
+		}
+	}
+	if len(p.frames) == 0 {
+		return nil, fmt.Errorf("replay %s has no states", id)
+	}
+	if p.Map == nil {
+		return nil, fmt.Errorf("replay %s has no map", id)
+	}
+
+	p.Seek(0)
+	return p, nil
+}
+
+// errStop ends a read early
+var errStop = errors.New("stop")
+
+// Map returns the map a replay was recorded on, without reading the rest of the replay
+func (s *Service) Map(ctx context.Context, id string) (*game.GameMap, error) {
+	var gameMap *game.GameMap
+	err := s.Read(ctx, id, func(data []byte) error {
+		var frame Frame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			return fmt.Errorf("failed to decode replay frame: %v", err)
+		}
+		if frame.Type == FrameMap {
+			gameMap = frame.Map.Build()
+		}
+		return errStop
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		return nil, err
+	}
+	if gameMap == nil {
+		return nil, fmt.Errorf("replay %s has no map", id)
+	}
+	return gameMap, nil
+}
+
+// FirstTick returns the tick of the first recorded state
+func (p *Playback) FirstTick() uint64 {
+	return p.frames[0].Tick
+}
+
+// LastTick returns the tick of the last recorded state
+func (p *Playback) LastTick() uint64 {
+	return p.frames[len(p.frames)-1].Tick
+}
+
+// State returns the current state
+func (p *Playback) State() game.GameState {
+	return p.state
+}
+
+// Seek moves to the last state at or before a tick, or the first state if the
+// tick is before the recording
+func (p *Playback) Seek(tick uint64) game.GameState {
+	// Start from the closest snapshot and replay the deltas after it
+	k := sort.Search(len(p.keyframes), func(i int) bool {
+		return p.frames[p.keyframes[i]].Tick > tick
+	})
+	if k > 0 {
+		k--
+	}
+	p.next = p.keyframes[k]
+	p.apply(p.frames[p.next])
+	p.next++
+
+	for p.next < len(p.frames) && p.frames[p.next].Tick <= tick {
+		p.apply(p.frames[p.next])
+		p.next++
+	}
+	return p.state
+}
+
+// Next advances to the next state. It returns false at the end of the replay.
+func (p *Playback) Next() (game.GameState, bool) {
+	if p.next >= len(p.frames) {
+		return p.state, false
+	}
+	p.apply(p.frames[p.next])
+	p.next++
+	return p.state, true
+}
+
+// peek returns the frame Next will apply
+func (p *Playback) peek() (Frame, bool) {
+	if p.next >= len(p.frames) {
+		return Frame{}, false
+	}
+	return p.frames[p.next], true
+}
+
+// apply makes a frame the current state
+func (p *Playback) apply(frame Frame) {
+	if frame.Type == FrameState {
+		p.state = *frame.State
+		return
+	}
+	p.state = game.ApplyDelta(p.state, *frame.Delta)
+}
+
+// Viewer plays a replay to one connection
+type Viewer struct {
+	id       string
+	ownerID  string
+	playback *Playback
+	controls chan Control
+	playing  bool
+	speed    float64
+}
+
+// ID returns the session controls for this viewer are sent to
+func (v *Viewer) ID() string {
+	return v.id
+}
+
+// Playback returns the replay being played
+func (v *Viewer) Playback() *Playback {
+	return v.playback
+}
+
+// Run plays the replay from the start, calling send with every state shown, until
+// the context is done or send fails. Playback pauses at the end of the replay.
+func (v *Viewer) Run(ctx context.Context, send func(state game.GameState, status PlaybackStatus) error) error {
+	p := v.playback
+	if err := send(p.State(), v.status()); err != nil {
+		return err
+	}
+
+	for {
+		// Wait for the next state in replay time, scaled by the speed
+		var wait <-chan time.Time
+		if v.playing {
+			if next, ok := p.peek(); ok {
+				gap := max(0, min(time.Duration(next.ServerTime-p.State().ServerTime)*time.Millisecond, maxFrameGap))
+				wait = time.After(time.Duration(float64(gap) / v.speed))
+			} else {
+				v.playing = false
+				if err := send(p.State(), v.status()); err != nil {
+					return err
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case control := <-v.controls:
+			v.handle(control)
+			if err := send(p.State(), v.status()); err != nil {
+				return err
+			}
+
+		case <-wait:
+			state, _ := p.Next()
+
+			// Above normal speed, skip states that would arrive faster than the send interval
+			for v.speed > 1 {
+				next, ok := p.peek()
+				if !ok || time.Duration(next.ServerTime-state.ServerTime)*time.Millisecond >= time.Duration(float64(minSendInterval)*v.speed) {
+					break
+				}
+				state, _ = p.Next()
+			}
+
+			if err := send(state, v.status()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handle applies a control to the playback
+func (v *Viewer) handle(control Control) {
+	switch control.Action {
+	case ActionPlay:
+		// Playing a finished replay starts it over
+		if _, ok := v.playback.peek(); !ok {
+			v.playback.Seek(v.playback.FirstTick())
+		}
+		v.playing = true
+	case ActionPause:
+		v.playing = false
+	case ActionSeek:
+		v.playback.Seek(control.Tick)
+	case ActionSpeed:
+		v.speed = max(MinSpeed, min(MaxSpeed, control.Speed))
+	}
+}
+
+// status describes the playback for the viewer
+func (v *Viewer) status() PlaybackStatus {
+	return PlaybackStatus{
+		Session:   v.id,
+		Tick:      v.playback.State().Tick,
+		FirstTick: v.playback.FirstTick(),
+		LastTick:  v.playback.LastTick(),
+		Playing:   v.playing,
+		Speed:     v.speed,
+	}
+}
+
+// Viewers tracks open playback sessions so controls can reach them
+type Viewers struct {
+	mutex   sync.RWMutex
+	viewers map[string]*Viewer
+	counter atomic.Uint64
+}
+
+// NewViewers creates an empty session registry
+func NewViewers() *Viewers {
+	return &Viewers{
+		viewers: make(map[string]*Viewer),
+	}
+}
+
+// Open registers a playback session for a viewer. Playback starts playing at normal speed.
+func (vs *Viewers) Open(ownerID string, playback *Playback) *Viewer {
+	viewer := &Viewer{
+		id:       fmt.Sprintf("replay_%d", vs.counter.Add(1)),
+		ownerID:  ownerID,
+		playback: playback,
+		controls: make(chan Control, controlBuffer),
+		playing:  true,
+		speed:    1,
+	}
+
+	vs.mutex.Lock()
+	vs.viewers[viewer.id] = viewer
+	vs.mutex.Unlock()
+
+	return viewer
+}
+
+// Close removes a playback session when its connection ends
+func (vs *Viewers) Close(id string) {
+	vs.mutex.Lock()
+	delete(vs.viewers, id)
+	vs.mutex.Unlock()
+}
+
+// Control forwards a control to a session. Viewers can only control their own sessions.
+func (vs *Viewers) Control(ownerID, id string, control Control) error {
+	vs.mutex.RLock()
+	viewer, exists := vs.viewers[id]
+	vs.mutex.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("replay session %s not found", id)
+	}
+	if viewer.ownerID != ownerID {
+		return fmt.Errorf("replay session %s does not belong to player %s", id, ownerID)
+	}
+
+	select {
+	case viewer.controls <- control:
+		return nil
+	default:
+		return fmt.Errorf("replay session %s is busy", id)
+	}
+}
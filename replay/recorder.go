@@ -0,0 +1,253 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/pocketbase/pocketbase/core"
+	"tank-game/game"
+)
+
+// Recorder settings
+const (
+	recorderQueueSize = 1024            // Calls buffered between the tick and the publisher
+	publishTimeout    = 5 * time.Second // Wait for outstanding frames when a replay ends
+)
+
+// recorderCall is one call of the game.ReplayRecorder interface, queued for the publisher
+type recorderCall struct {
+	match   *game.MatchState
+	gameMap *game.GameMap // Map of the match, set with match
+	state   *game.GameState
+	event   *game.MatchEvent
+	result  *game.MatchResult
+}
+
+// recording is the replay of the live match
+type recording struct {
+	record       *core.Record
+	subject      string
+	previous     *game.GameState // Last recorded state, the base of the next delta
+	lastKeyframe uint64          // Tick of the last full snapshot
+	frames       int
+	bytes        int
+	full         bool // True once the stream's size limit is reached
+}
+
+// Recorder records the matches of one room. It implements game.ReplayRecorder;
+// calls are queued and published to JetStream in the background so the tick
+// never waits on storage.
+type Recorder struct {
+	service *Service
+	roomID  string
+	calls   chan recorderCall
+	current *recording // Only used by the publisher
+}
+
+// RecordRoom creates the recorder of a room. It stops when the context is done,
+// aborting the replay of a match still live.
+func (s *Service) RecordRoom(ctx context.Context, roomID string) game.ReplayRecorder {
+	r := &Recorder{
+		service: s,
+		roomID:  roomID,
+		calls:   make(chan recorderCall, recorderQueueSize),
+	}
+	go r.run(ctx)
+	return r
+}
+
+func (r *Recorder) StartMatch(match game.MatchState, gameMap *game.GameMap) {
+	r.queue(recorderCall{match: &match, gameMap: gameMap})
+}
+
+func (r *Recorder) RecordState(state game.GameState) {
+	r.queue(recorderCall{state: &state})
+}
+
+func (r *Recorder) RecordEvent(event game.MatchEvent) {
+	r.queue(recorderCall{event: &event})
+}
+
+func (r *Recorder) EndMatch(result game.MatchResult) {
+	r.queue(recorderCall{result: &result})
+}
+
+// queue hands a call to the publisher without blocking. A dropped state only
+// widens the next delta; dropped events are lost.
+func (r *Recorder) queue(call recorderCall) {
+	select {
+	case r.calls <- call:
+	default:
+		log.Warn("Replay recorder queue full, dropping frame", "room", r.roomID)
+	}
+}
+
+// run publishes queued calls until the context is done
+func (r *Recorder) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			if r.current != nil {
+				r.finish(StatusAborted, nil)
+			}
+			return
+		case call := <-r.calls:
+			switch {
+			case call.match != nil:
+				r.start(ctx, *call.match, call.gameMap)
+			case call.state != nil:
+				r.recordState(*call.state)
+			case call.event != nil:
+				r.publish(Frame{Type: FrameEvent, Tick: call.event.Tick, ServerTime: call.event.ServerTime, Event: call.event})
+			case call.result != nil:
+				r.end(*call.result)
+			}
+		}
+	}
+}
+
+// start creates the index entry and stream of a new replay
+func (r *Recorder) start(ctx context.Context, match game.MatchState, gameMap *game.GameMap) {
+	// A match that never finished, e.g. because the mode was changed, is cut short
+	if r.current != nil {
+		r.finish(StatusAborted, nil)
+	}
+
+	collection, err := r.service.app.FindCachedCollectionByNameOrId(replaysCollection)
+	if err != nil {
+		log.Error("Error finding replays collection", "error", err)
+		return
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("room", r.roomID)
+	record.Set("number", match.Number)
+	record.Set("mode", string(match.Mode))
+	record.Set("map", match.Map)
+	record.Set("status", string(StatusRecording))
+	record.Set("started_at", time.UnixMilli(match.StartedAt).UTC())
+	record.Set("stream", "pending")
+	if err := r.service.app.Save(record); err != nil {
+		log.Error("Error creating replay", "room", r.roomID, "match", match.Number, "error", err)
+		return
+	}
+
+	// The stream is named after the record so replays are looked up by one ID
+	stream := streamName(record.Id)
+	_, err = r.service.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     stream,
+		Subjects: []string{subject(record.Id)},
+		Storage:  jetstream.FileStorage,
+		MaxAge:   r.service.config.Retention,
+		MaxBytes: r.service.config.MaxBytes,
+		Discard:  jetstream.DiscardNew, // Keep the start of a replay playable
+	})
+	if err != nil {
+		log.Error("Error creating replay stream", "replay", record.Id, "error", err)
+		if err := r.service.app.Delete(record); err != nil {
+			log.Error("Error deleting replay", "replay", record.Id, "error", err)
+		}
+		return
+	}
+
+	record.Set("stream", stream)
+	if err := r.service.app.Save(record); err != nil {
+		log.Error("Error saving replay stream", "replay", record.Id, "error", err)
+	}
+
+	r.current = &recording{record: record, subject: subject(record.Id)}
+
+	// The map goes first so a replay can be played without the room it was recorded in
+	r.publish(Frame{Type: FrameMap, ServerTime: match.StartedAt, Map: game.ExportMap(gameMap, match.Map)})
+
+	log.Info("Recording replay", "replay", record.Id, "room", r.roomID, "match", match.Number)
+}
+
+// recordState publishes a full snapshot every keyframe interval and deltas in between
+func (r *Recorder) recordState(state game.GameState) {
+	current := r.current
+	if current == nil {
+		return
+	}
+
+	frame := Frame{Tick: state.Tick, ServerTime: state.ServerTime}
+	if current.previous == nil || state.Tick-current.lastKeyframe >= uint64(r.service.config.KeyframeInterval) {
+		frame.Type = FrameState
+		frame.State = &state
+		current.lastKeyframe = state.Tick
+	} else {
+		delta := game.DiffState(*current.previous, state)
+		frame.Type = FrameDelta
+		frame.Delta = &delta
+	}
+	current.previous = &state
+
+	r.publish(frame)
+}
+
+// end publishes the result of the match and completes the replay
+func (r *Recorder) end(result game.MatchResult) {
+	if r.current == nil {
+		return
+	}
+	result.Room = r.roomID
+	r.publish(Frame{Type: FrameEnd, ServerTime: result.EndedAt, Result: &result})
+	r.finish(StatusComplete, &result)
+}
+
+// publish appends a frame to the replay stream
+func (r *Recorder) publish(frame Frame) {
+	current := r.current
+	if current == nil || current.full {
+		return
+	}
+
+	data, err := json.Marshal(frame)
+	if err != nil {
+		log.Error("Error encoding replay frame", "replay", current.record.Id, "error", err)
+		return
+	}
+	if int64(current.bytes+len(data)) > r.service.config.MaxBytes {
+		current.full = true
+		log.Warn("Replay reached its size limit, recording stopped", "replay", current.record.Id, "bytes", current.bytes)
+		return
+	}
+
+	if _, err := r.service.js.PublishAsync(current.subject, data); err != nil {
+		log.Error("Error publishing replay frame", "replay", current.record.Id, "error", err)
+		return
+	}
+	current.frames++
+	current.bytes += len(data)
+}
+
+// finish waits for outstanding frames and updates the index entry of the replay
+func (r *Recorder) finish(status Status, result *game.MatchResult) {
+	current := r.current
+	r.current = nil
+
+	select {
+	case <-r.service.js.PublishAsyncComplete():
+	case <-time.After(publishTimeout):
+		log.Warn("Timed out waiting for replay frames to be stored", "replay", current.record.Id)
+	}
+
+	record := current.record
+	record.Set("status", string(status))
+	record.Set("frames", current.frames)
+	record.Set("bytes", current.bytes)
+	if result != nil {
+		record.Set("ended_at", time.UnixMilli(result.EndedAt).UTC())
+		record.Set("winner", result.Winner)
+		record.Set("players", result.Players)
+	}
+	if err := r.service.app.Save(record); err != nil {
+		log.Error("Error saving replay", "replay", record.Id, "error", err)
+		return
+	}
+
+	log.Info("Replay recorded", "replay", record.Id, "status", status, "frames", current.frames, "bytes", current.bytes)
+}
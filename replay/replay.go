@@ -0,0 +1,300 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"tank-game/game"
+)
+
+// replaysCollection indexes the recorded matches
+const replaysCollection = "replays"
+
+// Replay defaults
+const (
+	defaultRetention        = 7 * 24 * time.Hour // How long replays are kept
+	defaultMaxBytes         = 64 << 20           // Size limit of one replay's stream
+	defaultKeyframeInterval = 100                // Ticks between full snapshots, 5 seconds at 20Hz
+	pruneInterval           = time.Hour          // How often expired replays are deleted
+	fetchBatch              = 256                // Frames read from a stream per request
+)
+
+// Status is where a replay is in its recording
+type Status string
+
+const (
+	StatusRecording Status = "recording" // The match is still live
+	StatusComplete  Status = "complete"  // The match finished
+	StatusAborted   Status = "aborted"   // Recording stopped before the match finished
+)
+
+// ErrNotFound is returned for replays that don't exist or have expired
+var ErrNotFound = errors.New("replay not found")
+
+// FrameType identifies what a frame of a replay holds
+type FrameType string
+
+const (
+	FrameMap   FrameType = "map"   // Map the match was played on, the first frame
+	FrameState FrameType = "state" // Full snapshot, a point playback can start from
+	FrameDelta FrameType = "delta" // Changes since the previous state or delta frame
+	FrameEvent FrameType = "event" // Discrete event
+	FrameEnd   FrameType = "end"   // Result of the finished match
+)
+
+// Frame is one message of a replay stream. Only the field of the frame's type is set.
+type Frame struct {
+	Type       FrameType         `json:"type"`
+	Tick       uint64            `json:"tick"`
+	ServerTime int64             `json:"t"`
+	State      *game.GameState   `json:"state,omitempty"`
+	Delta      *game.StateDelta  `json:"delta,omitempty"`
+	Event      *game.MatchEvent  `json:"event,omitempty"`
+	Result     *game.MatchResult `json:"result,omitempty"`
+	Map        *game.MapFile     `json:"map,omitempty"`
+}
+
+// Info is the index entry of a replay
+type Info struct {
+	ID        string              `json:"id"`
+	Room      string              `json:"room"`
+	Number    int                 `json:"number"`
+	Mode      game.GameModeType   `json:"mode"`
+	Map       string              `json:"map"`
+	Status    Status              `json:"status"`
+	StartedAt int64               `json:"startedAt"`
+	EndedAt   int64               `json:"endedAt,omitempty"`
+	Winner    string              `json:"winner,omitempty"`
+	Players   []game.PlayerResult `json:"players,omitempty"`
+	Frames    int                 `json:"frames"`
+	Bytes     int                 `json:"bytes"`
+}
+
+// Config holds the replay settings. Zero values use the defaults.
+type Config struct {
+	Retention        time.Duration // How long replays are kept before they are deleted
+	MaxBytes         int64         // Size limit of one replay's stream; recording stops when it is reached
+	KeyframeInterval int           // Ticks between full snapshots; the ticks in between are recorded as deltas
+}
+
+// withDefaults fills in zero values
+func (c Config) withDefaults() Config {
+	if c.Retention <= 0 {
+		c.Retention = defaultRetention
+	}
+	if c.MaxBytes <= 0 {
+		c.MaxBytes = defaultMaxBytes
+	}
+	if c.KeyframeInterval <= 0 {
+		c.KeyframeInterval = defaultKeyframeInterval
+	}
+	return c
+}
+
+// Service records matches into JetStream, one stream per replay, and indexes
+// them in PocketBase
+type Service struct {
+	js     jetstream.JetStream
+	app    core.App
+	config Config
+}
+
+// NewService creates a replay service
+func NewService(js jetstream.JetStream, app core.App, config Config) *Service {
+	return &Service{
+		js:     js,
+		app:    app,
+		config: config.withDefaults(),
+	}
+}
+
+// streamName returns the JetStream stream a replay is stored in
+func streamName(id string) string {
+	return "REPLAY_" + id
+}
+
+// subject returns the subject a replay's frames are published on
+func subject(id string) string {
+	return "replays." + id
+}
+
+// Run deletes expired replays periodically until the context is done. Replays
+// left recording by a previous run are marked aborted first.
+func (s *Service) Run(ctx context.Context) {
+	s.abortStale()
+	s.prune(ctx)
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.prune(ctx)
+		}
+	}
+}
+
+// abortStale marks replays that were recording when the server stopped
+func (s *Service) abortStale() {
+	records, err := s.app.FindRecordsByFilter(replaysCollection, "status = {:status}", "", 0, 0, dbx.Params{"status": string(StatusRecording)})
+	if err != nil {
+		log.Error("Error finding unfinished replays", "error", err)
+		return
+	}
+	for _, record := range records {
+		record.Set("status", string(StatusAborted))
+		if err := s.app.Save(record); err != nil {
+			log.Error("Error aborting unfinished replay", "replay", record.Id, "error", err)
+		}
+	}
+}
+
+// prune deletes replays older than the retention period along with their streams
+func (s *Service) prune(ctx context.Context) {
+	cutoff := time.Now().Add(-s.config.Retention).UTC()
+	records, err := s.app.FindRecordsByFilter(replaysCollection, "started_at < {:cutoff}", "", 0, 0, dbx.Params{"cutoff": cutoff})
+	if err != nil {
+		log.Error("Error finding expired replays", "error", err)
+		return
+	}
+
+	for _, record := range records {
+		if err := s.js.DeleteStream(ctx, record.GetString("stream")); err != nil && !errors.Is(err, jetstream.ErrStreamNotFound) {
+			log.Error("Error deleting replay stream", "replay", record.Id, "error", err)
+			continue
+		}
+		if err := s.app.Delete(record); err != nil {
+			log.Error("Error deleting replay", "replay", record.Id, "error", err)
+		}
+	}
+	if len(records) > 0 {
+		log.Info("Pruned expired replays", "count", len(records))
+	}
+}
+
+// Get returns the index entry of a replay
+func (s *Service) Get(id string) (Info, error) {
+	record, err := s.app.FindRecordById(replaysCollection, id)
+	if err != nil {
+		return Info{}, ErrNotFound
+	}
+	return infoFromRecord(record), nil
+}
+
+// List returns the most recent replays, newest first, optionally of one room only
+func (s *Service) List(roomID string, limit int) ([]Info, error) {
+	filter, params := "", dbx.Params{}
+	if roomID != "" {
+		filter, params = "room = {:room}", dbx.Params{"room": roomID}
+	}
+	records, err := s.app.FindRecordsByFilter(replaysCollection, filter, "-started_at", limit, 0, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replays: %v", err)
+	}
+
+	infos := make([]Info, len(records))
+	for i, record := range records {
+		infos[i] = infoFromRecord(record)
+	}
+	return infos, nil
+}
+
+// infoFromRecord reads the index entry of a replay from its record
+func infoFromRecord(record *core.Record) Info {
+	info := Info{
+		ID:        record.Id,
+		Room:      record.GetString("room"),
+		Number:    record.GetInt("number"),
+		Mode:      game.GameModeType(record.GetString("mode")),
+		Map:       record.GetString("map"),
+		Status:    Status(record.GetString("status")),
+		StartedAt: record.GetDateTime("started_at").Time().UnixMilli(),
+		Winner:    record.GetString("winner"),
+		Frames:    record.GetInt("frames"),
+		Bytes:     record.GetInt("bytes"),
+	}
+	if endedAt := record.GetDateTime("ended_at"); !endedAt.IsZero() {
+		info.EndedAt = endedAt.Time().UnixMilli()
+	}
+	if err := record.UnmarshalJSONField("players", &info.Players); err != nil {
+		log.Warn("Error reading replay players", "replay", record.Id, "error", err)
+	}
+	return info
+}
+
+// Read calls fn with every frame of a replay in order, as stored
+func (s *Service) Read(ctx context.Context, id string, fn func(data []byte) error) error {
+	stream, err := s.js.Stream(ctx, streamName(id))
+	if err != nil {
+		if errors.Is(err, jetstream.ErrStreamNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to open replay stream: %v", err)
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read replay stream info: %v", err)
+	}
+	if info.State.Msgs == 0 {
+		return nil
+	}
+
+	consumer, err := s.js.OrderedConsumer(ctx, streamName(id), jetstream.OrderedConsumerConfig{})
+	if err != nil {
+		return fmt.Errorf("failed to create replay consumer: %v", err)
+	}
+
+	// Read up to the last frame stored when reading started
+	for {
+		batch, err := consumer.Fetch(fetchBatch, jetstream.FetchMaxWait(time.Second))
+		if err != nil {
+			return fmt.Errorf("failed to fetch replay frames: %v", err)
+		}
+		received := 0
+		for msg := range batch.Messages() {
+			received++
+			meta, err := msg.Metadata()
+			if err != nil {
+				return fmt.Errorf("failed to read replay frame metadata: %v", err)
+			}
+			if err := fn(msg.Data()); err != nil {
+				return err
+			}
+			if meta.Sequence.Stream >= info.State.LastSeq {
+				return nil
+			}
+		}
+		if err := batch.Error(); err != nil {
+			return fmt.Errorf("failed to fetch replay frames: %v", err)
+		}
+
+		// Frames past the retention limits may be gone before they are read
+		if received == 0 {
+			return nil
+		}
+	}
+}
+
+// Frames returns every frame of a replay in order
+func (s *Service) Frames(ctx context.Context, id string) ([]Frame, error) {
+	var frames []Frame
+	err := s.Read(ctx, id, func(data []byte) error {
+		var frame Frame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			return fmt.Errorf("failed to decode replay frame: %v", err)
+		}
+		frames = append(frames, frame)
+		return nil
+	})
+	return frames, err
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/charmbracelet/log"
 	"tank-game/game"
+	"tank-game/game/codec"
+	"tank-game/game/room"
 	"tank-game/middleware"
 	"tank-game/views"
 	"github.com/pocketbase/pocketbase/apis"
@@ -19,30 +21,35 @@ import (
 
 // Signals struct for handling DataStar signals
 type Signals struct {
-	GameEvent    string `json:"gameEvent"`    // Consolidated game event
-	GameState    string `json:"gameState"`    // Game state for the client
-	Notification string `json:"notification"` // Kill notifications
+	GameEvent string `json:"gameEvent"` // Consolidated game event
+	GameState string `json:"gameState"` // Game state for the client
+	Events    string `json:"events"`    // Match events since the previous state, as a JSON array
 }
 
-func setupIndexRoutes(router *router.Router[*core.RequestEvent], gameManager *game.Manager) error {
+func setupIndexRoutes(router *router.Router[*core.RequestEvent], rooms *room.Registry, stateStreams *game.StateStreams) error {
 	// Create a group for protected routes
 	protected := router.Group("")
 	protected.BindFunc(middleware.AuthGuard)
 	protected.Bind(apis.Gzip())
 
-	// POST route for update endpoint
-	router.POST("/update", func(e *core.RequestEvent) error {
+	// Handler for the update endpoint, scoped to the room in the path
+	handleUpdate := func(e *core.RequestEvent) error {
+		gameRoom, err := resolveRoom(e, rooms)
+		if err != nil {
+			return e.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		gameManager := gameRoom.Manager
+
 		signals := &Signals{}
 		if err := datastar.ReadSignals(e.Request, signals); err != nil {
 			log.Error("Error reading signals", "error", err)
 			return e.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 		}
 
-		// Handle the consolidated game event
+		// Handle the consolidated game event, sent as JSON or in the binary format
 		if signals.GameEvent != "" {
-			var gameEvent game.GameEvent
-
-			if err := json.Unmarshal([]byte(signals.GameEvent), &gameEvent); err != nil {
+			gameEvent, err := codec.ParseEvent(signals.GameEvent)
+			if err != nil {
 				log.Error("Error unmarshaling game event", "error", err)
 				return e.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid game event data"})
 			}
@@ -56,10 +63,52 @@ func setupIndexRoutes(router *router.Router[*core.RequestEvent], gameManager *ga
 				playerID = authRecord.Id
 			}
 
+			// Spectators only ever ack states; gameplay events would give them a tank
+			if gameEvent.Type != game.EventStateAck && gameManager.IsSpectator(playerID) {
+				return e.JSON(http.StatusForbidden, map[string]string{"error": "Spectators cannot play"})
+			}
+
 			// Process based on event type
 			switch gameEvent.Type {
+			case game.EventPlayerInput:
+				// Handle player control input - the server simulates movement from it
+				var input game.PlayerInput
+				inputData, err := json.Marshal(gameEvent.Data)
+				if err != nil {
+					log.Error("Error marshaling player input", "error", err)
+					return e.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid player input"})
+				}
+
+				if err := json.Unmarshal(inputData, &input); err != nil {
+					log.Error("Error unmarshaling player input", "error", err)
+					return e.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid player input data"})
+				}
+
+				if err := gameManager.ApplyInput(input, playerID, playerName); err != nil {
+					log.Error("Error applying player input", "error", err)
+				}
+
+			case game.EventStateAck:
+				// Client confirmed the latest state revision it applied; deltas are built against it
+				var ack game.StateAck
+				ackData, err := json.Marshal(gameEvent.Data)
+				if err != nil {
+					log.Error("Error marshaling state ack", "error", err)
+					return e.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid state ack"})
+				}
+
+				if err := json.Unmarshal(ackData, &ack); err != nil {
+					log.Error("Error unmarshaling state ack", "error", err)
+					return e.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid state ack data"})
+				}
+
+				if err := stateStreams.Ack(playerID, ack); err != nil {
+					log.Debug("Ignoring state ack", "playerID", playerID, "error", err)
+				}
+
 			case game.EventPlayerUpdate:
-				// Handle player update event
+				// Legacy clients still send full player state. The server owns movement,
+				// so only the aim is taken from it and position/velocity are ignored.
 				var playerUpdate game.PlayerState
 				playerData, err := json.Marshal(gameEvent.Data)
 				if err != nil {
@@ -72,19 +121,11 @@ func setupIndexRoutes(router *router.Router[*core.RequestEvent], gameManager *ga
 					return e.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid player update data"})
 				}
 
-				// Check if player is destroyed in current game state
-				currentState := gameManager.GetState()
-				if currentPlayer, exists := currentState.Players[playerID]; exists && currentPlayer.IsDestroyed {
-					// Player is dead, ignore position updates from client
-					log.Warn("Ignoring position update from destroyed player", "playerID", playerID)
-					break
+				input := game.PlayerInput{
+					TurretRotation:  playerUpdate.TurretRotation,
+					BarrelElevation: playerUpdate.BarrelElevation,
 				}
-
-				// always set health to 0 because server should update this
-				playerUpdate.Health = 0
-
-				// Update player with game manager
-				if err := gameManager.UpdatePlayer(playerUpdate, playerID, playerName); err != nil {
+				if err := gameManager.ApplyInput(input, playerID, playerName); err != nil {
 					log.Error("Error updating player", "error", err)
 				}
 
@@ -124,7 +165,8 @@ func setupIndexRoutes(router *router.Router[*core.RequestEvent], gameManager *ga
 				}
 
 			case game.EventTankHit:
-				// Handle tank hit event
+				// Client hit reports are only hints; damage comes from the server's shell
+				// simulation and the claim is checked against it for cheating
 				var hitData game.HitData
 				hitDataJson, err := json.Marshal(gameEvent.Data)
 				if err != nil {
@@ -137,10 +179,7 @@ func setupIndexRoutes(router *router.Router[*core.RequestEvent], gameManager *ga
 					return e.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid tank hit data"})
 				}
 
-				// Process tank hit with game manager
-				if err := gameManager.ProcessTankHit(hitData); err != nil {
-					log.Error("Error processing tank hit", "error", err)
-				}
+				gameManager.ReportHitClaim(hitData, playerID)
 
 			case game.EventTankDeath:
 				// Handle tank death event
@@ -161,6 +200,9 @@ func setupIndexRoutes(router *router.Router[*core.RequestEvent], gameManager *ga
 					return e.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid tank respawn data"})
 				}
 
+				// Players can only respawn their own tank
+				respawnData.PlayerID = playerID
+
 				// Process tank respawn with game manager
 				if err := gameManager.RespawnTank(respawnData); err != nil {
 					log.Error("Error processing tank respawn", "error", err)
@@ -172,14 +214,21 @@ func setupIndexRoutes(router *router.Router[*core.RequestEvent], gameManager *ga
 		}
 
 		return e.JSON(http.StatusOK, map[string]bool{"success": true})
-	})
+	}
+
+	// Handler for the gamestate stream, scoped to the room in the path. Spectators get the
+	// same stream without a tank: they are counted while connected and never added as players.
+	handleGameState := func(e *core.RequestEvent, spectate bool) error {
+		gameRoom, err := resolveRoom(e, rooms)
+		if err != nil {
+			return e.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		gameManager := gameRoom.Manager
 
-	// GET route for gamestate endpoint
-	router.GET("/gamestate", func(e *core.RequestEvent) error {
 		sse := datastar.NewSSE(e.Response, e.Request)
 		ctx := e.Request.Context()
 
-		// Create a watcher for the gamestate KV
+		// Create a watcher for the room's gamestate KV
 		watcher, err := gameManager.WatchState(ctx)
 		if err != nil {
 			log.Error("Error creating gamestate watcher", "error", err)
@@ -187,24 +236,32 @@ func setupIndexRoutes(router *router.Router[*core.RequestEvent], gameManager *ga
 		}
 		defer watcher.Stop()
 
-		// Get the latest state to send to the client immediately
-		latestState := gameManager.GetState()
-		latestStateJSON, err := json.Marshal(latestState)
-		if err == nil {
-			err = sse.MergeSignals([]byte(fmt.Sprintf(`{"gameState": %q}`, string(latestStateJSON))))
-			if err != nil {
-				log.Error("Error sending initial game state", "error", err)
-			} else {
-				log.Info("Sent initial game state", 
-					"players", len(latestState.Players), 
-					"shells", len(latestState.Shells))
-			}
+		// Kills, shots, joins and phase changes arrive on the room's event subject
+		events, err := gameRoom.WatchEvents(ctx)
+		if err != nil {
+			log.Error("Error subscribing to match events", "room", gameRoom.ID, "error", err)
+			return e.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to watch match events"})
+		}
+		var pending []game.MatchEvent
+
+		// Each connection gets its own delta stream. The first message is a full snapshot,
+		// later ones only carry changes since the revision the client last acked.
+		encoder := stateStreams.Open(e.Auth.Id)
+		defer stateStreams.Close(encoder.ID())
+
+		if spectate {
+			gameManager.AddSpectator(e.Auth.Id)
 		}
 
 		// Process new updates from the watcher
 		for {
 			select {
 			case <-ctx.Done():
+				if spectate {
+					gameManager.RemoveSpectator(e.Auth.Id)
+					return nil
+				}
+
 				// Get player ID from auth (we can assume e.Auth is not nil due to auth guard)
 				playerID := e.Auth.Id
 				// Remove player from game state
@@ -214,6 +271,9 @@ func setupIndexRoutes(router *router.Router[*core.RequestEvent], gameManager *ga
 					log.Info("Player removed from game state on connection close", "playerID", playerID)
 				}
 				return nil
+			case event := <-events:
+				// Events go out with the next state so none are lost to signal merging
+				pending = append(pending, event)
 			case entry := <-watcher.Updates():
 				// Skip nil entries or deleted keys
 				if entry == nil {
@@ -227,24 +287,18 @@ func setupIndexRoutes(router *router.Router[*core.RequestEvent], gameManager *ga
 					continue
 				}
 
+				// Encode against the client's acked baseline
+				delta := encoder.Encode(entry.Revision(), state)
+
 				// Log game state for debugging
 				log.Debug("Broadcasting game state update", 
-					"players", len(state.Players),
-					"shells", len(state.Shells),
+					"players", len(delta.Players),
+					"shells", len(delta.Shells),
+					"full", delta.Full,
 					"revision", entry.Revision())
 
-				// Check for notifications in player states
-				var notification string
-				for _, player := range state.Players {
-					if player.Notification != "" {
-						notification = player.Notification
-						// Only take the first notification found
-						break
-					}
-				}
-
-				// Send the game state to the client
-				stateJSON, err := json.Marshal(state)
+				// Send the game state in the format the client negotiated in its acks
+				stateSignal, err := codec.StateSignal(codec.Negotiate(encoder.Codecs()), delta)
 				if err != nil {
 					log.Error("Error marshaling game state", "error", err)
 					continue
@@ -252,11 +306,16 @@ func setupIndexRoutes(router *router.Router[*core.RequestEvent], gameManager *ga
 
 				// Build signals JSON string
 				var signalsJSON string
-				if notification != "" {
-					signalsJSON = fmt.Sprintf(`{"gameState": %q, "notification": %q}`, string(stateJSON), notification)
-					log.Info("Sending notification", "message", notification)
+				if len(pending) > 0 {
+					eventsJSON, err := json.Marshal(pending)
+					if err != nil {
+						log.Error("Error marshaling match events", "error", err)
+						continue
+					}
+					pending = pending[:0]
+					signalsJSON = fmt.Sprintf(`{"gameState": %q, "events": %q}`, stateSignal, string(eventsJSON))
 				} else {
-					signalsJSON = fmt.Sprintf(`{"gameState": %q}`, string(stateJSON))
+					signalsJSON = fmt.Sprintf(`{"gameState": %q}`, stateSignal)
 				}
 
 				err = sse.MergeSignals([]byte(signalsJSON))
@@ -265,14 +324,46 @@ func setupIndexRoutes(router *router.Router[*core.RequestEvent], gameManager *ga
 				}
 			}
 		}
+	}
+
+	// The unscoped endpoints serve the default room
+	handlePlayerState := func(e *core.RequestEvent) error {
+		return handleGameState(e, false)
+	}
+	router.POST("/update", handleUpdate)
+	router.GET("/gamestate", handlePlayerState)
+	router.POST("/rooms/{id}/update", handleUpdate)
+	router.GET("/rooms/{id}/gamestate", handlePlayerState)
+
+	// Spectators only ever ack states, so their updates never reach the game
+	protected.POST("/rooms/{id}/spectate/update", handleStateAck(stateStreams))
+	protected.GET("/rooms/{id}/spectate/gamestate", func(e *core.RequestEvent) error {
+		return handleGameState(e, true)
 	})
 
 	// Add routes to protected group
 	protected.GET("/", func(e *core.RequestEvent) error {
 		log.Debug("Auth record", "auth", e.Auth)
+		gameRoom, err := resolveRoom(e, rooms)
+		if err != nil {
+			return e.String(http.StatusServiceUnavailable, err.Error())
+		}
+
 		ctx := context.WithValue(context.Background(), "user", e.Auth)
 		ctx = context.WithValue(ctx, "app", e.App)
-		return views.Index().Render(ctx, e.Response)
+		return views.Index(gameRoom.ID, gameRoom.Map()).Render(ctx, e.Response)
+	})
+
+	// Watch the default room without a tank
+	protected.GET("/spectate", func(e *core.RequestEvent) error {
+		gameRoom, err := resolveRoom(e, rooms)
+		if err != nil {
+			return e.String(http.StatusServiceUnavailable, err.Error())
+		}
+
+		ctx := context.WithValue(context.Background(), "user", e.Auth)
+		ctx = context.WithValue(ctx, "app", e.App)
+		return views.Spectate(gameRoom.ID, gameRoom.Map()).Render(ctx, e.Response)
 	})
 
 	protected.GET("/settings", func(e *core.RequestEvent) error {
@@ -337,4 +428,47 @@ func setupIndexRoutes(router *router.Router[*core.RequestEvent], gameManager *ga
 	})
 
 	return nil
-}
\ No newline at end of file
+}
+
+// handleStateAck returns a handler for clients that only watch the game: state acks
+// in their game events are forwarded to the state streams and everything else is ignored
+func handleStateAck(stateStreams *game.StateStreams) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		signals := &Signals{}
+		if err := datastar.ReadSignals(e.Request, signals); err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		gameEvent, err := codec.ParseEvent(signals.GameEvent)
+		if err != nil || gameEvent.Type != game.EventStateAck {
+			return e.JSON(http.StatusOK, map[string]bool{"success": true})
+		}
+
+		var ack game.StateAck
+		ackData, err := json.Marshal(gameEvent.Data)
+		if err == nil {
+			err = json.Unmarshal(ackData, &ack)
+		}
+		if err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid state ack data"})
+		}
+		if err := stateStreams.Ack(e.Auth.Id, ack); err != nil {
+			log.Debug("Ignoring state ack", "playerID", e.Auth.Id, "error", err)
+		}
+		return e.JSON(http.StatusOK, map[string]bool{"success": true})
+	}
+}
+
+// resolveRoom returns the room named in the request path, or the default room for unscoped routes
+func resolveRoom(e *core.RequestEvent, rooms *room.Registry) (*room.Room, error) {
+	roomID := e.Request.PathValue("id")
+	if roomID == "" {
+		roomID = room.DefaultRoomID
+	}
+
+	gameRoom, exists := rooms.Get(roomID)
+	if !exists {
+		return nil, fmt.Errorf("room %s not found", roomID)
+	}
+	return gameRoom, nil
+}
@@ -0,0 +1,67 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"tank-game/middleware"
+	"tank-game/stats"
+	"tank-game/views"
+)
+
+func setupLeaderboardRoutes(router *router.Router[*core.RequestEvent]) error {
+	protected := router.Group("")
+	protected.BindFunc(middleware.AuthGuard)
+
+	// Rankings for the current day, week or season
+	protected.GET("/api/leaderboard", func(e *core.RequestEvent) error {
+		period, metric, err := parseLeaderboardQuery(e)
+		if err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		board, err := stats.LoadLeaderboard(e.App, period, metric)
+		if err != nil {
+			log.Error("Failed to load leaderboard", "period", period, "metric", metric, "error", err)
+			return e.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load leaderboard"})
+		}
+		return e.JSON(http.StatusOK, board)
+	})
+
+	// Leaderboard page
+	protected.GET("/leaderboard", func(e *core.RequestEvent) error {
+		period, metric, err := parseLeaderboardQuery(e)
+		if err != nil {
+			return e.Redirect(http.StatusFound, "/leaderboard")
+		}
+
+		board, err := stats.LoadLeaderboard(e.App, period, metric)
+		if err != nil {
+			log.Error("Failed to load leaderboard", "period", period, "metric", metric, "error", err)
+			return e.Redirect(http.StatusFound, "/")
+		}
+
+		ctx := context.WithValue(context.Background(), "user", e.Auth)
+		ctx = context.WithValue(ctx, "app", e.App)
+		return views.Leaderboard(board).Render(ctx, e.Response)
+	})
+
+	return nil
+}
+
+// parseLeaderboardQuery reads the period and metric query parameters
+func parseLeaderboardQuery(e *core.RequestEvent) (stats.Period, stats.Metric, error) {
+	query := e.Request.URL.Query()
+	period, err := stats.ParsePeriod(query.Get("period"))
+	if err != nil {
+		return "", "", err
+	}
+	metric, err := stats.ParseMetric(query.Get("metric"))
+	if err != nil {
+		return "", "", err
+	}
+	return period, metric, nil
+}
@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"tank-game/matchmaking"
+	"tank-game/middleware"
+)
+
+func setupMatchmakingRoutes(router *router.Router[*core.RequestEvent], matchmaker *matchmaking.Matchmaker) error {
+	protected := router.Group("")
+	protected.BindFunc(middleware.AuthGuard)
+
+	// Queue for a rated match
+	protected.POST("/api/matchmaking", func(e *core.RequestEvent) error {
+		ticket, err := matchmaker.Join(e.Auth.Id)
+		if err != nil {
+			log.Error("Error joining matchmaking", "playerID", e.Auth.Id, "error", err)
+			return e.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to join matchmaking"})
+		}
+		return e.JSON(http.StatusOK, ticket)
+	})
+
+	// Poll the ticket; once matched it names the room to join at /rooms/{id}
+	protected.GET("/api/matchmaking", func(e *core.RequestEvent) error {
+		ticket, err := matchmaker.Status(e.Auth.Id)
+		if err != nil {
+			return e.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return e.JSON(http.StatusOK, ticket)
+	})
+
+	// Leave the queue
+	protected.DELETE("/api/matchmaking", func(e *core.RequestEvent) error {
+		if err := matchmaker.Leave(e.Auth.Id); err != nil {
+			if errors.Is(err, matchmaking.ErrNotQueued) {
+				return e.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+			}
+			return e.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return e.NoContent(http.StatusNoContent)
+	})
+
+	return nil
+}
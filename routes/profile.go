@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"tank-game/middleware"
+	"tank-game/stats"
+	"tank-game/views"
+)
+
+func setupProfileRoutes(router *router.Router[*core.RequestEvent]) error {
+	protected := router.Group("")
+	protected.BindFunc(middleware.AuthGuard)
+
+	// Your own profile
+	protected.GET("/profile", func(e *core.RequestEvent) error {
+		return e.Redirect(http.StatusFound, "/profile/"+e.Auth.GetString("callsign"))
+	})
+
+	// Career stats and recent matches of any player
+	protected.GET("/profile/{callsign}", func(e *core.RequestEvent) error {
+		profile, err := stats.LoadProfile(e.App, e.Request.PathValue("callsign"))
+		if err != nil {
+			log.Debug("Profile not found", "callsign", e.Request.PathValue("callsign"), "error", err)
+			return e.Redirect(http.StatusFound, "/")
+		}
+
+		ctx := context.WithValue(context.Background(), "user", e.Auth)
+		ctx = context.WithValue(ctx, "app", e.App)
+		return views.Profile(profile).Render(ctx, e.Response)
+	})
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/charmbracelet/log"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	datastar "github.com/starfederation/datastar/sdk/go"
+	"tank-game/game"
+	"tank-game/game/codec"
+	"tank-game/middleware"
+	"tank-game/replay"
+	"tank-game/views"
+)
+
+// maxReplayList caps the number of replays returned by one listing
+const maxReplayList = 100
+
+func setupReplayRoutes(router *router.Router[*core.RequestEvent], replays *replay.Service, stateStreams *game.StateStreams) error {
+	protected := router.Group("")
+	protected.BindFunc(middleware.AuthGuard)
+
+	// Open playback sessions, so controls posted to /control reach the right viewer
+	viewers := replay.NewViewers()
+
+	// Most recent replays, optionally of one room with ?room=
+	protected.GET("/api/replays", func(e *core.RequestEvent) error {
+		query := e.Request.URL.Query()
+		limit, err := strconv.Atoi(query.Get("limit"))
+		if err != nil || limit <= 0 || limit > maxReplayList {
+			limit = maxReplayList
+		}
+
+		infos, err := replays.List(query.Get("room"), limit)
+		if err != nil {
+			log.Error("Failed to list replays", "error", err)
+			return e.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to list replays"})
+		}
+		return e.JSON(http.StatusOK, infos)
+	})
+
+	// Index entry of one replay
+	protected.GET("/api/replays/{id}", func(e *core.RequestEvent) error {
+		info, err := replays.Get(e.Request.PathValue("id"))
+		if err != nil {
+			return e.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return e.JSON(http.StatusOK, info)
+	})
+
+	// Download a replay as a gzipped export file
+	protected.GET("/api/replays/{id}/export", func(e *core.RequestEvent) error {
+		id := e.Request.PathValue("id")
+		if _, err := replays.Get(id); err != nil {
+			return e.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+
+		e.Response.Header().Set("Content-Type", "application/gzip")
+		e.Response.Header().Set("Content-Disposition", "attachment; filename=\""+id+".replay.gz\"")
+		if err := replays.Export(e.Request.Context(), id, e.Response); err != nil {
+			// Headers are already sent, so the download is just cut short
+			if !errors.Is(err, replay.ErrNotFound) {
+				log.Error("Failed to export replay", "replay", id, "error", err)
+			}
+		}
+		return nil
+	})
+
+	// Replay page, rendered by the same game component as a live room
+	protected.GET("/replay/{id}", func(e *core.RequestEvent) error {
+		id := e.Request.PathValue("id")
+		gameMap, err := replays.Map(e.Request.Context(), id)
+		if err != nil {
+			if !errors.Is(err, replay.ErrNotFound) {
+				log.Error("Failed to load replay map", "replay", id, "error", err)
+			}
+			return e.String(http.StatusNotFound, "Replay not found")
+		}
+
+		ctx := context.WithValue(context.Background(), "user", e.Auth)
+		ctx = context.WithValue(ctx, "app", e.App)
+		return views.Replay(id, gameMap).Render(ctx, e.Response)
+	}).Bind(apis.Gzip())
+
+	// Stream of recorded game states, sent as the gameState signals of /gamestate
+	// along with a replay signal describing the playback
+	protected.GET("/replay/{id}/gamestate", func(e *core.RequestEvent) error {
+		id := e.Request.PathValue("id")
+		playback, err := replays.Load(e.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, replay.ErrNotFound) {
+				return e.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+			}
+			log.Error("Failed to load replay", "replay", id, "error", err)
+			return e.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load replay"})
+		}
+
+		sse := datastar.NewSSE(e.Response, e.Request)
+
+		// Revisions count the states sent on this connection, so seeking back never reuses one
+		encoder := stateStreams.Open(e.Auth.Id)
+		defer stateStreams.Close(encoder.ID())
+		viewer := viewers.Open(e.Auth.Id, playback)
+		defer viewers.Close(viewer.ID())
+
+		var revision uint64
+		err = viewer.Run(e.Request.Context(), func(state game.GameState, status replay.PlaybackStatus) error {
+			revision++
+			stateSignal, err := codec.StateSignal(codec.Negotiate(encoder.Codecs()), encoder.Encode(revision, state))
+			if err != nil {
+				return err
+			}
+			statusJSON, err := json.Marshal(status)
+			if err != nil {
+				return err
+			}
+			return sse.MergeSignals([]byte(fmt.Sprintf(`{"gameState": %q, "replay": %s}`, stateSignal, string(statusJSON))))
+		})
+		if err != nil {
+			log.Debug("Replay playback stopped", "replay", id, "error", err)
+		}
+		return nil
+	})
+
+	// State acks from the game component; nobody plays in a replay
+	protected.POST("/replay/{id}/update", handleStateAck(stateStreams))
+
+	// Play, pause, seek and speed controls for a playback session
+	protected.POST("/replay/{id}/control", func(e *core.RequestEvent) error {
+		signals := &replaySignals{}
+		if err := datastar.ReadSignals(e.Request, signals); err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		var control replay.Control
+		if err := json.Unmarshal([]byte(signals.ReplayControl), &control); err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid replay control"})
+		}
+		if err := viewers.Control(e.Auth.Id, signals.Replay.Session, control); err != nil {
+			return e.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return e.JSON(http.StatusOK, map[string]bool{"success": true})
+	})
+
+	return nil
+}
+
+// replaySignals are the DataStar signals of the replay page
+type replaySignals struct {
+	ReplayControl string                `json:"replayControl"` // Control to send, as JSON
+	Replay        replay.PlaybackStatus `json:"replay"`        // Playback status, naming the session
+}
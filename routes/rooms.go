@@ -0,0 +1,180 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"tank-game/game"
+	"tank-game/game/room"
+	"tank-game/middleware"
+	"tank-game/views"
+)
+
+// createRoomRequest is the body of a create room request
+type createRoomRequest struct {
+	ID        string                `json:"id"`
+	Name      string                `json:"name"`
+	TickRate  int                   `json:"tickRate"`
+	NPCs      *int                  `json:"npcs"`
+	Mode      *game.ModeConfig      `json:"mode"`
+	Lifecycle *game.LifecycleConfig `json:"lifecycle"`
+	Map       *game.MapConfig       `json:"map"`
+	MapName   string                `json:"mapName"`
+}
+
+func setupRoomRoutes(router *router.Router[*core.RequestEvent], rooms *room.Registry) error {
+	protected := router.Group("")
+	protected.BindFunc(middleware.AuthGuard)
+
+	// List all running rooms
+	protected.GET("/api/rooms", func(e *core.RequestEvent) error {
+		return e.JSON(http.StatusOK, rooms.List())
+	})
+
+	// Create a new room
+	protected.POST("/api/rooms", func(e *core.RequestEvent) error {
+		var req createRoomRequest
+		if err := e.BindBody(&req); err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid room data"})
+		}
+
+		// Use the default NPC count unless one was given
+		cfg := room.Config{
+			Name:     req.Name,
+			TickRate: req.TickRate,
+			NumNPCs:  rooms.Defaults().NumNPCs,
+			MapName:  req.MapName,
+			Owner:    e.Auth.Id,
+		}
+		if req.NPCs != nil {
+			cfg.NumNPCs = *req.NPCs
+		}
+		if req.Mode != nil {
+			cfg.Mode = *req.Mode
+		}
+		if req.Lifecycle != nil {
+			cfg.Lifecycle = *req.Lifecycle
+		}
+		if req.Map != nil {
+			cfg.Map = *req.Map
+		}
+
+		gameRoom, err := rooms.Create(req.ID, cfg)
+		if err != nil {
+			log.Error("Error creating room", "id", req.ID, "error", err)
+			switch {
+			case errors.Is(err, room.ErrRoomExists):
+				return e.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+			case errors.Is(err, room.ErrTooManyRooms):
+				return e.JSON(http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+			case errors.Is(err, room.ErrOwnerLimit):
+				return e.JSON(http.StatusTooManyRequests, map[string]string{"error": err.Error()})
+			default:
+				return e.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+		}
+
+		log.Info("Room created by player", "roomID", gameRoom.ID, "playerID", e.Auth.Id)
+		return e.JSON(http.StatusCreated, gameRoom.Info())
+	})
+
+	// Close a room; only its owner or a superuser may
+	protected.DELETE("/api/rooms/{id}", func(e *core.RequestEvent) error {
+		gameRoom, exists := rooms.Get(e.Request.PathValue("id"))
+		if !exists {
+			return e.JSON(http.StatusNotFound, map[string]string{"error": room.ErrRoomNotFound.Error()})
+		}
+		if gameRoom.Owner != e.Auth.Id && !e.Auth.IsSuperuser() {
+			return e.JSON(http.StatusForbidden, map[string]string{"error": "Only the room's owner can close it"})
+		}
+
+		if err := rooms.Remove(gameRoom.ID); err != nil {
+			log.Error("Error removing room", "roomID", gameRoom.ID, "error", err)
+			switch {
+			case errors.Is(err, room.ErrRoomNotFound):
+				return e.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+			case errors.Is(err, room.ErrDefaultRoom):
+				return e.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+			default:
+				return e.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to close room"})
+			}
+		}
+
+		log.Info("Room closed by player", "roomID", gameRoom.ID, "playerID", e.Auth.Id)
+		return e.NoContent(http.StatusNoContent)
+	})
+
+	// List the maps rooms can be created with
+	protected.GET("/api/maps", func(e *core.RequestEvent) error {
+		return e.JSON(http.StatusOK, rooms.Maps())
+	})
+
+	// Export a room's map as a map file, as JSON or with ?format=yaml
+	protected.GET("/api/rooms/{id}/map", func(e *core.RequestEvent) error {
+		gameRoom, exists := rooms.Get(e.Request.PathValue("id"))
+		if !exists {
+			return e.JSON(http.StatusNotFound, map[string]string{"error": room.ErrRoomNotFound.Error()})
+		}
+
+		gameMap := gameRoom.Map()
+		name := gameMap.Name
+		if name == "" {
+			name = gameRoom.ID
+		}
+		format := game.MapFormat(e.Request.URL.Query().Get("format"))
+		data, err := game.ExportMap(gameMap, name).Marshal(format)
+		if err != nil {
+			log.Error("Error exporting map", "roomID", gameRoom.ID, "error", err)
+			return e.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to export map"})
+		}
+
+		if format == game.MapFormatYAML {
+			return e.Blob(http.StatusOK, "application/yaml", data)
+		}
+		return e.Blob(http.StatusOK, "application/json", data)
+	})
+
+	// Rejected client hit claims and suspicion per player, for moderators
+	router.GET("/api/rooms/{id}/hits", func(e *core.RequestEvent) error {
+		gameRoom, exists := rooms.Get(e.Request.PathValue("id"))
+		if !exists {
+			return e.JSON(http.StatusNotFound, map[string]string{"error": room.ErrRoomNotFound.Error()})
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"rejections": gameRoom.Manager.HitRejections(),
+			"suspicion":  gameRoom.Manager.Suspicion(),
+		})
+	}).Bind(apis.RequireSuperuserAuth())
+
+	// Join a room by opening the game scoped to it
+	protected.GET("/rooms/{id}", func(e *core.RequestEvent) error {
+		gameRoom, exists := rooms.Get(e.Request.PathValue("id"))
+		if !exists {
+			return e.Redirect(http.StatusFound, "/")
+		}
+
+		ctx := context.WithValue(context.Background(), "user", e.Auth)
+		ctx = context.WithValue(ctx, "app", e.App)
+		return views.Index(gameRoom.ID, gameRoom.Map()).Render(ctx, e.Response)
+	})
+
+	// Watch a room without a tank, e.g. one that is full
+	protected.GET("/rooms/{id}/spectate", func(e *core.RequestEvent) error {
+		gameRoom, exists := rooms.Get(e.Request.PathValue("id"))
+		if !exists {
+			return e.Redirect(http.StatusFound, "/spectate")
+		}
+
+		ctx := context.WithValue(context.Background(), "user", e.Auth)
+		ctx = context.WithValue(ctx, "app", e.App)
+		return views.Spectate(gameRoom.ID, gameRoom.Map()).Render(ctx, e.Response)
+	})
+
+	return nil
+}
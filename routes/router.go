@@ -6,16 +6,27 @@ import (
 	"fmt"
 
 	"tank-game/game"
+	"tank-game/game/room"
+	"tank-game/matchmaking"
+	"tank-game/replay"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/router"
 )
 
-// SetupRoutes initializes all routes with game manager
-func SetupRoutes(ctx context.Context, router *router.Router[*core.RequestEvent], gameManager *game.Manager) error {
+// SetupRoutes initializes all routes with the room registry, matchmaker and replay service
+func SetupRoutes(ctx context.Context, router *router.Router[*core.RequestEvent], rooms *room.Registry, matchmaker *matchmaking.Matchmaker, replays *replay.Service) error {
+
+	// Open state streams, so state acks posted to /update reach the right delta encoder
+	stateStreams := game.NewStateStreams()
 
 	err := errors.Join(
-		setupIndexRoutes(router, gameManager),
+		setupIndexRoutes(router, rooms, stateStreams),
+		setupRoomRoutes(router, rooms),
 		setupAuthRoutes(router),
+		setupProfileRoutes(router),
+		setupLeaderboardRoutes(router),
+		setupMatchmakingRoutes(router, matchmaker),
+		setupReplayRoutes(router, replays, stateStreams),
 	)
 	if err != nil {
 		return fmt.Errorf("Error: %v", err)
@@ -0,0 +1,224 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"tank-game/game"
+)
+
+// Leaderboard collections
+const (
+	seasonsCollection            = "seasons"
+	leaderboardEntriesCollection = "leaderboard_entries"
+)
+
+// leaderboardSize is the number of players returned per leaderboard
+const leaderboardSize = 50
+
+// minShotsForAccuracy keeps players with a lucky handful of shots off the accuracy board
+const minShotsForAccuracy = 20
+
+// Period is the time window a leaderboard covers
+type Period string
+
+const (
+	PeriodDaily  Period = "daily"
+	PeriodWeekly Period = "weekly"
+	PeriodSeason Period = "season"
+)
+
+// Metric is what a leaderboard ranks by
+type Metric string
+
+const (
+	MetricKills    Metric = "kills"
+	MetricKD       Metric = "kd"
+	MetricAccuracy Metric = "accuracy"
+)
+
+// Errors returned for invalid leaderboard requests
+var (
+	ErrInvalidPeriod = errors.New("period must be daily, weekly or season")
+	ErrInvalidMetric = errors.New("metric must be kills, kd or accuracy")
+	ErrNoSeason      = errors.New("no season is active")
+)
+
+// ParsePeriod validates a period name, defaulting to daily
+func ParsePeriod(value string) (Period, error) {
+	switch Period(value) {
+	case "":
+		return PeriodDaily, nil
+	case PeriodDaily, PeriodWeekly, PeriodSeason:
+		return Period(value), nil
+	default:
+		return "", ErrInvalidPeriod
+	}
+}
+
+// ParseMetric validates a metric name, defaulting to kills
+func ParseMetric(value string) (Metric, error) {
+	switch Metric(value) {
+	case "":
+		return MetricKills, nil
+	case MetricKills, MetricKD, MetricAccuracy:
+		return Metric(value), nil
+	default:
+		return "", ErrInvalidMetric
+	}
+}
+
+// Rank is one row of a leaderboard
+type Rank struct {
+	Rank     int     `json:"rank"`
+	Callsign string  `json:"callsign"`
+	Kills    int     `json:"kills"`
+	Deaths   int     `json:"deaths"`
+	KD       float64 `json:"kd"`
+	Accuracy float64 `json:"accuracy"`
+	Matches  int     `json:"matches"`
+	Wins     int     `json:"wins"`
+}
+
+// Leaderboard is a ranked list for one period and metric
+type Leaderboard struct {
+	Period  Period `json:"period"`
+	Metric  Metric `json:"metric"`
+	Key     string `json:"key"`              // Day, ISO week or season ID the board covers
+	Season  string `json:"season,omitempty"` // Season name for season boards
+	Entries []Rank `json:"entries"`
+}
+
+// periodKey returns the key of the day, week or season containing t
+func periodKey(app core.App, period Period, t time.Time) (string, string, error) {
+	t = t.UTC()
+	switch period {
+	case PeriodDaily:
+		return t.Format("2006-01-02"), "", nil
+	case PeriodWeekly:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week), "", nil
+	case PeriodSeason:
+		season, err := app.FindFirstRecordByFilter(seasonsCollection,
+			"starts_at <= {:at} && ends_at > {:at}",
+			dbx.Params{"at": t.Format("2006-01-02 15:04:05.000Z")})
+		if err != nil {
+			return "", "", ErrNoSeason
+		}
+		return season.Id, season.GetString("name"), nil
+	default:
+		return "", "", ErrInvalidPeriod
+	}
+}
+
+// updateLeaderboards adds a finished match to the daily, weekly and season entries of every user who played it
+func (s *Store) updateLeaderboards(app core.App, result game.MatchResult) error {
+	collection, err := app.FindCachedCollectionByNameOrId(leaderboardEntriesCollection)
+	if err != nil {
+		return fmt.Errorf("failed to find leaderboard collection: %v", err)
+	}
+
+	endedAt := time.UnixMilli(result.EndedAt)
+	for _, period := range []Period{PeriodDaily, PeriodWeekly, PeriodSeason} {
+		key, _, err := periodKey(app, period, endedAt)
+		if errors.Is(err, ErrNoSeason) {
+			// Matches between seasons only count towards daily and weekly boards
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, player := range result.Players {
+			if !s.isUser(app, player.ID) {
+				continue
+			}
+
+			entry, err := app.FindFirstRecordByFilter(leaderboardEntriesCollection,
+				"user = {:user} && period = {:period} && period_key = {:key}",
+				dbx.Params{"user": player.ID, "period": string(period), "key": key})
+			if err != nil {
+				entry = core.NewRecord(collection)
+				entry.Set("user", player.ID)
+				entry.Set("period", string(period))
+				entry.Set("period_key", key)
+			}
+
+			entry.Set("callsign", player.Name)
+			entry.Set("kills+", player.Kills)
+			entry.Set("deaths+", player.Deaths)
+			entry.Set("shots_fired+", player.ShotsFired)
+			entry.Set("hits+", player.Hits)
+			entry.Set("matches+", 1)
+			if won(result, player) {
+				entry.Set("wins+", 1)
+			}
+
+			// Ratios are stored so they can be sorted on without scanning
+			totals := Career{
+				Kills:      entry.GetInt("kills"),
+				Deaths:     entry.GetInt("deaths"),
+				ShotsFired: entry.GetInt("shots_fired"),
+				Hits:       entry.GetInt("hits"),
+			}
+			entry.Set("kd", totals.KD())
+			entry.Set("accuracy", totals.Accuracy())
+
+			if err := app.Save(entry); err != nil {
+				return fmt.Errorf("failed to save leaderboard entry for %s: %v", player.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// LoadLeaderboard returns the current leaderboard for a period, ranked by metric.
+// Between seasons the season board is empty.
+func LoadLeaderboard(app core.App, period Period, metric Metric) (*Leaderboard, error) {
+	board := &Leaderboard{
+		Period:  period,
+		Metric:  metric,
+		Entries: []Rank{},
+	}
+
+	key, seasonName, err := periodKey(app, period, time.Now())
+	if errors.Is(err, ErrNoSeason) {
+		return board, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	board.Key = key
+	board.Season = seasonName
+
+	filter := "period = {:period} && period_key = {:key}"
+	if metric == MetricAccuracy {
+		filter += fmt.Sprintf(" && shots_fired >= %d", minShotsForAccuracy)
+	}
+
+	// Ties on the metric go to whoever has more kills
+	records, err := app.FindRecordsByFilter(leaderboardEntriesCollection, filter, "-"+string(metric)+",-kills", leaderboardSize, 0,
+		dbx.Params{"period": string(period), "key": key})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load leaderboard: %v", err)
+	}
+
+	for i, record := range records {
+		board.Entries = append(board.Entries, Rank{
+			Rank:     i + 1,
+			Callsign: record.GetString("callsign"),
+			Kills:    record.GetInt("kills"),
+			Deaths:   record.GetInt("deaths"),
+			KD:       record.GetFloat("kd"),
+			Accuracy: record.GetFloat("accuracy"),
+			Matches:  record.GetInt("matches"),
+			Wins:     record.GetInt("wins"),
+		})
+	}
+
+	return board, nil
+}
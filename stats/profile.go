@@ -0,0 +1,110 @@
+package stats
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// recentMatchesLimit is the number of matches shown on a profile
+const recentMatchesLimit = 10
+
+// Career holds a player's lifetime totals
+type Career struct {
+	Kills       int
+	Deaths      int
+	ShotsFired  int
+	Hits        int
+	DamageDealt int
+	DamageTaken int
+	Playtime    int // Seconds
+	Matches     int
+	Wins        int
+}
+
+// KD returns kills per death, or kills if the player has never died
+func (c Career) KD() float64 {
+	if c.Deaths == 0 {
+		return float64(c.Kills)
+	}
+	return float64(c.Kills) / float64(c.Deaths)
+}
+
+// Accuracy returns the percentage of shots fired that hit
+func (c Career) Accuracy() float64 {
+	if c.ShotsFired == 0 {
+		return 0
+	}
+	return float64(c.Hits) / float64(c.ShotsFired) * 100
+}
+
+// MatchLine is one of a player's recent matches
+type MatchLine struct {
+	Mode    string
+	Map     string
+	EndedAt time.Time
+	Team    string
+	Kills   int
+	Deaths  int
+	Score   int
+	Won     bool
+}
+
+// Profile is everything shown on a player's profile page
+type Profile struct {
+	Callsign string
+	Career   Career
+	Recent   []MatchLine
+}
+
+// LoadProfile loads the career stats and recent matches of the user with the given callsign
+func LoadProfile(app core.App, callsign string) (*Profile, error) {
+	user, err := app.FindFirstRecordByFilter(usersCollection, "callsign = {:callsign}", dbx.Params{"callsign": callsign})
+	if err != nil {
+		return nil, fmt.Errorf("player %s not found: %v", callsign, err)
+	}
+
+	profile := &Profile{Callsign: user.GetString("callsign")}
+
+	// Players who haven't finished a fight yet have no stats record
+	if record, err := app.FindFirstRecordByData(playerStatsCollection, "user", user.Id); err == nil {
+		profile.Career = Career{
+			Kills:       record.GetInt("kills"),
+			Deaths:      record.GetInt("deaths"),
+			ShotsFired:  record.GetInt("shots_fired"),
+			Hits:        record.GetInt("hits"),
+			DamageDealt: record.GetInt("damage_dealt"),
+			DamageTaken: record.GetInt("damage_taken"),
+			Playtime:    record.GetInt("playtime"),
+			Matches:     record.GetInt("matches"),
+			Wins:        record.GetInt("wins"),
+		}
+	}
+
+	lines, err := app.FindRecordsByFilter(matchPlayersCollection, "user = {:user}", "-created", recentMatchesLimit, 0, dbx.Params{"user": user.Id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recent matches: %v", err)
+	}
+
+	for _, line := range lines {
+		match, err := app.FindRecordById(matchesCollection, line.GetString("match"))
+		if err != nil {
+			continue
+		}
+
+		profile.Recent = append(profile.Recent, MatchLine{
+			Mode:    match.GetString("mode"),
+			Map:     match.GetString("map"),
+			EndedAt: match.GetDateTime("ended_at").Time(),
+			Team:    line.GetString("team"),
+			Kills:   line.GetInt("kills"),
+			Deaths:  line.GetInt("deaths"),
+			Score:   line.GetInt("score"),
+			Won:     line.GetBool("won"),
+		})
+	}
+
+	return profile, nil
+}
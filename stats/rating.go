@@ -0,0 +1,238 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/charmbracelet/log"
+	"github.com/pocketbase/pocketbase/core"
+	"tank-game/game"
+)
+
+// Glicko-2 parameters. New players start at 1500 with a wide deviation that
+// narrows as they play, so their first matches move the rating the most.
+const (
+	defaultRating     = 1500.0
+	defaultDeviation  = 350.0
+	defaultVolatility = 0.06
+
+	glickoScale   = 173.7178 // Converts between the Glicko and Glicko-2 scales
+	glickoTau     = 0.5      // Constrains how fast volatility changes
+	glickoEpsilon = 0.000001 // Convergence tolerance of the volatility iteration
+)
+
+// Rating is a player's Glicko-2 skill rating
+type Rating struct {
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility"`
+}
+
+// DefaultRating is the rating of a player without rated matches
+func DefaultRating() Rating {
+	return Rating{Rating: defaultRating, Deviation: defaultDeviation, Volatility: defaultVolatility}
+}
+
+// ratingOutcome is one pairwise result: 1 for a win, 0.5 for a draw and 0 for a loss
+type ratingOutcome struct {
+	opponent Rating
+	score    float64
+}
+
+// update returns the rating after a rating period with the given outcomes
+func (r Rating) update(outcomes []ratingOutcome) Rating {
+	mu := (r.Rating - defaultRating) / glickoScale
+	phi := r.Deviation / glickoScale
+	sigma := r.Volatility
+
+	// Without games only the deviation grows
+	if len(outcomes) == 0 {
+		return Rating{
+			Rating:     r.Rating,
+			Deviation:  math.Min(math.Sqrt(phi*phi+sigma*sigma)*glickoScale, defaultDeviation),
+			Volatility: sigma,
+		}
+	}
+
+	// Estimated variance and improvement from the outcomes
+	var varianceSum, deltaSum float64
+	for _, outcome := range outcomes {
+		muJ := (outcome.opponent.Rating - defaultRating) / glickoScale
+		phiJ := outcome.opponent.Deviation / glickoScale
+		g := 1 / math.Sqrt(1+3*phiJ*phiJ/(math.Pi*math.Pi))
+		expected := 1 / (1 + math.Exp(-g*(mu-muJ)))
+		varianceSum += g * g * expected * (1 - expected)
+		deltaSum += g * (outcome.score - expected)
+	}
+	v := 1 / varianceSum
+	delta := v * deltaSum
+
+	// New volatility by the Illinois algorithm
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(glickoTau*glickoTau)
+	}
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*glickoTau) < 0 {
+			k++
+		}
+		B = a - k*glickoTau
+	}
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > glickoEpsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	newSigma := math.Exp(A / 2)
+
+	// New deviation and rating
+	phiStar := math.Sqrt(phi*phi + newSigma*newSigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*deltaSum
+
+	return Rating{
+		Rating:     newMu*glickoScale + defaultRating,
+		Deviation:  newPhi * glickoScale,
+		Volatility: newSigma,
+	}
+}
+
+// ratingRecord returns a user's rating record, or a new unsaved one for unrated users
+func ratingRecord(app core.App, userID string) (*core.Record, error) {
+	record, err := app.FindFirstRecordByData(playerRatingsCollection, "user", userID)
+	if err == nil {
+		return record, nil
+	}
+
+	collection, err := app.FindCachedCollectionByNameOrId(playerRatingsCollection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find player ratings collection: %v", err)
+	}
+	record = core.NewRecord(collection)
+	record.Set("user", userID)
+	return record, nil
+}
+
+// ratingOf reads a rating record, using the default for unrated users
+func ratingOf(record *core.Record) Rating {
+	if record.GetFloat("deviation") == 0 {
+		return DefaultRating()
+	}
+	return Rating{
+		Rating:     record.GetFloat("rating"),
+		Deviation:  record.GetFloat("deviation"),
+		Volatility: record.GetFloat("volatility"),
+	}
+}
+
+// Rating returns a user's current skill rating
+func (s *Store) Rating(userID string) (Rating, error) {
+	record, err := ratingRecord(s.app, userID)
+	if err != nil {
+		return Rating{}, err
+	}
+	return ratingOf(record), nil
+}
+
+// pairScore is the outcome of a match for player a against player b. Teammates
+// are not rated against each other; across teams the match winner decides. In
+// solo modes a player who left loses, otherwise the higher placement wins.
+func pairScore(result game.MatchResult, a, b game.PlayerResult) (float64, bool) {
+	if a.Team != "" && b.Team != "" {
+		switch {
+		case a.Team == b.Team:
+			return 0, false
+		case result.Winner == a.Team:
+			return 1, true
+		case result.Winner == b.Team:
+			return 0, true
+		default:
+			return 0.5, true
+		}
+	}
+
+	switch {
+	case a.Left && !b.Left:
+		return 0, true
+	case b.Left && !a.Left:
+		return 1, true
+	case a.Score != b.Score:
+		return boolScore(a.Score > b.Score), true
+	case a.Kills != b.Kills:
+		return boolScore(a.Kills > b.Kills), true
+	default:
+		return 0.5, true
+	}
+}
+
+// boolScore converts a win into a rating outcome
+func boolScore(won bool) float64 {
+	if won {
+		return 1
+	}
+	return 0
+}
+
+// updateRatings rates every user in a finished match against every other user in it
+func (s *Store) updateRatings(app core.App, result game.MatchResult) error {
+	type ratedPlayer struct {
+		result game.PlayerResult
+		record *core.Record
+		rating Rating
+	}
+
+	var players []ratedPlayer
+	for _, player := range result.Players {
+		if !s.isUser(app, player.ID) {
+			continue
+		}
+		record, err := ratingRecord(app, player.ID)
+		if err != nil {
+			return err
+		}
+		players = append(players, ratedPlayer{result: player, record: record, rating: ratingOf(record)})
+	}
+
+	// Bots are not rated, so a match needs two users to count
+	if len(players) < 2 {
+		return nil
+	}
+
+	// All updates are computed from the ratings before the match
+	for i, player := range players {
+		var outcomes []ratingOutcome
+		for j, opponent := range players {
+			if i == j {
+				continue
+			}
+			if score, ok := pairScore(result, player.result, opponent.result); ok {
+				outcomes = append(outcomes, ratingOutcome{opponent: opponent.rating, score: score})
+			}
+		}
+
+		updated := player.rating.update(outcomes)
+		player.record.Set("rating", math.Round(updated.Rating*100)/100)
+		player.record.Set("deviation", math.Round(updated.Deviation*100)/100)
+		player.record.Set("volatility", updated.Volatility)
+		if err := app.Save(player.record); err != nil {
+			return fmt.Errorf("failed to save rating for %s: %v", player.result.ID, err)
+		}
+
+		log.Debug("Rating updated", "player", player.result.ID, "from", player.rating.Rating, "to", updated.Rating)
+	}
+
+	return nil
+}
@@ -0,0 +1,274 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/pocketbase/pocketbase/core"
+	"tank-game/game"
+)
+
+// flushInterval is how often buffered combat stats are written to the database
+const flushInterval = 10 * time.Second
+
+// Collection names
+const (
+	matchesCollection       = "matches"
+	matchPlayersCollection  = "match_players"
+	playerStatsCollection   = "player_stats"
+	playerRatingsCollection = "player_ratings"
+	usersCollection         = "users"
+)
+
+// careerDelta is a change to a player's lifetime stats that hasn't been written yet
+type careerDelta struct {
+	Kills       int
+	Deaths      int
+	ShotsFired  int
+	Hits        int
+	DamageDealt int
+	DamageTaken int
+	Playtime    int // Seconds
+	Matches     int
+	Wins        int
+}
+
+// Store persists match results and lifetime player stats in PocketBase.
+// Combat events are buffered in memory and written in batches so the game tick never waits on the database.
+type Store struct {
+	app        core.App
+	mutex      sync.Mutex
+	flushMutex sync.Mutex              // Serializes flushes so read-modify-write of a stats record never interleaves
+	pending    map[string]*careerDelta // Unwritten changes keyed by player ID
+	notUser    map[string]bool         // Player IDs known to have no user account, e.g. NPCs
+}
+
+// NewStore creates a stats store on top of the PocketBase app
+func NewStore(app core.App) *Store {
+	return &Store{
+		app:     app,
+		pending: make(map[string]*careerDelta),
+		notUser: make(map[string]bool),
+	}
+}
+
+// Run flushes buffered stats periodically until the context is done
+func (s *Store) Run(ctx context.Context) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.Flush()
+			return
+		case <-ticker.C:
+			s.Flush()
+		}
+	}
+}
+
+// delta returns the pending change for a player. Caller must hold the mutex.
+func (s *Store) delta(playerID string) *careerDelta {
+	delta, exists := s.pending[playerID]
+	if !exists {
+		delta = &careerDelta{}
+		s.pending[playerID] = delta
+	}
+	return delta
+}
+
+// ShotFired counts a shell fired by a player
+func (s *Store) ShotFired(playerID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.notUser[playerID] {
+		return
+	}
+	s.delta(playerID).ShotsFired++
+}
+
+// Hit counts damage dealt and taken, and the kill and death if the hit destroyed the target
+func (s *Store) Hit(sourceID, targetID string, damage int, killed bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if sourceID != "" && sourceID != targetID && !s.notUser[sourceID] {
+		source := s.delta(sourceID)
+		source.Hits++
+		source.DamageDealt += damage
+		if killed {
+			source.Kills++
+		}
+	}
+
+	if !s.notUser[targetID] {
+		target := s.delta(targetID)
+		target.DamageTaken += damage
+		if killed {
+			target.Deaths++
+		}
+	}
+}
+
+// RecordMatch stores a finished match with a line per player and adds it to everyone's lifetime stats, rating and leaderboards
+func (s *Store) RecordMatch(result game.MatchResult) error {
+	err := s.app.RunInTransaction(func(txApp core.App) error {
+		matches, err := txApp.FindCachedCollectionByNameOrId(matchesCollection)
+		if err != nil {
+			return fmt.Errorf("failed to find matches collection: %v", err)
+		}
+		matchPlayers, err := txApp.FindCachedCollectionByNameOrId(matchPlayersCollection)
+		if err != nil {
+			return fmt.Errorf("failed to find match players collection: %v", err)
+		}
+
+		match := core.NewRecord(matches)
+		match.Set("room", result.Room)
+		match.Set("number", result.Number)
+		match.Set("mode", string(result.Mode))
+		match.Set("map", result.Map)
+		match.Set("started_at", time.UnixMilli(result.StartedAt).UTC())
+		match.Set("ended_at", time.UnixMilli(result.EndedAt).UTC())
+		match.Set("winner", result.Winner)
+		match.Set("teams", result.Teams)
+		if err := txApp.Save(match); err != nil {
+			return fmt.Errorf("failed to save match: %v", err)
+		}
+
+		for _, player := range result.Players {
+			line := core.NewRecord(matchPlayers)
+			line.Set("match", match.Id)
+			if s.isUser(txApp, player.ID) {
+				line.Set("user", player.ID)
+			}
+			line.Set("player_id", player.ID)
+			line.Set("name", player.Name)
+			line.Set("team", player.Team)
+			line.Set("kills", player.Kills)
+			line.Set("deaths", player.Deaths)
+			line.Set("score", player.Score)
+			line.Set("shots_fired", player.ShotsFired)
+			line.Set("hits", player.Hits)
+			line.Set("damage_dealt", player.DamageDealt)
+			line.Set("damage_taken", player.DamageTaken)
+			line.Set("playtime", player.PlaytimeMs/1000)
+			line.Set("won", won(result, player))
+			line.Set("left", player.Left)
+			if err := txApp.Save(line); err != nil {
+				return fmt.Errorf("failed to save match player %s: %v", player.ID, err)
+			}
+		}
+
+		if err := s.updateRatings(txApp, result); err != nil {
+			return err
+		}
+		return s.updateLeaderboards(txApp, result)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Matches, wins and playtime go into lifetime stats with the next flush
+	s.mutex.Lock()
+	for _, player := range result.Players {
+		if s.notUser[player.ID] {
+			continue
+		}
+		delta := s.delta(player.ID)
+		delta.Matches++
+		delta.Playtime += int(player.PlaytimeMs / 1000)
+		if won(result, player) {
+			delta.Wins++
+		}
+	}
+	s.mutex.Unlock()
+
+	log.Info("Match recorded", "room", result.Room, "match", result.Number, "players", len(result.Players))
+
+	s.Flush()
+	return nil
+}
+
+// won reports whether the player was on the winning side of the match
+func won(result game.MatchResult, player game.PlayerResult) bool {
+	if result.Winner == "" {
+		return false
+	}
+	return result.Winner == player.ID || (player.Team != "" && result.Winner == player.Team)
+}
+
+// isUser reports whether a player ID belongs to a user account, remembering IDs that don't
+func (s *Store) isUser(app core.App, playerID string) bool {
+	s.mutex.Lock()
+	known := s.notUser[playerID]
+	s.mutex.Unlock()
+	if known {
+		return false
+	}
+
+	if _, err := app.FindRecordById(usersCollection, playerID); err != nil {
+		s.mutex.Lock()
+		s.notUser[playerID] = true
+		delete(s.pending, playerID)
+		s.mutex.Unlock()
+		return false
+	}
+	return true
+}
+
+// Flush writes all buffered lifetime stats
+func (s *Store) Flush() {
+	s.flushMutex.Lock()
+	defer s.flushMutex.Unlock()
+
+	s.mutex.Lock()
+	pending := s.pending
+	s.pending = make(map[string]*careerDelta)
+	s.mutex.Unlock()
+
+	for playerID, delta := range pending {
+		if !s.isUser(s.app, playerID) {
+			continue
+		}
+		if err := s.apply(playerID, delta); err != nil {
+			log.Error("Error writing player stats", "playerID", playerID, "error", err)
+		}
+	}
+
+	if len(pending) > 0 {
+		log.Debug("Flushed player stats", "players", len(pending))
+	}
+}
+
+// apply adds a delta to a user's lifetime stats record, creating it on first use
+func (s *Store) apply(userID string, delta *careerDelta) error {
+	record, err := s.app.FindFirstRecordByData(playerStatsCollection, "user", userID)
+	if err != nil {
+		collection, err := s.app.FindCachedCollectionByNameOrId(playerStatsCollection)
+		if err != nil {
+			return fmt.Errorf("failed to find player stats collection: %v", err)
+		}
+		record = core.NewRecord(collection)
+		record.Set("user", userID)
+	}
+
+	record.Set("kills+", delta.Kills)
+	record.Set("deaths+", delta.Deaths)
+	record.Set("shots_fired+", delta.ShotsFired)
+	record.Set("hits+", delta.Hits)
+	record.Set("damage_dealt+", delta.DamageDealt)
+	record.Set("damage_taken+", delta.DamageTaken)
+	record.Set("playtime+", delta.Playtime)
+	record.Set("matches+", delta.Matches)
+	record.Set("wins+", delta.Wins)
+
+	if err := s.app.Save(record); err != nil {
+		return fmt.Errorf("failed to save player stats: %v", err)
+	}
+	return nil
+}
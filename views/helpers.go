@@ -1,10 +0,0 @@
-package views
-
-import (
-	"tank-game/game"
-)
-
-// InitGameMap returns the initialized game map
-func InitGameMap() *game.GameMap {
-	return game.InitGameMap()
-}
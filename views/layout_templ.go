@@ -143,7 +143,7 @@ func Layout(loggedIn bool, appURL string) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "<!-- Favicon --><link rel=\"icon\" type=\"image/png\" href=\"/static/img/favicon.png\"><!-- Fonts --><link rel=\"preconnect\" href=\"https://rsms.me/\"><link rel=\"stylesheet\" href=\"https://rsms.me/inter/inter.css\"><style>\n      :root {\n        font-family: Inter, sans-serif;\n        font-feature-settings: \"liga\" 1, \"calt\" 1; /* fix for Chrome */\n      }\n      @supports (font-variation-settings: normal) {\n        :root {\n          font-family: InterVariable, sans-serif;\n        }\n      }\n      html, body {\n        margin: 0;\n        padding: 0;\n        height: 100%;\n        overflow: hidden;\n      }\n    </style><link rel=\"stylesheet\" href=\"https://unpkg.com/franken-ui@internal/dist/css/core.min.css\"><link rel=\"stylesheet\" href=\"https://unpkg.com/franken-ui@internal/dist/css/utilities.min.css\"><script>\n    const htmlElement = document.documentElement;\n    \n    // Set violet theme by default\n    htmlElement.classList.add(\"dark\", \"uk-theme-violet\");\n    \n    // Initialize theme based on system preference\n    if (window.matchMedia(\"(prefers-color-scheme: light)\").matches) {\n        htmlElement.classList.remove(\"dark\");\n        document.getElementById(\"themeIcon\").setAttribute(\"icon\", \"sun\");\n    }\n    \n    function toggleTheme() {\n        const isDark = htmlElement.classList.toggle(\"dark\");\n        document.getElementById(\"themeIcon\").setAttribute(\"icon\", isDark ? \"moon\" : \"sun\");\n    }\n    </script><script type=\"module\" src=\"https://unpkg.com/franken-ui@internal/dist/js/core.iife.js\"></script><script type=\"module\" src=\"https://unpkg.com/franken-ui@internal/dist/js/icon.iife.js\"></script><script type=\"module\" src=\"https://cdn.jsdelivr.net/gh/starfederation/datastar@v1.0.0-beta.9/bundles/datastar.js\"></script><script type=\"module\" src=\"https://cdnjs.cloudflare.com/ajax/libs/pocketbase/0.25.0/pocketbase.es.mjs\"></script></head><body class=\"bg-background text-foreground min-h-screen flex flex-col\"><!-- Header Navigation --><header class=\"border-b border-border\"><div class=\"uk-container uk-container-xl\"><nav class=\"flex items-center justify-between h-16\"><div class=\"flex items-center gap-x-6\"><a href=\"/\" class=\"font-bold text-lg\">Shell Shock Showdown</a><div class=\"flex gap-x-4\"><a href=\"/settings\" class=\"text-lg\">Settings</a></div></div><div class=\"flex items-center gap-x-2\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "<!-- Favicon --><link rel=\"icon\" type=\"image/png\" href=\"/static/img/favicon.png\"><!-- Fonts --><link rel=\"preconnect\" href=\"https://rsms.me/\"><link rel=\"stylesheet\" href=\"https://rsms.me/inter/inter.css\"><style>\n      :root {\n        font-family: Inter, sans-serif;\n        font-feature-settings: \"liga\" 1, \"calt\" 1; /* fix for Chrome */\n      }\n      @supports (font-variation-settings: normal) {\n        :root {\n          font-family: InterVariable, sans-serif;\n        }\n      }\n      html, body {\n        margin: 0;\n        padding: 0;\n        height: 100%;\n        overflow: hidden;\n      }\n    </style><link rel=\"stylesheet\" href=\"https://unpkg.com/franken-ui@internal/dist/css/core.min.css\"><link rel=\"stylesheet\" href=\"https://unpkg.com/franken-ui@internal/dist/css/utilities.min.css\"><script>\n    const htmlElement = document.documentElement;\n    \n    // Set violet theme by default\n    htmlElement.classList.add(\"dark\", \"uk-theme-violet\");\n    \n    // Initialize theme based on system preference\n    if (window.matchMedia(\"(prefers-color-scheme: light)\").matches) {\n        htmlElement.classList.remove(\"dark\");\n        document.getElementById(\"themeIcon\").setAttribute(\"icon\", \"sun\");\n    }\n    \n    function toggleTheme() {\n        const isDark = htmlElement.classList.toggle(\"dark\");\n        document.getElementById(\"themeIcon\").setAttribute(\"icon\", isDark ? \"moon\" : \"sun\");\n    }\n    </script><script type=\"module\" src=\"https://unpkg.com/franken-ui@internal/dist/js/core.iife.js\"></script><script type=\"module\" src=\"https://unpkg.com/franken-ui@internal/dist/js/icon.iife.js\"></script><script type=\"module\" src=\"https://cdn.jsdelivr.net/gh/starfederation/datastar@v1.0.0-beta.9/bundles/datastar.js\"></script><script type=\"module\" src=\"https://cdnjs.cloudflare.com/ajax/libs/pocketbase/0.25.0/pocketbase.es.mjs\"></script></head><body class=\"bg-background text-foreground min-h-screen flex flex-col\"><!-- Header Navigation --><header class=\"border-b border-border\"><div class=\"uk-container uk-container-xl\"><nav class=\"flex items-center justify-between h-16\"><div class=\"flex items-center gap-x-6\"><a href=\"/\" class=\"font-bold text-lg\">Shell Shock Showdown</a><div class=\"flex gap-x-4\"><a href=\"/settings\" class=\"text-lg\">Settings</a><a href=\"/profile\" class=\"text-lg\">Profile</a><a href=\"/leaderboard\" class=\"text-lg\">Leaderboard</a></div></div><div class=\"flex items-center gap-x-2\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -161,7 +161,7 @@ func Layout(loggedIn bool, appURL string) templ.Component {
 					var templ_7745c5c3_Var7 string
 					templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(appURL + "/api/files/" + auth.BaseFilesPath() + "/" + auth.GetString("avatar"))
 					if templ_7745c5c3_Err != nil {
-						return templ.Error{Err: templ_7745c5c3_Err, FileName: `views/layout.templ`, Line: 138, Col: 96}
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `views/layout.templ`, Line: 140, Col: 96}
 					}
 					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
 					if templ_7745c5c3_Err != nil {